@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// runHealthcheck implements the healthcheck subcommand. It asks the server
+// on port for its readiness and fails unless it answers 200, for container
+// health checks in images without curl:
+//
+//	backend healthcheck [--port 8080]
+func runHealthcheck(port string) error {
+	client := http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get("http://127.0.0.1:" + port + "/health/ready")
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("health check failed: %s: %s", resp.Status, body)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/yourusername/backend/internal/api"
+	"github.com/yourusername/backend/internal/auth"
+	"github.com/yourusername/backend/internal/config"
+)
+
+// loadKeyRing builds the key ring access tokens are signed and verified
+// with. Without a signing key file, which Validate only allows in
+// development, a key is generated that logs out every user on restart.
+func loadKeyRing(cfg config.Auth, logger *slog.Logger) (*auth.KeyRing, error) {
+	var signing *auth.Key
+	var err error
+	if cfg.SigningKeyFile == "" {
+		logger.Warn("No JWT signing key file configured, generated a key that is lost on restart; never run production this way")
+		signing, err = auth.GenerateKey()
+	} else {
+		signing, err = auth.LoadKey(cfg.SigningKeyFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	verifying := make([]*auth.Key, 0, len(cfg.VerificationKeyFiles))
+	for _, path := range cfg.VerificationKeyFiles {
+		key, err := auth.LoadKey(path)
+		if err != nil {
+			return nil, err
+		}
+		verifying = append(verifying, key)
+	}
+
+	ring, err := auth.NewKeyRing(cfg.Issuer, cfg.Audience, signing, verifying...)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Loaded JWT signing key", "kid", signing.ID, "alg", signing.Method.Alg(), "verification_keys", len(verifying))
+	return ring, nil
+}
+
+// rotateOnSIGHUP reloads the signing key file whenever the process receives
+// SIGHUP, until ctx is done. The previous key keeps verifying until the
+// tokens it signed have expired, so rotating logs nobody out.
+func rotateOnSIGHUP(ctx context.Context, ring *auth.KeyRing, path string, logger *slog.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+
+		key, err := auth.LoadKey(path)
+		if err == nil {
+			err = ring.Rotate(key, api.AccessTokenTTL+auth.ClockSkew)
+		}
+		if err != nil {
+			logger.Error("Failed to rotate JWT signing key", "error", err)
+			continue
+		}
+		logger.Info("Rotated JWT signing key", "kid", key.ID, "alg", key.Method.Alg())
+	}
+}
@@ -1,50 +1,337 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/yourusername/backend/internal/api"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/config"
 	"github.com/yourusername/backend/internal/database"
+	"github.com/yourusername/backend/internal/health"
+	"github.com/yourusername/backend/internal/jobs"
+	"github.com/yourusername/backend/internal/logging"
+	"github.com/yourusername/backend/internal/mail"
+	"github.com/yourusername/backend/internal/metrics"
+	"github.com/yourusername/backend/internal/ratelimit"
+	"github.com/yourusername/backend/internal/server"
+	"github.com/yourusername/backend/internal/sso"
+	"github.com/yourusername/backend/internal/store"
+	"github.com/yourusername/backend/internal/tracing"
 )
 
+// mailQueueSize is how many emails may wait for delivery before sending
+// fails
+const mailQueueSize = 1000
+
+// jobQueueSize is how many background jobs may wait before queueing fails
+const jobQueueSize = 1000
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+		cfg, err := config.Load(os.Args[2:])
+		if err != nil {
+			log.Fatalf("Failed to load configuration: %v", err)
+		}
+		if err := runHealthcheck(cfg.Server.Port); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		// Migrations only need the database, so the rest of the
+		// configuration is not validated
+		cfg, err := config.Load(nil)
+		if err == nil {
+			err = cfg.Database.Validate()
+		}
+		if err != nil {
+			log.Fatalf("Failed to load configuration: %v", err)
+		}
+		if err := runMigrate(cfg.Database, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	logger, err := logging.New(cfg.Logging, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Code without a request logger at hand, the standard log package
+	// included, logs through the default
+	slog.SetDefault(logger)
+
+	if err := serve(cfg, logger); err != nil {
+		logger.Error("Server failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// serve runs the API until SIGINT or SIGTERM, then drains in-flight
+// requests, stops the background workers and closes the database
+func serve(cfg *config.Config, logger *slog.Logger) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	keys, err := loadKeyRing(cfg.Auth, logger)
+	if err != nil {
+		return fmt.Errorf("failed to load JWT keys: %w", err)
+	}
+
 	// Initialize database
-	db, err := database.Connect()
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		return err
 	}
+	db.Logger = logging.GormLogger(logger)
 
 	// Run migrations
-	database.Migrate(db)
+	if err := database.Migrate(db); err != nil {
+		sqlDB.Close()
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
 
-	// Create Fiber app
-	app := fiber.New()
+	stores := store.NewGorm(db)
+
+	// Metrics are only collected when something can scrape them
+	var m *metrics.Metrics
+	var metricsListener net.Listener
+	if cfg.Server.MetricsAddr != "" {
+		m = metrics.New()
+		if err := db.Use(m.GormPlugin()); err != nil {
+			sqlDB.Close()
+			return fmt.Errorf("failed to register query metrics: %w", err)
+		}
+		m.ActiveUsers(func(ctx context.Context) (int64, error) {
+			return stores.CountActiveUsers(ctx, time.Now())
+		})
+		metricsListener, err = net.Listen("tcp", cfg.Server.MetricsAddr)
+		if err != nil {
+			sqlDB.Close()
+			return fmt.Errorf("failed to listen for metrics: %w", err)
+		}
+	}
+
+	// abort releases what setup has opened so far when a later step fails
+	abort := func() {
+		if metricsListener != nil {
+			metricsListener.Close()
+		}
+		sqlDB.Close()
+	}
+	if err := db.Use(tracing.GormPlugin()); err != nil {
+		abort()
+		return fmt.Errorf("failed to register query tracing: %w", err)
+	}
+
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		abort()
+		return fmt.Errorf("failed to configure mailer: %w", err)
+	}
+
+	// Create Fiber app. Shutdown waits for idle keep-alive connections, so
+	// they must time out.
+	app := fiber.New(fiber.Config{
+		ReadTimeout: time.Minute,
+		IdleTimeout: time.Minute,
+		// The banner would break up the JSON log stream
+		DisableStartupMessage: true,
+		ErrorHandler:          apierror.Handler,
+		// Behind a reverse proxy the client address, which rate limits and
+		// logs use, comes from its header. Only trusted proxies may set it.
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          cfg.Server.TrustedProxies,
+		ProxyHeader:             cfg.Server.ProxyHeader,
+		EnableIPValidation:      true,
+	})
+	srv := server.New(app, cfg.Server.ShutdownTimeout, cfg.Server.DrainDelay)
+	srv.Logger = logger
+
+	// Registered first so it runs last, flushing the spans of the final
+	// requests and queries
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		abort()
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	srv.OnClose(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return shutdownTracing(ctx)
+	})
+
+	checks := []health.Check{health.Database(db), health.Migrations(db)}
+	if path, ok := database.SQLitePath(cfg.Database.URL); ok && cfg.Database.MinFreeDiskMB > 0 {
+		checks = append(checks, health.DiskSpace(path, uint64(cfg.Database.MinFreeDiskMB)<<20))
+	}
+	srv.OnClose(sqlDB.Close)
 
 	// Middleware
+	app.Use(tracing.Middleware())
 	app.Use(cors.New(cors.Config{
-		AllowOrigins:     "http://localhost:3000,http://localhost:3001",
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
-		AllowMethods:     "GET, POST, PUT, DELETE, OPTIONS",
+		AllowOrigins:     strings.Join(cfg.Server.CORSOrigins, ","),
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, Traceparent, Tracestate, Baggage, X-Request-ID",
+		AllowMethods:     "GET, POST, PUT, PATCH, DELETE, OPTIONS",
+		ExposeHeaders:    "X-Request-ID, Retry-After, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset",
 		AllowCredentials: true,
 	}))
 
-	app.Use(logger.New())
+	// Emails and other background jobs run after the response, so slow work
+	// such as talking to the mail server neither holds up requests nor shows
+	// in their timing
+	jobQueue := jobs.NewQueue(jobQueueSize)
+	jobsDone := make(chan struct{})
+	srv.Go(func(ctx context.Context) {
+		defer close(jobsDone)
+		jobQueue.Run(ctx)
+	})
+	mailQueue := mail.NewQueue(mailer, mailQueueSize)
+	srv.Go(func(ctx context.Context) {
+		// Jobs send emails too, so the mail queue stops after the last job
+		mailCtx, stop := context.WithCancel(context.WithoutCancel(ctx))
+		go func() {
+			<-ctx.Done()
+			<-jobsDone
+			stop()
+		}()
+		mailQueue.Run(mailCtx)
+	})
+
+	if metricsListener != nil {
+		logger.Info("Serving metrics", "addr", metricsListener.Addr().String())
+		srv.Go(func(ctx context.Context) {
+			serveMetrics(ctx, metricsListener, m, logger)
+		})
+	}
+
+	if cfg.Auth.SigningKeyFile != "" {
+		srv.Go(func(ctx context.Context) {
+			rotateOnSIGHUP(ctx, keys, cfg.Auth.SigningKeyFile, logger)
+		})
+	}
+
+	if cfg.Auth.AccountDeletionGracePeriod > 0 {
+		srv.Go(func(ctx context.Context) {
+			purgeDeletedAccounts(ctx, stores, logger)
+		})
+	}
 
 	// Setup routes
-	api.SetupRoutes(app, db)
+	svc := api.Services{
+		Users:                      stores,
+		Thoughts:                   stores,
+		Keys:                       keys,
+		Mailer:                     mailQueue,
+		Jobs:                       jobQueue,
+		BaseURL:                    cfg.Server.PublicURL,
+		AppURL:                     cfg.Server.AppURL,
+		RequireVerifiedEmail:       cfg.Auth.RequireEmailVerification,
+		AccountDeletionGracePeriod: cfg.Auth.AccountDeletionGracePeriod,
+		Ready:                      srv.Ready,
+		Metrics:                    m,
+		Health:                     health.NewChecker(checks...),
+		Logger:                     logger,
+	}
+	if cfg.RateLimit.Enabled {
+		// Validate only accepts the memory store so far
+		limits := ratelimit.NewMemory()
+		svc.RateLimiter = ratelimit.New(limits)
+		svc.RateLimits = api.RateLimits{
+			Auth:      ratelimit.Limit(cfg.RateLimit.Auth),
+			AuthEmail: ratelimit.Limit(cfg.RateLimit.AuthEmail),
+			API:       ratelimit.Limit(cfg.RateLimit.API),
+			Writes:    ratelimit.Limit(cfg.RateLimit.Writes),
+		}
+		svc.Lockout = ratelimit.NewLockout(limits, ratelimit.LockoutPolicy(cfg.RateLimit.Lockout))
+	}
+	for _, p := range cfg.Auth.OIDCProviders {
+		svc.OIDCProviders = append(svc.OIDCProviders, sso.New(sso.Config{
+			Name:         p.Name,
+			DisplayName:  p.DisplayName,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			Scopes:       p.Scopes,
+		}, api.OIDCRedirectURL(cfg.Server.PublicURL, p.Name)))
+	}
+	api.SetupRoutes(app, svc)
 
 	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	logger.Info("Server starting", "port", cfg.Server.Port)
+	if err := srv.Run(ctx, ":"+cfg.Server.Port); err != nil {
+		return err
 	}
+	logger.Info("Server stopped")
+	return nil
+}
+
+// serveMetrics serves /metrics on its own listener until ctx is done. It runs
+// as a worker, so scrapes keep working while the API drains.
+func serveMetrics(ctx context.Context, ln net.Listener, m *metrics.Metrics, logger *slog.Logger) {
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/metrics", m.Handler())
+
+	served := make(chan error, 1)
+	go func() {
+		served <- app.Listener(ln)
+	}()
+
+	select {
+	case err := <-served:
+		logger.Error("Metrics listener failed", "error", err)
+		return
+	case <-ctx.Done():
+	}
+	if err := app.ShutdownWithTimeout(5 * time.Second); err != nil {
+		logger.Error("Failed to stop metrics listener", "error", err)
+	}
+	<-served
+}
+
+// purgeDeletedAccounts periodically removes accounts whose deletion grace
+// period has ended until ctx is done. A purge under way when shutdown begins
+// is allowed to finish.
+func purgeDeletedAccounts(ctx context.Context, users store.UserStore, logger *slog.Logger) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		n, err := api.PurgeScheduledDeletions(context.WithoutCancel(ctx), users, time.Now())
+		if err != nil {
+			logger.Error("Failed to purge deleted accounts", "error", err)
+		} else if n > 0 {
+			logger.Info("Purged deleted accounts", "count", n)
+		}
 
-	log.Printf("Server starting on port %s\n", port)
-	if err := app.Listen(":" + port); err != nil {
-		log.Fatalf("Error starting server: %v", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yourusername/backend/internal/config"
+	"github.com/yourusername/backend/internal/database"
+)
+
+const migrateUsage = "usage: backend migrate up|down [steps]|status"
+
+// runMigrate implements the migrate subcommand:
+//
+//	backend migrate up            apply all pending migrations
+//	backend migrate down [steps]  roll back the last steps migrations (default 1)
+//	backend migrate status        list migrations and whether they are applied
+//
+// Only the database settings of cfg are used.
+func runMigrate(cfg config.Database, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	db, err := database.Connect(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	switch args[0] {
+	case "up":
+		pending, err := database.PendingMigrations(db)
+		if err != nil {
+			return err
+		}
+		if err := database.Migrate(db); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Applied %d migrations\n", pending)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		n, err := database.MigrateDown(db, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Rolled back %d migrations\n", n)
+
+	case "status":
+		states, err := database.MigrationStatus(db)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+		for _, state := range states {
+			status := "pending"
+			switch {
+			case state.Missing:
+				status = "unknown to this build"
+			case state.Dirty:
+				status = "checksum mismatch"
+			case state.AppliedAt != nil:
+				status = "applied " + state.AppliedAt.Format(time.RFC3339)
+			case state.Unsupported:
+				status = "not supported by this database"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", state.Version, state.Name, status)
+		}
+		return w.Flush()
+
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/auth"
+	"github.com/yourusername/backend/internal/logging"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/store"
+)
+
+// RecentLoginWindow is how long after logging in at its provider an account
+// without a password may make changes that otherwise need the password
+const RecentLoginWindow = 10 * time.Minute
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6"`
+}
+
+type ChangeEmailRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+// ChangePassword sets a new password after checking the current one. Other
+// sessions are logged out; the session making the change stays signed in.
+func ChangePassword(c *fiber.Ctx, svc Services) error {
+	user, err := auth.GetUserFromContext(c, svc.Users)
+	if err != nil {
+		return apierror.Unauthorized(apierror.CodeUnauthorized, "Unauthorized")
+	}
+
+	var req ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apierror.BadRequest("Invalid request")
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return apierror.Validation(err)
+	}
+
+	if err := user.CheckPassword(c.UserContext(), req.CurrentPassword); err != nil {
+		return apierror.Forbidden(apierror.CodeIncorrectPassword, "Current password is incorrect")
+	}
+
+	if err := user.SetPassword(c.UserContext(), req.NewPassword); err != nil {
+		return apierror.Internal("Could not change password", err)
+	}
+
+	sessionID, _ := c.Locals("sessionID").(string)
+	if err := svc.Users.ChangePassword(c.UserContext(), user.ID, user.Password, sessionID); err != nil {
+		return apierror.Internal("Could not change password", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ChangeEmail moves the account to a new email address after checking the
+// password. The new address starts unverified and gets a verification link.
+func ChangeEmail(c *fiber.Ctx, svc Services) error {
+	user, err := auth.GetUserFromContext(c, svc.Users)
+	if err != nil {
+		return apierror.Unauthorized(apierror.CodeUnauthorized, "Unauthorized")
+	}
+
+	var req ChangeEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apierror.BadRequest("Invalid request")
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return apierror.Validation(err)
+	}
+
+	if err := user.CheckPassword(c.UserContext(), req.Password); err != nil {
+		return apierror.Forbidden(apierror.CodeIncorrectPassword, "Password is incorrect")
+	}
+
+	if req.Email == user.Email {
+		return apierror.Invalid("email", "invalid", "New email must be different from the current one")
+	}
+
+	if err := svc.Users.ChangeEmail(c.UserContext(), user.ID, req.Email); err != nil {
+		if errors.Is(err, store.ErrEmailTaken) {
+			return apierror.New(fiber.StatusBadRequest, apierror.CodeEmailTaken, "Email is already in use")
+		}
+		return apierror.Internal("Could not change email", err)
+	}
+	user.Email = req.Email
+	user.EmailVerified = false
+
+	if err := sendVerificationEmail(c.UserContext(), svc, user); err != nil {
+		logging.FromContext(c.UserContext()).Error("Could not send verification email", "error", err)
+	}
+
+	return c.JSON(UserResponse{
+		ID:            user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		CreatedAt:     user.CreatedAt.Format(time.RFC3339),
+	})
+}
+
+// confirmIdentity checks that a sensitive change comes from the account
+// holder. Accounts with a password confirm it. Accounts created through a
+// login provider have none; their session must instead have started with a
+// login at the provider within RecentLoginWindow.
+func confirmIdentity(c *fiber.Ctx, svc Services, user *models.User, password string) error {
+	if user.HasPassword() {
+		if password == "" {
+			return apierror.Invalid("password", "required", "Password is required")
+		}
+		if err := user.CheckPassword(c.UserContext(), password); err != nil {
+			return apierror.Forbidden(apierror.CodeIncorrectPassword, "Password is incorrect")
+		}
+		return nil
+	}
+
+	sessionID, _ := c.Locals("sessionID").(string)
+	startedAt, err := svc.Users.SessionStartedAt(c.UserContext(), user.ID, sessionID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return apierror.Internal("Could not confirm identity", err)
+	}
+	if err != nil || time.Since(startedAt) > RecentLoginWindow {
+		return apierror.Forbidden(apierror.CodeReauthRequired, "Log in again with your login provider to confirm it is you")
+	}
+	return nil
+}
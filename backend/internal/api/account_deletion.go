@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/auth"
+	"github.com/yourusername/backend/internal/store"
+)
+
+type DeleteAccountRequest struct {
+	// Password is left empty by accounts that have none, see confirmIdentity
+	Password string `json:"password"`
+}
+
+// DeleteAccount permanently removes the authenticated user and all of their
+// data after confirmIdentity. With a grace period configured the
+// account is only scheduled for deletion and every session is signed out;
+// logging in again before the period ends restores it.
+func DeleteAccount(c *fiber.Ctx, svc Services) error {
+	user, err := auth.GetUserFromContext(c, svc.Users)
+	if err != nil {
+		return apierror.Unauthorized(apierror.CodeUnauthorized, "Unauthorized")
+	}
+
+	var req DeleteAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apierror.BadRequest("Invalid request")
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return apierror.Validation(err)
+	}
+
+	if err := confirmIdentity(c, svc, user, req.Password); err != nil {
+		return err
+	}
+
+	if svc.AccountDeletionGracePeriod <= 0 {
+		if err := svc.Users.PurgeUser(c.UserContext(), user.ID); err != nil {
+			return apierror.Internal("Could not delete account", err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	dueAt := time.Now().Add(svc.AccountDeletionGracePeriod)
+	if err := svc.Users.ScheduleDeletion(c.UserContext(), user.ID, dueAt); err != nil {
+		return apierror.Internal("Could not delete account", err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message":         "Account scheduled for deletion, log in before the due date to restore it",
+		"deletion_due_at": dueAt.Format(time.RFC3339),
+	})
+}
+
+// PurgeScheduledDeletions permanently removes every account whose grace
+// period ended before now and returns how many were removed
+func PurgeScheduledDeletions(ctx context.Context, users store.UserStore, now time.Time) (int, error) {
+	ids, err := users.DueDeletions(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, id := range ids {
+		if err := users.PurgeUser(ctx, id); err != nil {
+			return i, err
+		}
+	}
+
+	return len(ids), nil
+}
@@ -0,0 +1,168 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/api"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/store"
+	"github.com/yourusername/backend/internal/testutils"
+	"gorm.io/gorm"
+)
+
+func deleteAccount(t *testing.T, app *fiber.App, token, password string) (int, map[string]interface{}) {
+	t.Helper()
+
+	body, _ := json.Marshal(map[string]string{"password": password})
+	req := httptest.NewRequest("DELETE", "/api/me", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+// seedUserData gives the user a tagged thought, an extra session and a
+// password reset so a purge has something to remove from every table
+func seedUserData(t *testing.T, db *gorm.DB, app *fiber.App, userID uint, email string) {
+	t.Helper()
+
+	thought := models.Thought{
+		Content: "Something to forget",
+		UserID:  userID,
+		Tags:    []models.Tag{{Name: "private", UserID: userID}},
+	}
+	if err := db.Create(&thought).Error; err != nil {
+		t.Fatalf("Failed to create thought: %v", err)
+	}
+	postJSON(t, app, "/api/auth/forgot-password", map[string]string{"email": email})
+}
+
+func countUserRows(db *gorm.DB, userID uint) map[string]int64 {
+	counts := map[string]int64{}
+	for name, model := range map[string]interface{}{
+		"thoughts":        &models.Thought{},
+		"tags":            &models.Tag{},
+		"sessions":        &models.Session{},
+		"password_resets": &models.PasswordReset{},
+	} {
+		var n int64
+		db.Model(model).Where("user_id = ?", userID).Count(&n)
+		counts[name] = n
+	}
+
+	var n int64
+	db.Table("thought_tags").Count(&n)
+	counts["thought_tags"] = n
+	db.Unscoped().Model(&models.User{}).Where("id = ?", userID).Count(&n)
+	counts["users"] = n
+	return counts
+}
+
+func TestDeleteAccount(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	app := testutils.SetupTestApp(t, db)
+	token, userID := registerAndLogin(t, app, "test@example.com", "password123")
+	otherToken, otherID := registerAndLogin(t, app, "other@example.com", "password123")
+	seedUserData(t, db, app, userID, "test@example.com")
+	createTestThought(t, db, otherID, "Keep me")
+
+	status, result := deleteAccount(t, app, token, "wrongpassword")
+	assert.Equal(t, fiber.StatusForbidden, status)
+	assert.Equal(t, "Password is incorrect", result["detail"])
+
+	status, result = deleteAccount(t, app, token, "")
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Equal(t, "Password is required", result["detail"])
+
+	status, _ = deleteAccount(t, app, token, "password123")
+	assert.Equal(t, fiber.StatusNoContent, status)
+
+	// Every row is gone, not just soft-deleted
+	for table, n := range countUserRows(db, userID) {
+		assert.Zero(t, n, table)
+	}
+
+	// The token no longer works and the email can be registered again
+	status, _ = getMe(t, app, token)
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+	registerAndLogin(t, app, "test@example.com", "newpassword")
+
+	// Other users are untouched
+	var others int64
+	db.Model(&models.Thought{}).Where("user_id = ?", otherID).Count(&others)
+	assert.Equal(t, int64(1), others)
+	status, _ = getMe(t, app, otherToken)
+	assert.Equal(t, fiber.StatusOK, status)
+}
+
+func TestDeleteAccountGracePeriod(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	app := testutils.SetupTestAppWithServices(t, db, api.Services{
+		AccountDeletionGracePeriod: 7 * 24 * time.Hour,
+	})
+
+	t.Run("login restores the account", func(t *testing.T) {
+		token, userID := registerAndLogin(t, app, "restore@example.com", "password123")
+		createTestThought(t, db, userID, "Still here")
+
+		status, result := deleteAccount(t, app, token, "password123")
+		assert.Equal(t, fiber.StatusAccepted, status)
+		assert.NotEmpty(t, result["deletion_due_at"])
+
+		// Scheduling signs out every session
+		status, _ = getMe(t, app, token)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+
+		// Purging before the due date keeps the account
+		purged, err := api.PurgeScheduledDeletions(context.Background(), store.NewGorm(db), time.Now())
+		assert.NoError(t, err)
+		assert.Zero(t, purged)
+
+		token, _ = loginTokens(t, app, "restore@example.com", "password123")
+		status, _ = getMe(t, app, token)
+		assert.Equal(t, fiber.StatusOK, status)
+
+		var user models.User
+		db.First(&user, userID)
+		assert.Nil(t, user.DeletionDueAt)
+
+		// Restored accounts are no longer purged
+		purged, err = api.PurgeScheduledDeletions(context.Background(), store.NewGorm(db), time.Now().Add(30*24*time.Hour))
+		assert.NoError(t, err)
+		assert.Zero(t, purged)
+		assert.Equal(t, int64(1), countUserRows(db, userID)["thoughts"])
+	})
+
+	t.Run("purged after the grace period", func(t *testing.T) {
+		token, userID := registerAndLogin(t, app, "purge@example.com", "password123")
+		seedUserData(t, db, app, userID, "purge@example.com")
+
+		status, _ := deleteAccount(t, app, token, "password123")
+		assert.Equal(t, fiber.StatusAccepted, status)
+
+		// Once the due date has passed the account cannot be restored
+		past := time.Now().Add(-time.Minute)
+		db.Model(&models.User{}).Where("id = ?", userID).Update("deletion_due_at", past)
+		status, _ = postJSON(t, app, "/api/auth/login", map[string]string{"email": "purge@example.com", "password": "password123"})
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+
+		purged, err := api.PurgeScheduledDeletions(context.Background(), store.NewGorm(db), time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, purged)
+		for table, n := range countUserRows(db, userID) {
+			assert.Zero(t, n, table)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/api"
+	"github.com/yourusername/backend/internal/mail"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+func putJSON(t *testing.T, app *fiber.App, path, token string, payload interface{}) (int, map[string]interface{}) {
+	t.Helper()
+
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest("PUT", path, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func TestChangePassword(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	app := testutils.SetupTestApp(t, db)
+	registerAndLogin(t, app, "test@example.com", "password123")
+	access, _ := loginTokens(t, app, "test@example.com", "password123")
+	otherAccess, otherRefresh := loginTokens(t, app, "test@example.com", "password123")
+
+	tests := []struct {
+		name           string
+		token          string
+		payload        map[string]string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "missing token",
+			payload:        map[string]string{"current_password": "password123", "new_password": "newpassword"},
+			expectedStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:           "wrong current password",
+			token:          access,
+			payload:        map[string]string{"current_password": "wrongpassword", "new_password": "newpassword"},
+			expectedStatus: fiber.StatusForbidden,
+			expectedError:  "Current password is incorrect",
+		},
+		{
+			name:           "short new password",
+			token:          access,
+			payload:        map[string]string{"current_password": "password123", "new_password": "short"},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "NewPassword must be at least 6 characters",
+		},
+		{
+			name:           "missing current password",
+			token:          access,
+			payload:        map[string]string{"new_password": "newpassword"},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "CurrentPassword is required",
+		},
+		{
+			name:           "successful change",
+			token:          access,
+			payload:        map[string]string{"current_password": "password123", "new_password": "newpassword"},
+			expectedStatus: fiber.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, result := putJSON(t, app, "/api/me/password", tt.token, tt.payload)
+			assert.Equal(t, tt.expectedStatus, status)
+			if tt.expectedError != "" {
+				assert.Equal(t, tt.expectedError, result["detail"])
+			}
+		})
+	}
+
+	// The password is re-hashed, not stored in plain text
+	var user models.User
+	db.Where("email = ?", "test@example.com").First(&user)
+	assert.NotEqual(t, "newpassword", user.Password)
+	assert.NoError(t, user.CheckPassword(context.Background(), "newpassword"))
+
+	status, _ := postJSON(t, app, "/api/auth/login", map[string]string{"email": "test@example.com", "password": "password123"})
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+	loginTokens(t, app, "test@example.com", "newpassword")
+
+	// The current session survives, other sessions are revoked
+	status, _ = getMe(t, app, access)
+	assert.Equal(t, fiber.StatusOK, status)
+	status, _ = getMe(t, app, otherAccess)
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+	status, _ = refresh(t, app, otherRefresh)
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+}
+
+func TestChangeEmail(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	mailer := mail.NewMemoryMailer()
+	app := testutils.SetupTestAppWithServices(t, db, api.Services{Mailer: mailer})
+	token, userID := registerAndLogin(t, app, "test@example.com", "password123")
+	registerAndLogin(t, app, "taken@example.com", "password123")
+
+	// Start from a verified account
+	status, _ := verify(t, app, lastVerificationToken(t, mailer, "test@example.com"))
+	assert.Equal(t, fiber.StatusOK, status)
+
+	tests := []struct {
+		name           string
+		payload        map[string]string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "wrong password",
+			payload:        map[string]string{"email": "new@example.com", "password": "wrongpassword"},
+			expectedStatus: fiber.StatusForbidden,
+			expectedError:  "Password is incorrect",
+		},
+		{
+			name:           "invalid email",
+			payload:        map[string]string{"email": "not-an-email", "password": "password123"},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "Invalid email format",
+		},
+		{
+			name:           "same email",
+			payload:        map[string]string{"email": "test@example.com", "password": "password123"},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "New email must be different from the current one",
+		},
+		{
+			name:           "email taken",
+			payload:        map[string]string{"email": "taken@example.com", "password": "password123"},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "Email is already in use",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, result := putJSON(t, app, "/api/me/email", token, tt.payload)
+			assert.Equal(t, tt.expectedStatus, status)
+			assert.Equal(t, tt.expectedError, result["detail"])
+		})
+	}
+
+	t.Run("successful change", func(t *testing.T) {
+		status, result := putJSON(t, app, "/api/me/email", token, map[string]string{"email": "new@example.com", "password": "password123"})
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, "new@example.com", result["email"])
+		assert.Equal(t, false, result["email_verified"])
+
+		var user models.User
+		db.First(&user, userID)
+		assert.Equal(t, "new@example.com", user.Email)
+		assert.False(t, user.EmailVerified)
+
+		// A verification link goes to the new address
+		status, _ = verify(t, app, lastVerificationToken(t, mailer, "new@example.com"))
+		assert.Equal(t, fiber.StatusOK, status)
+		status, me := getMe(t, app, token)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, true, me["email_verified"])
+	})
+}
@@ -1,20 +1,43 @@
 package api
 
 import (
-	"os"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/auth"
+	"github.com/yourusername/backend/internal/logging"
+	"github.com/yourusername/backend/internal/metrics"
 	"github.com/yourusername/backend/internal/models"
-	"gorm.io/gorm"
+	"github.com/yourusername/backend/internal/ratelimit"
+	"github.com/yourusername/backend/internal/store"
+)
+
+const (
+	// AccessTokenTTL is how long an access token is accepted
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL is how long a refresh token can be exchanged
+	RefreshTokenTTL = 30 * 24 * time.Hour
 )
 
 var validate *validator.Validate
 
 func init() {
 	validate = validator.New()
+	// Report fields by their JSON names, which is how clients know them
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
 }
 
 type LoginRequest struct {
@@ -28,7 +51,8 @@ type RegisterRequest struct {
 }
 
 type AuthResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 type UserResponse struct {
@@ -38,93 +62,118 @@ type UserResponse struct {
 	CreatedAt     string `json:"created_at"`
 }
 
-// Login handles user login
-func Login(c *fiber.Ctx, db *gorm.DB) error {
+// Login handles user login. Accounts with two-factor authentication get an
+// MFAChallengeResponse instead of the tokens.
+func Login(c *fiber.Ctx, svc Services) error {
 	var req LoginRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request",
-		})
+		return apierror.BadRequest("Invalid request")
 	}
 
 	// Validate request
 	if err := validate.Struct(req); err != nil {
-		// Return the first validation error
-		errMsg := ""
-		for _, err := range err.(validator.ValidationErrors) {
-			switch err.Tag() {
-			case "required":
-				errMsg = err.Field() + " is required"
-			case "email":
-				errMsg = "Invalid email format"
-			case "min":
-				errMsg = err.Field() + " must be at least " + err.Param() + " characters"
-			}
+		return apierror.Validation(err)
+	}
+
+	// Unknown emails are locked out like real ones, so a lockout does not
+	// reveal which accounts exist
+	lockoutKey := ratelimit.NormalizeEmail(req.Email)
+	if err := checkLockout(c, svc, lockoutKey); err != nil {
+		return err
+	}
+
+	user, err := svc.Users.UserByEmail(c.UserContext(), req.Email)
+	if err != nil {
+		return loginFailed(c, svc, lockoutKey)
+	}
+
+	if err := user.CheckPassword(c.UserContext(), req.Password); err != nil {
+		return loginFailed(c, svc, lockoutKey)
+	}
+	if user.DeletionDueAt != nil && !user.DeletionDueAt.After(time.Now()) {
+		return loginFailed(c, svc, lockoutKey)
+	}
+
+	// With two-factor authentication the password only earns a challenge.
+	// The lockout is not reset until the code is right too, or knowing the
+	// password would allow unlimited guesses at codes.
+	twoFactor, err := userTwoFactor(c.UserContext(), svc, user.ID)
+	if err != nil {
+		return apierror.Internal("Could not log in", err)
+	}
+	if twoFactor.Enabled() {
+		challenge, err := createChallenge(svc.Keys, user.ID)
+		if err != nil {
+			return apierror.Internal("Could not create token", err)
 		}
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": errMsg,
-		})
+		return c.JSON(challenge)
 	}
 
-	var user models.User
-	if err := db.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid credentials",
-		})
+	return completeLogin(c, svc, user, lockoutKey)
+}
+
+// completeLogin logs in a user who passed every factor: it restores an
+// account in its deletion grace period, resets the lockout and issues the
+// tokens
+func completeLogin(c *fiber.Ctx, svc Services, user *models.User, lockoutKey string) error {
+	if user.DeletionDueAt != nil {
+		if !user.DeletionDueAt.After(time.Now()) {
+			return loginFailed(c, svc, lockoutKey)
+		}
+		if err := svc.Users.CancelDeletion(c.UserContext(), user.ID); err != nil {
+			return apierror.Internal("Could not restore account", err)
+		}
 	}
 
-	if err := user.CheckPassword(req.Password); err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid credentials",
-		})
+	if err := svc.Lockout.Reset(c.UserContext(), lockoutKey); err != nil {
+		logging.FromContext(c.UserContext()).Error("Could not reset login lockout", "error", err)
 	}
 
-	token, err := createToken(user.ID)
+	tokens, err := issueTokens(c.UserContext(), svc, user.ID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Could not create token",
-		})
+		return apierror.Internal("Could not create token", err)
 	}
 
-	return c.JSON(AuthResponse{Token: token})
+	svc.Metrics.Login(metrics.LoginSuccess)
+
+	return c.JSON(tokens)
+}
+
+// checkLockout rejects logins to a locked account
+func checkLockout(c *fiber.Ctx, svc Services, lockoutKey string) error {
+	locked, err := svc.Lockout.Remaining(c.UserContext(), lockoutKey)
+	if err != nil {
+		logging.FromContext(c.UserContext()).Error("Could not check login lockout", "error", err)
+	}
+	if locked > 0 {
+		svc.Metrics.Login(metrics.LoginFailure)
+		c.Set(fiber.HeaderRetryAfter, ratelimit.FormatSeconds(locked))
+		return apierror.New(fiber.StatusTooManyRequests, apierror.CodeAccountLocked, "Too many failed logins, try again later")
+	}
+	return nil
+}
+
+// loginFailed counts a failed login towards the lockout of the account and
+// rejects it. The response is the same whether or not the account is now
+// locked; the next attempt finds out.
+func loginFailed(c *fiber.Ctx, svc Services, lockoutKey string) error {
+	svc.Metrics.Login(metrics.LoginFailure)
+	if _, err := svc.Lockout.Fail(c.UserContext(), lockoutKey); err != nil {
+		logging.FromContext(c.UserContext()).Error("Could not record failed login", "error", err)
+	}
+	return apierror.Unauthorized(apierror.CodeInvalidCredentials, "Invalid credentials")
 }
 
-// Register handles user registration
-// Register handles user registration
-func Register(c *fiber.Ctx, db *gorm.DB) error {
+// Register handles user registration and emails a verification link
+func Register(c *fiber.Ctx, svc Services) error {
 	var req RegisterRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request",
-		})
+		return apierror.BadRequest("Invalid request")
 	}
 
 	// Validate request
 	if err := validate.Struct(req); err != nil {
-		// Return the first validation error
-		errMsg := ""
-		for _, err := range err.(validator.ValidationErrors) {
-			switch err.Tag() {
-			case "required":
-				errMsg = err.Field() + " is required"
-			case "email":
-				errMsg = "Invalid email format"
-			case "min":
-				errMsg = err.Field() + " must be at least " + err.Param() + " characters"
-			}
-			break
-		}
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": errMsg,
-		})
-	}
-
-	// Check if user already exists
-	var existingUser models.User
-	if err := db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "User already exists",
-		})
+		return apierror.Validation(err)
 	}
 
 	user := models.User{
@@ -132,42 +181,58 @@ func Register(c *fiber.Ctx, db *gorm.DB) error {
 		Password: req.Password,
 	}
 
-	if err := db.Create(&user).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Could not create user",
-		})
+	if err := svc.Users.CreateUser(c.UserContext(), &user); err != nil {
+		if errors.Is(err, store.ErrEmailTaken) {
+			return apierror.New(fiber.StatusBadRequest, apierror.CodeEmailTaken, "User already exists")
+		}
+		return apierror.Internal("Could not create user", err)
 	}
 
-	token, err := createToken(user.ID)
+	// A failed email must not fail registration; the user can ask for a resend
+	if err := sendVerificationEmail(c.UserContext(), svc, &user); err != nil {
+		logging.FromContext(c.UserContext()).Error("Could not send verification email", "user_id", user.ID, "error", err)
+	}
+
+	tokens, err := issueTokens(c.UserContext(), svc, user.ID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Could not create token",
-		})
+		return apierror.Internal("Could not create token", err)
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(AuthResponse{Token: token})
+	return c.Status(fiber.StatusCreated).JSON(tokens)
 }
 
-// createToken generates a JWT token for the user
-func createToken(userID uint) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
+// createToken generates a short-lived JWT access token bound to a session,
+// signed with the current key of the key ring
+func createToken(keys *auth.KeyRing, userID uint, sessionID string) (string, error) {
+	now := time.Now()
+	return keys.Sign(auth.Claims{
+		UserID:    userID,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
+		},
 	})
+}
 
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+// JWKS serves the public keys access tokens can be verified with, so other
+// services can verify them without holding a signing key. Verifiers should
+// fetch the set again when a token names a key they do not know.
+func JWKS(c *fiber.Ctx, svc Services) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(svc.Keys.JWKS())
 }
 
 // GetCurrentUser returns the current authenticated user's profile
-func GetCurrentUser(c *fiber.Ctx, db *gorm.DB) error {
+func GetCurrentUser(c *fiber.Ctx, svc Services) error {
 	// Get user ID from JWT
 	userID := c.Locals("userID").(uint)
 
-	var user models.User
-	if err := db.First(&user, userID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "User not found",
-		})
+	user, err := svc.Users.UserByID(c.UserContext(), userID)
+	if err != nil {
+		return apierror.NotFound("User not found")
 	}
 
 	response := UserResponse{
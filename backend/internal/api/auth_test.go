@@ -7,7 +7,9 @@ import (
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/auth"
 	"github.com/yourusername/backend/internal/testutils"
 )
 
@@ -67,7 +69,7 @@ func TestRegister(t *testing.T) {
 			if tt.expectedError != "" {
 				var result map[string]string
 				json.NewDecoder(resp.Body).Decode(&result)
-				assert.Contains(t, result["error"], tt.expectedError)
+				assert.Contains(t, result["detail"], tt.expectedError)
 			}
 		})
 	}
@@ -158,7 +160,7 @@ func TestLogin(t *testing.T) {
 			name:           "empty request body",
 			payload:        map[string]string{},
 			expectedStatus: fiber.StatusBadRequest,
-			expectedError:  "Password is required",
+			expectedError:  "Email is required",
 		},
 	}
 
@@ -183,7 +185,7 @@ func TestLogin(t *testing.T) {
 			}
 
 			if tt.expectedError != "" {
-				assert.Contains(t, result["error"], tt.expectedError)
+				assert.Contains(t, result["detail"], tt.expectedError)
 			}
 		})
 	}
@@ -295,7 +297,7 @@ func TestGetCurrentUser(t *testing.T) {
 			}
 
 			if tt.expectedError != "" {
-				assert.Contains(t, result["error"], tt.expectedError)
+				assert.Contains(t, result["detail"], tt.expectedError)
 			}
 
 			if tt.expectedEmail != "" {
@@ -307,3 +309,31 @@ func TestGetCurrentUser(t *testing.T) {
 		})
 	}
 }
+
+func TestJWKS(t *testing.T) {
+	app, _ := testutils.SetupMemoryApp(t)
+	token, _ := registerAndLogin(t, app, "jwks@example.com", "password123")
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "public, max-age=300", resp.Header.Get(fiber.HeaderCacheControl))
+
+	var jwks auth.JWKS
+	json.NewDecoder(resp.Body).Decode(&jwks)
+	if assert.Len(t, jwks.Keys, 1) {
+		assert.Equal(t, testutils.Keys.Current().ID, jwks.Keys[0].KeyID)
+		assert.Equal(t, "EdDSA", jwks.Keys[0].Algorithm)
+		assert.Equal(t, "OKP", jwks.Keys[0].KeyType)
+		assert.NotEmpty(t, jwks.Keys[0].X)
+	}
+
+	// Access tokens name the published key
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &auth.Claims{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, jwks.Keys[0].KeyID, parsed.Header["kid"])
+		assert.Equal(t, "EdDSA", parsed.Method.Alg())
+	}
+}
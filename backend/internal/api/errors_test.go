@@ -0,0 +1,126 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/api"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/store"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+// brokenThoughts fails to create thoughts, as a database that went away would
+type brokenThoughts struct {
+	*store.Memory
+}
+
+func (brokenThoughts) CreateThought(ctx context.Context, thought *models.Thought) error {
+	return errors.New("database is locked")
+}
+
+func TestServerErrorIsLoggedWithRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	memory := store.NewMemory()
+	app := testutils.SetupTestAppWithServices(t, nil, api.Services{
+		Users:    memory,
+		Thoughts: brokenThoughts{memory},
+		Logger:   slog.New(slog.NewJSONHandler(&logs, nil)),
+	})
+	token, userID := registerAndLogin(t, app, "logs@example.com", "password123")
+
+	req := httptest.NewRequest("POST", "/api/thoughts", strings.NewReader(`{"content":"Lost"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Request-ID", "support-1234")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body map[string]string
+	json.NewDecoder(resp.Body).Decode(&body)
+	assert.Equal(t, 500, resp.StatusCode)
+	assert.Equal(t, "support-1234", resp.Header.Get("X-Request-ID"))
+	assert.Equal(t, "Could not create thought", body["detail"])
+	assert.Equal(t, "support-1234", body["request_id"])
+	assert.NotContains(t, body["detail"], "database is locked", "internal errors stay out of responses")
+
+	var logged map[string]interface{}
+	for _, line := range strings.Split(logs.String(), "\n") {
+		if strings.Contains(line, `"msg":"Could not create thought"`) {
+			json.Unmarshal([]byte(line), &logged)
+		}
+	}
+	if assert.NotNil(t, logged, "error is logged") {
+		assert.Equal(t, "ERROR", logged["level"])
+		assert.Equal(t, "database is locked", logged["error"])
+		assert.Equal(t, "support-1234", logged["request_id"])
+		assert.Equal(t, fmt.Sprint(userID), fmt.Sprint(logged["user_id"]))
+	}
+}
+
+func TestClientErrorsCarryRequestID(t *testing.T) {
+	app, _ := testutils.SetupMemoryApp(t)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/me", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body map[string]string
+	json.NewDecoder(resp.Body).Decode(&body)
+	assert.Equal(t, 401, resp.StatusCode)
+	assert.NotEmpty(t, body["request_id"])
+	assert.Equal(t, resp.Header.Get("X-Request-ID"), body["request_id"])
+}
+
+func TestValidationProblem(t *testing.T) {
+	app, _ := testutils.SetupMemoryApp(t)
+
+	req := httptest.NewRequest("POST", "/api/auth/register", strings.NewReader(`{"password":"abc"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var problem apierror.Problem
+	json.NewDecoder(resp.Body).Decode(&problem)
+	assert.Equal(t, 400, resp.StatusCode)
+	assert.Equal(t, apierror.ContentType, resp.Header.Get("Content-Type"))
+	assert.Equal(t, "urn:thoughts:problem:validation_failed", problem.Type)
+	assert.Equal(t, "Bad Request", problem.Title)
+	assert.Equal(t, 400, problem.Status)
+	assert.Equal(t, apierror.CodeValidationFailed, problem.Code)
+	assert.Equal(t, "/api/auth/register", problem.Instance)
+	assert.Equal(t, "Email is required", problem.Detail)
+	assert.Equal(t, []apierror.FieldError{
+		{Field: "email", Code: "required", Message: "Email is required"},
+		{Field: "password", Code: "min", Message: "Password must be at least 6 characters"},
+	}, problem.Errors)
+}
+
+func TestUnknownRouteProblem(t *testing.T) {
+	app, _ := testutils.SetupMemoryApp(t)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/nowhere", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var problem apierror.Problem
+	json.NewDecoder(resp.Body).Decode(&problem)
+	assert.Equal(t, 404, resp.StatusCode)
+	assert.Equal(t, apierror.ContentType, resp.Header.Get("Content-Type"))
+	assert.Equal(t, apierror.CodeNotFound, problem.Code)
+}
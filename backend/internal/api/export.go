@@ -0,0 +1,204 @@
+package api
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/auth"
+	"github.com/yourusername/backend/internal/logging"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/store"
+)
+
+// exportWriters stream an export in one format. They are keyed by the value
+// of the format query parameter.
+var exportWriters = map[string]struct {
+	contentType string
+	extension   string
+	write       func(ctx context.Context, w io.Writer, thoughts store.ThoughtStore, user *models.User) error
+}{
+	"json":     {"application/json", "json", writeJSONExport},
+	"csv":      {"text/csv; charset=utf-8", "csv", writeCSVExport},
+	"markdown": {"application/zip", "zip", writeMarkdownExport},
+}
+
+// ExportThought is a thought as it appears in an export
+type ExportThought struct {
+	ID        uint      `json:"id"`
+	Content   string    `json:"content"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ExportData streams the authenticated user's profile and thoughts as JSON,
+// CSV or a zip archive of Markdown files. Thoughts are read in batches so
+// large accounts are never held in memory at once.
+func ExportData(c *fiber.Ctx, svc Services) error {
+	user, err := auth.GetUserFromContext(c, svc.Users)
+	if err != nil {
+		return apierror.Unauthorized(apierror.CodeUnauthorized, "Unauthorized")
+	}
+
+	format := c.Query("format", "json")
+	exporter, ok := exportWriters[format]
+	if !ok {
+		return apierror.Invalid("format", "invalid", "Invalid export format")
+	}
+
+	filename := fmt.Sprintf("thoughts-export-%s.%s", time.Now().UTC().Format("20060102"), exporter.extension)
+	c.Set(fiber.HeaderContentType, exporter.contentType)
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+
+	// The status line is already sent once streaming starts, so failures
+	// past this point can only be logged and the body is left truncated
+	ctx := c.UserContext()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := exporter.write(ctx, w, svc.Thoughts, user); err != nil {
+			logging.FromContext(ctx).Error("Export failed", "error", err)
+		}
+		w.Flush()
+	})
+
+	return nil
+}
+
+// eachThought calls fn for every thought of the user, oldest first
+func eachThought(ctx context.Context, thoughts store.ThoughtStore, userID uint, fn func(ExportThought) error) error {
+	return thoughts.EachThought(ctx, userID, func(thought models.Thought) error {
+		tags := make([]string, 0, len(thought.Tags))
+		for _, tag := range thought.Tags {
+			tags = append(tags, tag.Name)
+		}
+		return fn(ExportThought{
+			ID:        thought.ID,
+			Content:   thought.Content,
+			Tags:      tags,
+			CreatedAt: thought.CreatedAt,
+			UpdatedAt: thought.UpdatedAt,
+		})
+	})
+}
+
+func exportProfile(user *models.User) UserResponse {
+	return UserResponse{
+		ID:            user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		CreatedAt:     user.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// writeJSONExport writes {"user": ..., "exported_at": ..., "thoughts": [...]}
+// one thought at a time
+func writeJSONExport(ctx context.Context, w io.Writer, thoughts store.ThoughtStore, user *models.User) error {
+	profile, err := json.Marshal(exportProfile(user))
+	if err != nil {
+		return err
+	}
+	header := fmt.Sprintf(`{"user":%s,"exported_at":%q,"thoughts":[`, profile, time.Now().UTC().Format(time.RFC3339))
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	first := true
+	err = eachThought(ctx, thoughts, user.ID, func(thought ExportThought) error {
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+
+		data, err := json.Marshal(thought)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
+
+// writeCSVExport writes one row per thought. Tags are space separated.
+func writeCSVExport(ctx context.Context, w io.Writer, thoughts store.ThoughtStore, user *models.User) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "created_at", "updated_at", "content", "tags"}); err != nil {
+		return err
+	}
+
+	err := eachThought(ctx, thoughts, user.ID, func(thought ExportThought) error {
+		return cw.Write([]string{
+			strconv.FormatUint(uint64(thought.ID), 10),
+			thought.CreatedAt.UTC().Format(time.RFC3339),
+			thought.UpdatedAt.UTC().Format(time.RFC3339),
+			thought.Content,
+			strings.Join(thought.Tags, " "),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeMarkdownExport writes a zip archive with the profile in profile.md
+// and one Markdown file per thought under thoughts/, each starting with
+// YAML front-matter
+func writeMarkdownExport(ctx context.Context, w io.Writer, thoughts store.ThoughtStore, user *models.User) error {
+	zw := zip.NewWriter(w)
+
+	profile := exportProfile(user)
+	f, err := zw.Create("profile.md")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f, "---\nid: %d\nemail: %q\nemail_verified: %t\ncreated_at: %s\n---\n",
+		profile.ID, profile.Email, profile.EmailVerified, profile.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = eachThought(ctx, thoughts, user.ID, func(thought ExportThought) error {
+		name := fmt.Sprintf("thoughts/%s-%d.md", thought.CreatedAt.UTC().Format("2006-01-02"), thought.ID)
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+
+		// JSON strings are valid YAML, which keeps tags like "2024" strings
+		tags, err := json.Marshal(thought.Tags)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(f, "---\nid: %d\ncreated_at: %s\nupdated_at: %s\ntags: %s\n---\n\n%s\n",
+			thought.ID,
+			thought.CreatedAt.UTC().Format(time.RFC3339),
+			thought.UpdatedAt.UTC().Format(time.RFC3339),
+			tags,
+			thought.Content)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
@@ -0,0 +1,134 @@
+package api_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/api"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+func export(t *testing.T, app *fiber.App, token, format string) (int, string, []byte) {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", "/api/me/export?format="+format, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, resp.Header.Get("Content-Type"), body
+}
+
+func TestExportData(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	app := testutils.SetupTestApp(t, db)
+	token, userID := registerAndLogin(t, app, "test@example.com", "password123")
+	_, otherID := registerAndLogin(t, app, "other@example.com", "password123")
+
+	// More thoughts than one export batch, the first one tagged
+	tagged := models.Thought{
+		Content: "Hiking, \"quoted\"\nsecond line",
+		UserID:  userID,
+		Tags:    []models.Tag{{Name: "outdoors", UserID: userID}, {Name: "2024", UserID: userID}},
+	}
+	if err := db.Create(&tagged).Error; err != nil {
+		t.Fatalf("Failed to create test thought: %v", err)
+	}
+	for i := 0; i < 249; i++ {
+		createTestThought(t, db, userID, "Thought")
+	}
+	createTestThought(t, db, otherID, "Not mine")
+
+	t.Run("json", func(t *testing.T) {
+		status, contentType, body := export(t, app, token, "json")
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, "application/json", contentType)
+
+		var data struct {
+			User     api.UserResponse    `json:"user"`
+			Thoughts []api.ExportThought `json:"thoughts"`
+		}
+		if !assert.NoError(t, json.Unmarshal(body, &data)) {
+			return
+		}
+		assert.Equal(t, "test@example.com", data.User.Email)
+		if !assert.Len(t, data.Thoughts, 250) {
+			return
+		}
+		assert.Equal(t, tagged.ID, data.Thoughts[0].ID)
+		assert.Equal(t, tagged.Content, data.Thoughts[0].Content)
+		assert.ElementsMatch(t, []string{"outdoors", "2024"}, data.Thoughts[0].Tags)
+		for _, thought := range data.Thoughts {
+			assert.NotEqual(t, "Not mine", thought.Content)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		status, contentType, body := export(t, app, token, "csv")
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.True(t, strings.HasPrefix(contentType, "text/csv"))
+
+		rows, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+		if !assert.NoError(t, err) {
+			return
+		}
+		if !assert.Len(t, rows, 251) {
+			return
+		}
+		assert.Equal(t, []string{"id", "created_at", "updated_at", "content", "tags"}, rows[0])
+		assert.Equal(t, tagged.Content, rows[1][3])
+		assert.ElementsMatch(t, []string{"outdoors", "2024"}, strings.Fields(rows[1][4]))
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		status, contentType, body := export(t, app, token, "markdown")
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, "application/zip", contentType)
+
+		archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		if !assert.NoError(t, err) {
+			return
+		}
+		if !assert.Len(t, archive.File, 251) {
+			return
+		}
+		assert.Equal(t, "profile.md", archive.File[0].Name)
+
+		f, err := archive.File[1].Open()
+		if !assert.NoError(t, err) {
+			return
+		}
+		md, _ := io.ReadAll(f)
+		f.Close()
+		assert.True(t, strings.HasPrefix(archive.File[1].Name, "thoughts/"))
+		assert.True(t, strings.HasPrefix(string(md), "---\n"))
+		assert.Contains(t, string(md), "created_at: "+tagged.CreatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"))
+		assert.Contains(t, string(md), `"2024"`)
+		assert.True(t, strings.HasSuffix(string(md), "---\n\n"+tagged.Content+"\n"))
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		status, _, body := export(t, app, token, "xml")
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Contains(t, string(body), "Invalid export format")
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		status, _, _ := export(t, app, "", "json")
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+	})
+}
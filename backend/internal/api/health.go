@@ -0,0 +1,37 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/backend/internal/health"
+)
+
+// Live is the liveness probe. It only shows the process is serving requests
+// and checks no dependencies, so a database outage does not get every
+// instance restarted.
+func Live(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"status": health.StatusUp,
+	})
+}
+
+// Ready is the readiness probe. It fails as soon as shutdown begins and
+// otherwise reports every health check with its latency, answering 503 when
+// any component is down.
+func Ready(c *fiber.Ctx, svc Services) error {
+	if svc.Ready != nil && !svc.Ready() {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status": "shutting down",
+		})
+	}
+
+	report := health.Report{Status: health.StatusUp, Components: map[string]health.Component{}}
+	if svc.Health != nil {
+		report = svc.Health.Run(c.UserContext())
+	}
+
+	status := fiber.StatusOK
+	if !report.Healthy() {
+		status = fiber.StatusServiceUnavailable
+	}
+	return c.Status(status).JSON(report)
+}
@@ -0,0 +1,85 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/api"
+	"github.com/yourusername/backend/internal/health"
+	"github.com/yourusername/backend/internal/store"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+func TestReadinessFailsOnShutdown(t *testing.T) {
+	var draining atomic.Bool
+	memory := store.NewMemory()
+	app := testutils.SetupTestAppWithServices(t, nil, api.Services{
+		Users:    memory,
+		Thoughts: memory,
+		Ready:    func() bool { return !draining.Load() },
+	})
+
+	ready := func() (int, string) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/health/ready", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body map[string]string
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body["status"]
+	}
+
+	status, state := ready()
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "up", state)
+
+	draining.Store(true)
+	status, state = ready()
+	assert.Equal(t, fiber.StatusServiceUnavailable, status)
+	assert.Equal(t, "shutting down", state)
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	app := testutils.SetupTestApp(t, db)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/health/live", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	readiness := func() (int, health.Report) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/health/ready", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var report health.Report
+		json.NewDecoder(resp.Body).Decode(&report)
+		return resp.StatusCode, report
+	}
+
+	status, report := readiness()
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, health.StatusUp, report.Status)
+	assert.Equal(t, health.StatusUp, report.Components["database"].Status)
+	assert.Equal(t, health.StatusUp, report.Components["migrations"].Status)
+
+	// Losing the database fails readiness but not liveness
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	status, report = readiness()
+	assert.Equal(t, fiber.StatusServiceUnavailable, status)
+	assert.Equal(t, health.StatusDown, report.Components["database"].Status)
+	assert.NotEmpty(t, report.Components["database"].Error)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/health/live", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
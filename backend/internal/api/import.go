@@ -0,0 +1,383 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/auth"
+	"github.com/yourusername/backend/internal/logging"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/store"
+)
+
+const (
+	// MaxImportItems caps how many thoughts one import may contain
+	MaxImportItems = 5000
+	// importBatchSize is how many thoughts are inserted per transaction
+	importBatchSize = 100
+)
+
+// importTimeLayouts are the timestamp formats accepted in imported files
+var importTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// ImportItem is one thought read from an import file
+type ImportItem struct {
+	Content   string   `json:"content"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+	Tags      []string `json:"tags"`
+}
+
+// ImportResult reports what happened to one item. Row is the 1-based
+// position of the item in the file, not counting a CSV header.
+type ImportResult struct {
+	Row    int    `json:"row"`
+	Status string `json:"status"`
+	ID     uint   `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport is the response of ImportThoughts
+type ImportReport struct {
+	Imported int            `json:"imported"`
+	Rejected int            `json:"rejected"`
+	Results  []ImportResult `json:"results"`
+}
+
+// importRow is a parsed item, or the reason it could not be parsed
+type importRow struct {
+	item ImportItem
+	err  error
+}
+
+// ImportThoughts creates thoughts in bulk from an uploaded JSON, CSV or
+// plain-text file, keeping the original timestamps. Each item is validated
+// like CreateThought; invalid items are reported and skipped.
+func ImportThoughts(c *fiber.Ctx, svc Services) error {
+	user, err := auth.GetUserFromContext(c, svc.Users)
+	if err != nil {
+		return apierror.Unauthorized(apierror.CodeUnauthorized, "Unauthorized")
+	}
+
+	if svc.RequireVerifiedEmail && !user.EmailVerified {
+		return apierror.Forbidden(apierror.CodeEmailNotVerified, "Email verification required")
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return apierror.Invalid("file", "required", "File is required")
+	}
+
+	format := c.FormValue("format")
+	if format == "" {
+		format = importFormat(header.Filename)
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return apierror.BadRequest("Could not read file")
+	}
+	defer file.Close()
+
+	var rows []importRow
+	switch format {
+	case "json":
+		rows, err = parseJSONImport(file)
+	case "csv":
+		rows, err = parseCSVImport(file)
+	case "text":
+		rows, err = parseTextImport(file)
+	default:
+		return apierror.Invalid("format", "invalid", "Invalid import format")
+	}
+	if err != nil {
+		return apierror.BadRequest(err.Error())
+	}
+
+	if len(rows) > MaxImportItems {
+		return apierror.BadRequest(fmt.Sprintf("Too many items (maximum %d)", MaxImportItems))
+	}
+
+	report := importRows(c.UserContext(), svc.Thoughts, user.ID, rows)
+	svc.Metrics.ThoughtsCreated(report.Imported)
+	return c.JSON(report)
+}
+
+// importFormat guesses the format from the file extension
+func importFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return "json"
+	case ".csv":
+		return "csv"
+	default:
+		return "text"
+	}
+}
+
+// importRows validates every row and inserts the valid ones in batches
+func importRows(ctx context.Context, thoughts store.ThoughtStore, userID uint, rows []importRow) ImportReport {
+	report := ImportReport{Results: make([]ImportResult, len(rows))}
+
+	type pending struct {
+		index   int
+		thought *models.Thought
+	}
+	var batch []pending
+
+	record := func(p pending, err error) {
+		if err != nil {
+			report.Results[p.index] = ImportResult{Row: p.index + 1, Status: "rejected", Error: "Could not save thought"}
+			report.Rejected++
+			return
+		}
+		report.Results[p.index] = ImportResult{Row: p.index + 1, Status: "accepted", ID: p.thought.ID}
+		report.Imported++
+	}
+
+	flush := func() {
+		defer func() { batch = batch[:0] }()
+
+		saved := make([]*models.Thought, len(batch))
+		for i, p := range batch {
+			saved[i] = p.thought
+		}
+		err := thoughts.ImportThoughts(ctx, saved)
+		if err == nil {
+			for _, p := range batch {
+				record(p, nil)
+			}
+			return
+		}
+		logging.FromContext(ctx).Error("Could not import thoughts", "error", err)
+
+		// Retry one at a time, so a bad row only rejects itself
+		for _, p := range batch {
+			// The failed transaction may have assigned an ID that was rolled back
+			p.thought.ID = 0
+			err := thoughts.ImportThoughts(ctx, []*models.Thought{p.thought})
+			if err != nil {
+				logging.FromContext(ctx).Error("Could not import thought", "row", p.index+1, "error", err)
+			}
+			record(p, err)
+		}
+	}
+
+	for i, row := range rows {
+		thought, err := importThought(userID, row)
+		if err != nil {
+			report.Results[i] = ImportResult{Row: i + 1, Status: "rejected", Error: err.Error()}
+			report.Rejected++
+			continue
+		}
+
+		batch = append(batch, pending{index: i, thought: thought})
+		if len(batch) == importBatchSize {
+			flush()
+		}
+	}
+	if len(batch) > 0 {
+		flush()
+	}
+
+	return report
+}
+
+// importThought applies the CreateThought rules to one row
+func importThought(userID uint, row importRow) (*models.Thought, error) {
+	if row.err != nil {
+		return nil, row.err
+	}
+
+	content, err := validateContent(row.item.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := mergeTags(content, row.item.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt, err := parseImportTime(row.item.CreatedAt)
+	if err != nil {
+		return nil, errors.New("Invalid created_at")
+	}
+	updatedAt, err := parseImportTime(row.item.UpdatedAt)
+	if err != nil {
+		return nil, errors.New("Invalid updated_at")
+	}
+
+	now := time.Now()
+	if createdAt.IsZero() {
+		createdAt = now
+	}
+	if createdAt.After(now) {
+		return nil, errors.New("created_at is in the future")
+	}
+	if updatedAt.IsZero() || updatedAt.Before(createdAt) {
+		updatedAt = createdAt
+	}
+
+	return &models.Thought{
+		Content:   content,
+		UserID:    userID,
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+		Tags:      namedTags(tags),
+	}, nil
+}
+
+// parseImportTime parses a timestamp in any of importTimeLayouts. An empty
+// value yields the zero time.
+func parseImportTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range importTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("invalid timestamp")
+}
+
+// parseJSONImport reads an array of items. The object written by the JSON
+// export, with the items under "thoughts", is accepted too.
+func parseJSONImport(r io.Reader) ([]importRow, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.New("Could not read file")
+	}
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		var export struct {
+			Thoughts []json.RawMessage `json:"thoughts"`
+		}
+		if err := json.Unmarshal(data, &export); err != nil || export.Thoughts == nil {
+			return nil, errors.New("Invalid JSON: expected an array of thoughts")
+		}
+		raw = export.Thoughts
+	}
+
+	rows := make([]importRow, len(raw))
+	for i, item := range raw {
+		if err := json.Unmarshal(item, &rows[i].item); err != nil {
+			rows[i].err = errors.New("Invalid item")
+		}
+	}
+	return rows, nil
+}
+
+// parseCSVImport reads rows with a header naming the columns. Only content
+// is required; tags are separated by spaces or commas.
+func parseCSVImport(r io.Reader) ([]importRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, errors.New("Invalid CSV: missing header")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["content"]; !ok {
+		return nil, errors.New("Invalid CSV: missing content column")
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var rows []importRow
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, errors.New("Could not read file")
+			}
+			rows = append(rows, importRow{err: errors.New("Malformed CSV row")})
+			continue
+		}
+
+		rows = append(rows, importRow{item: ImportItem{
+			Content:   field(record, "content"),
+			CreatedAt: field(record, "created_at"),
+			UpdatedAt: field(record, "updated_at"),
+			Tags: strings.FieldsFunc(field(record, "tags"), func(r rune) bool {
+				return r == ',' || r == ' '
+			}),
+		}})
+	}
+	return rows, nil
+}
+
+// parseTextImport splits a journal into thoughts. Entries are separated by
+// blank lines; a file without blank lines has one thought per line.
+func parseTextImport(r io.Reader) ([]importRow, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.New("Could not read file")
+	}
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+
+	var entries []string
+	var current []string
+	blankSeparated := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			blankSeparated = true
+			if len(current) > 0 {
+				entries = append(entries, strings.Join(current, "\n"))
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		entries = append(entries, strings.Join(current, "\n"))
+	}
+
+	if !blankSeparated && len(entries) == 1 {
+		entries = strings.Split(entries[0], "\n")
+	}
+
+	rows := make([]importRow, len(entries))
+	for i, entry := range entries {
+		rows[i].item.Content = entry
+	}
+	return rows, nil
+}
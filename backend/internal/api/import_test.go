@@ -0,0 +1,221 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/api"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/store"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+func importFile(t *testing.T, app *fiber.App, token, filename, content string, fields map[string]string) (int, api.ImportReport, map[string]string) {
+	t.Helper()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for name, value := range fields {
+		w.WriteField(name, value)
+	}
+	if filename != "" {
+		part, _ := w.CreateFormFile("file", filename)
+		part.Write([]byte(content))
+	}
+	w.Close()
+
+	req := httptest.NewRequest("POST", "/api/thoughts/import", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var raw json.RawMessage
+	json.NewDecoder(resp.Body).Decode(&raw)
+	var report api.ImportReport
+	var errResp map[string]string
+	json.Unmarshal(raw, &report)
+	json.Unmarshal(raw, &errResp)
+	return resp.StatusCode, report, errResp
+}
+
+func TestImportThoughts(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	app := testutils.SetupTestApp(t, db)
+	token, userID := registerAndLogin(t, app, "test@example.com", "password123")
+
+	thoughtsOf := func() []models.Thought {
+		var thoughts []models.Thought
+		db.Preload("Tags").Where("user_id = ?", userID).Order("id").Find(&thoughts)
+		db.Where("user_id = ?", userID).Delete(&models.Thought{})
+		return thoughts
+	}
+
+	t.Run("json", func(t *testing.T) {
+		file := `[
+			{"content": "First day #journal", "created_at": "2021-03-04T05:06:07Z", "tags": ["Travel"]},
+			{"content": "   "},
+			{"content": 42},
+			{"content": "Later", "created_at": "yesterday"},
+			{"content": "No timestamp"}
+		]`
+		status, report, _ := importFile(t, app, token, "notes.json", file, nil)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, 2, report.Imported)
+		assert.Equal(t, 3, report.Rejected)
+		if !assert.Len(t, report.Results, 5) {
+			return
+		}
+		assert.Equal(t, "accepted", report.Results[0].Status)
+		assert.NotZero(t, report.Results[0].ID)
+		assert.Equal(t, api.ImportResult{Row: 2, Status: "rejected", Error: "Content cannot be empty"}, report.Results[1])
+		assert.Equal(t, api.ImportResult{Row: 3, Status: "rejected", Error: "Invalid item"}, report.Results[2])
+		assert.Equal(t, api.ImportResult{Row: 4, Status: "rejected", Error: "Invalid created_at"}, report.Results[3])
+
+		thoughts := thoughtsOf()
+		if !assert.Len(t, thoughts, 2) {
+			return
+		}
+		assert.True(t, thoughts[0].CreatedAt.Equal(time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)))
+		assert.True(t, thoughts[0].UpdatedAt.Equal(thoughts[0].CreatedAt))
+		assert.ElementsMatch(t, []string{"journal", "travel"}, tagNames(thoughts[0]))
+		assert.WithinDuration(t, time.Now(), thoughts[1].CreatedAt, time.Minute)
+	})
+
+	t.Run("json export round trip", func(t *testing.T) {
+		file := `{"user": {"id": 1}, "thoughts": [{"id": 9, "content": "Exported", "tags": ["old"], "created_at": "2020-01-02T03:04:05Z"}]}`
+		status, report, _ := importFile(t, app, token, "export.json", file, nil)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, 1, report.Imported)
+
+		thoughts := thoughtsOf()
+		if assert.Len(t, thoughts, 1) {
+			assert.Equal(t, []string{"old"}, tagNames(thoughts[0]))
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		file := "created_at,content,tags\n" +
+			"2022-05-06,\"Comma, and \"\"quotes\"\"\",\"a b,c\"\n" +
+			"2022-05-07 08:09:10,,\n" +
+			"3000-01-01,From the future,\n"
+		status, report, _ := importFile(t, app, token, "notes.csv", file, nil)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, 1, report.Imported)
+		if !assert.Len(t, report.Results, 3) {
+			return
+		}
+		assert.Equal(t, "Content is required", report.Results[1].Error)
+		assert.Equal(t, "created_at is in the future", report.Results[2].Error)
+
+		thoughts := thoughtsOf()
+		if assert.Len(t, thoughts, 1) {
+			assert.Equal(t, `Comma, and "quotes"`, thoughts[0].Content)
+			assert.True(t, thoughts[0].CreatedAt.Equal(time.Date(2022, 5, 6, 0, 0, 0, 0, time.UTC)))
+			assert.ElementsMatch(t, []string{"a", "b", "c"}, tagNames(thoughts[0]))
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		status, report, _ := importFile(t, app, token, "journal.txt", "Morning walk\nwith the dog\n\n\nEvening #reading\r\n", nil)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, 2, report.Imported)
+
+		thoughts := thoughtsOf()
+		if assert.Len(t, thoughts, 2) {
+			assert.Equal(t, "Morning walk\nwith the dog", thoughts[0].Content)
+			assert.Equal(t, []string{"reading"}, tagNames(thoughts[1]))
+		}
+
+		// Without blank lines every line is a thought
+		status, report, _ = importFile(t, app, token, "lines", "one\ntwo\nthree\n", map[string]string{"format": "text"})
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, 3, report.Imported)
+		thoughtsOf()
+	})
+
+	t.Run("batches", func(t *testing.T) {
+		var file strings.Builder
+		for i := 0; i < 250; i++ {
+			fmt.Fprintf(&file, "Entry %d\n", i)
+		}
+		status, report, _ := importFile(t, app, token, "many.txt", file.String(), nil)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, 250, report.Imported)
+		assert.Len(t, thoughtsOf(), 250)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		status, _, errResp := importFile(t, app, token, "", "", nil)
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Equal(t, "File is required", errResp["detail"])
+
+		status, _, errResp = importFile(t, app, token, "notes.json", "{not json", nil)
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Equal(t, "Invalid JSON: expected an array of thoughts", errResp["detail"])
+
+		status, _, errResp = importFile(t, app, token, "notes.csv", "title\nfoo\n", nil)
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Equal(t, "Invalid CSV: missing content column", errResp["detail"])
+
+		status, _, errResp = importFile(t, app, token, "notes.txt", "x", map[string]string{"format": "xml"})
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Equal(t, "Invalid import format", errResp["detail"])
+	})
+}
+
+// poisonedImports fails any import that contains a "poison" thought, as a
+// row breaking a database constraint would
+type poisonedImports struct {
+	*store.Memory
+}
+
+func (s poisonedImports) ImportThoughts(ctx context.Context, thoughts []*models.Thought) error {
+	for _, thought := range thoughts {
+		if strings.Contains(thought.Content, "poison") {
+			return errors.New("constraint failed")
+		}
+	}
+	return s.Memory.ImportThoughts(ctx, thoughts)
+}
+
+func TestImportRetriesFailedBatchRowByRow(t *testing.T) {
+	var logs bytes.Buffer
+	memory := store.NewMemory()
+	app := testutils.SetupTestAppWithServices(t, nil, api.Services{
+		Users:    memory,
+		Thoughts: poisonedImports{memory},
+		Logger:   slog.New(slog.NewJSONHandler(&logs, nil)),
+	})
+	token, userID := registerAndLogin(t, app, "import@example.com", "password123")
+
+	status, report, _ := importFile(t, app, token, "journal.txt", "first\npoison\nthird\n", nil)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, 2, report.Imported)
+	assert.Equal(t, 1, report.Rejected)
+	if assert.Len(t, report.Results, 3) {
+		assert.Equal(t, "accepted", report.Results[0].Status)
+		assert.Equal(t, api.ImportResult{Row: 2, Status: "rejected", Error: "Could not save thought"}, report.Results[1])
+		assert.Equal(t, "accepted", report.Results[2].Status)
+	}
+
+	thoughts, err := memory.ListThoughts(context.Background(), userID, store.ListOptions{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, thoughts, 2)
+
+	assert.Contains(t, logs.String(), `"msg":"Could not import thoughts"`)
+	assert.Contains(t, logs.String(), `"error":"constraint failed"`)
+}
@@ -0,0 +1,147 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/api"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/store"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+// sendJSON sends an authenticated request and decodes the response into out
+func sendJSON(t *testing.T, app *fiber.App, method, path, token string, payload, out interface{}) int {
+	t.Helper()
+
+	var body bytes.Buffer
+	if payload != nil {
+		json.NewEncoder(&body).Encode(payload)
+	}
+	req := httptest.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+// TestHandlersWithMemoryStore runs the thought handlers without a database
+func TestHandlersWithMemoryStore(t *testing.T) {
+	app, memory := testutils.SetupMemoryApp(t)
+	token, userID := registerAndLogin(t, app, "memory@example.com", "password123")
+	otherToken, _ := registerAndLogin(t, app, "other@example.com", "password123")
+
+	var first, second models.Thought
+	status := sendJSON(t, app, "POST", "/api/thoughts", token, map[string]interface{}{
+		"content": "Planning the #work offsite",
+	}, &first)
+	assert.Equal(t, fiber.StatusCreated, status)
+	assert.Equal(t, userID, first.UserID)
+	assert.Equal(t, []string{"work"}, tagNames(first))
+
+	status = sendJSON(t, app, "POST", "/api/thoughts", token, map[string]interface{}{
+		"content": "Groceries for the weekend",
+		"tags":    []string{"home", "work"},
+	}, &second)
+	assert.Equal(t, fiber.StatusCreated, status)
+
+	t.Run("list pages newest first", func(t *testing.T) {
+		var page api.ThoughtPage
+		status := sendJSON(t, app, "GET", "/api/thoughts?limit=1", token, nil, &page)
+		assert.Equal(t, fiber.StatusOK, status)
+		if assert.Len(t, page.Thoughts, 1) {
+			assert.Equal(t, second.ID, page.Thoughts[0].ID)
+		}
+		assert.NotEmpty(t, page.NextCursor)
+
+		var last api.ThoughtPage
+		status = sendJSON(t, app, "GET", "/api/thoughts?limit=1&cursor="+page.NextCursor, token, nil, &last)
+		assert.Equal(t, fiber.StatusOK, status)
+		if assert.Len(t, last.Thoughts, 1) {
+			assert.Equal(t, first.ID, last.Thoughts[0].ID)
+		}
+		assert.Empty(t, last.NextCursor)
+	})
+
+	t.Run("tags and tag filter", func(t *testing.T) {
+		var tags struct {
+			Tags []map[string]interface{} `json:"tags"`
+		}
+		sendJSON(t, app, "GET", "/api/tags", token, nil, &tags)
+		assert.Equal(t, []map[string]interface{}{
+			{"name": "work", "count": float64(2)},
+			{"name": "home", "count": float64(1)},
+		}, tags.Tags)
+
+		var page api.ThoughtPage
+		sendJSON(t, app, "GET", "/api/thoughts?tag=home", token, nil, &page)
+		if assert.Len(t, page.Thoughts, 1) {
+			assert.Equal(t, second.ID, page.Thoughts[0].ID)
+		}
+	})
+
+	t.Run("search", func(t *testing.T) {
+		var response api.SearchResponse
+		status := sendJSON(t, app, "GET", "/api/thoughts/search?q=OFFSITE", token, nil, &response)
+		assert.Equal(t, fiber.StatusOK, status)
+		if assert.Len(t, response.Results, 1) {
+			assert.Equal(t, first.ID, response.Results[0].Thought.ID)
+		}
+	})
+
+	t.Run("other users cannot see the thought", func(t *testing.T) {
+		var body map[string]string
+		status := sendJSON(t, app, "GET", "/api/thoughts/"+strconv.Itoa(int(first.ID)), otherToken, nil, &body)
+		assert.Equal(t, fiber.StatusNotFound, status)
+		assert.Equal(t, "Thought not found", body["detail"])
+	})
+
+	t.Run("update keeps explicit tags", func(t *testing.T) {
+		var updated models.Thought
+		status := sendJSON(t, app, "PATCH", "/api/thoughts/"+strconv.Itoa(int(second.ID)), token, map[string]interface{}{
+			"content": "Groceries for the #garden",
+		}, &updated)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.ElementsMatch(t, []string{"garden", "home", "work"}, tagNames(updated))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		status := sendJSON(t, app, "DELETE", "/api/thoughts/"+strconv.Itoa(int(first.ID)), token, nil, nil)
+		assert.Equal(t, fiber.StatusNoContent, status)
+
+		_, err := memory.Thought(context.Background(), userID, first.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}
+
+// TestSessionsWithMemoryStore checks refresh token rotation without a database
+func TestSessionsWithMemoryStore(t *testing.T) {
+	app, _ := testutils.SetupMemoryApp(t)
+	registerAndLogin(t, app, "memory@example.com", "password123")
+	token, refreshToken := loginTokens(t, app, "memory@example.com", "password123")
+
+	status, rotated := refresh(t, app, refreshToken)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.NotEqual(t, refreshToken, rotated["refresh_token"])
+
+	// Replaying the old token revokes the session
+	status, body := refresh(t, app, refreshToken)
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+	assert.Equal(t, "Refresh token reuse detected, session revoked", body["detail"])
+
+	status, _ = getMe(t, app, token)
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+}
@@ -0,0 +1,63 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/api"
+	"github.com/yourusername/backend/internal/metrics"
+	"github.com/yourusername/backend/internal/store"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	memory := store.NewMemory()
+	m := metrics.New()
+	app := testutils.SetupTestAppWithServices(t, nil, api.Services{
+		Users:    memory,
+		Thoughts: memory,
+		Metrics:  m,
+	})
+
+	token, _ := registerAndLogin(t, app, "metrics@example.com", "password123")
+	sendJSON(t, app, "POST", "/api/thoughts", token, map[string]interface{}{"content": "Counted"}, nil)
+	getMe(t, app, "not-a-token")
+	app.Test(httptest.NewRequest("GET", "/api/me", nil))
+
+	// A wrong password and an unknown email both count as failures
+	for _, email := range []string{"metrics@example.com", "nobody@example.com"} {
+		payload, _ := json.Marshal(map[string]string{"email": email, "password": "wrong-password"})
+		req := httptest.NewRequest("POST", "/api/auth/login", bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		app.Test(req)
+	}
+
+	// Metrics are served on their own listener, never on the API
+	resp, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	internal := fiber.New()
+	internal.Get("/metrics", m.Handler())
+	resp, err = internal.Test(httptest.NewRequest("GET", "/metrics", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	body := string(raw)
+
+	assert.Contains(t, body, `thoughts_logins_total{result="success"} 1`)
+	assert.Contains(t, body, `thoughts_logins_total{result="failure"} 2`)
+	assert.Contains(t, body, `thoughts_jwt_validation_failures_total{reason="invalid"} 1`)
+	assert.Contains(t, body, `thoughts_jwt_validation_failures_total{reason="missing"} 1`)
+	assert.Contains(t, body, "thoughts_created_total 1")
+	assert.Contains(t, body, `thoughts_http_requests_total{method="POST",route="/api/thoughts",status="201"} 1`)
+	assert.Contains(t, body, `thoughts_http_requests_total{method="POST",route="/api/auth/login",status="401"} 2`)
+}
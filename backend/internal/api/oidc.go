@@ -0,0 +1,269 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/logging"
+	"github.com/yourusername/backend/internal/metrics"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/sso"
+	"github.com/yourusername/backend/internal/store"
+)
+
+const (
+	// oidcFlowCookie carries the secrets of an external login from its start
+	// to the callback
+	oidcFlowCookie = "oidc_flow"
+	// OIDCLoginTTL is how long a user has to log in at the provider
+	OIDCLoginTTL = 10 * time.Minute
+	// OIDCCallbackPath is the frontend page external logins end on. The
+	// tokens, an MFA challenge token or the error code and detail follow in
+	// the URL fragment.
+	OIDCCallbackPath = "/auth/callback"
+)
+
+// oidcFlow is the content of the flow cookie
+type oidcFlow struct {
+	Provider string `json:"provider"`
+	sso.Flow
+}
+
+// OIDCProviderResponse describes a provider users can log in with
+type OIDCProviderResponse struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	LoginURL    string `json:"login_url"`
+}
+
+// OIDCRedirectURL is the callback URL to register with a provider
+func OIDCRedirectURL(baseURL, provider string) string {
+	return baseURL + "/api/auth/oidc/" + provider + "/callback"
+}
+
+// OIDCProviders lists the providers users can log in with
+func OIDCProviders(c *fiber.Ctx, svc Services) error {
+	providers := make([]OIDCProviderResponse, 0, len(svc.OIDCProviders))
+	for _, p := range svc.OIDCProviders {
+		providers = append(providers, OIDCProviderResponse{
+			Name:        p.Name,
+			DisplayName: p.DisplayName,
+			LoginURL:    svc.BaseURL + "/api/auth/oidc/" + p.Name + "/login",
+		})
+	}
+	return c.JSON(providers)
+}
+
+// OIDCLogin starts a login at the provider. The browser is sent there with
+// a fresh state, nonce and PKCE challenge, whose secrets wait in a cookie
+// for the callback.
+func OIDCLogin(c *fiber.Ctx, svc Services) error {
+	provider := findOIDCProvider(svc, c.Params("provider"))
+	if provider == nil {
+		return apierror.NotFound("Unknown login provider")
+	}
+
+	flow, err := sso.NewFlow()
+	if err != nil {
+		return apierror.Internal("Could not start login", err)
+	}
+	authURL, err := provider.AuthCodeURL(c.UserContext(), flow)
+	if err != nil {
+		unavailable := apierror.New(fiber.StatusBadGateway, apierror.CodeUnavailable, "Login provider is unavailable")
+		unavailable.Err = err
+		return unavailable
+	}
+
+	value, err := json.Marshal(oidcFlow{Provider: provider.Name, Flow: flow})
+	if err != nil {
+		return apierror.Internal("Could not start login", err)
+	}
+	setFlowCookie(c, svc, base64.RawURLEncoding.EncodeToString(value), OIDCLoginTTL)
+
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// OIDCCallback completes a login the provider sent the browser back from.
+// The user linked to the provider's identity is logged in; new identities
+// are linked to a new account, or to the account with the same email when
+// both the provider and the account have verified it. The browser ends up
+// on the frontend's callback page either way, as this is a navigation and
+// not an API call.
+func OIDCCallback(c *fiber.Ctx, svc Services) error {
+	ctx := c.UserContext()
+
+	// Flows are good for one attempt
+	cookie := c.Cookies(oidcFlowCookie)
+	setFlowCookie(c, svc, "", -time.Hour)
+
+	provider := findOIDCProvider(svc, c.Params("provider"))
+	if provider == nil {
+		return oidcFailed(c, svc, apierror.NotFound("Unknown login provider"))
+	}
+	if reason := c.Query("error"); reason != "" {
+		return oidcFailed(c, svc, apierror.LoginFailed("Login was cancelled at the provider", errors.New(reason)))
+	}
+
+	flow, ok := decodeFlow(cookie)
+	if !ok || flow.Provider != provider.Name ||
+		subtle.ConstantTimeCompare([]byte(flow.State), []byte(c.Query("state"))) != 1 {
+		return oidcFailed(c, svc, apierror.LoginFailed("Login expired or was started in another browser, try again", nil))
+	}
+
+	claims, err := provider.Exchange(ctx, c.Query("code"), flow.Flow)
+	if err != nil {
+		return oidcFailed(c, svc, apierror.LoginFailed("Login provider could not confirm the login", err))
+	}
+
+	user, err := oidcUser(ctx, svc, provider.Name, claims)
+	if err != nil {
+		return oidcFailed(c, svc, err)
+	}
+
+	if user.DeletionDueAt != nil && !user.DeletionDueAt.After(time.Now()) {
+		return oidcFailed(c, svc, apierror.NotFound("Account not found"))
+	}
+
+	// The provider stands in for the password only; the second factor is
+	// still asked for
+	twoFactor, err := userTwoFactor(ctx, svc, user.ID)
+	if err != nil {
+		return oidcFailed(c, svc, apierror.Internal("Could not log in", err))
+	}
+	if twoFactor.Enabled() {
+		challenge, err := createChallenge(svc.Keys, user.ID)
+		if err != nil {
+			return oidcFailed(c, svc, apierror.Internal("Could not create token", err))
+		}
+		fragment := url.Values{"mfa_token": {challenge.MFAToken}}
+		return c.Redirect(svc.AppURL+OIDCCallbackPath+"#"+fragment.Encode(), fiber.StatusFound)
+	}
+
+	// Logging in during the deletion grace period restores the account
+	if user.DeletionDueAt != nil {
+		if err := svc.Users.CancelDeletion(ctx, user.ID); err != nil {
+			return oidcFailed(c, svc, apierror.Internal("Could not restore account", err))
+		}
+	}
+
+	tokens, err := issueTokens(ctx, svc, user.ID)
+	if err != nil {
+		return oidcFailed(c, svc, apierror.Internal("Could not create token", err))
+	}
+	svc.Metrics.Login(metrics.LoginSuccess)
+
+	fragment := url.Values{"token": {tokens.Token}, "refresh_token": {tokens.RefreshToken}}
+	return c.Redirect(svc.AppURL+OIDCCallbackPath+"#"+fragment.Encode(), fiber.StatusFound)
+}
+
+// oidcUser returns the account of the identity the provider vouched for,
+// linking or creating it on the first login
+func oidcUser(ctx context.Context, svc Services, provider string, claims *sso.Claims) (*models.User, error) {
+	now := time.Now()
+	user, err := svc.Users.UserByIdentity(ctx, provider, claims.Subject, claims.Email, now)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, apierror.Internal("Could not log in", err)
+	}
+
+	if claims.Email == "" {
+		return nil, apierror.LoginFailed("Login provider did not share an email address", nil)
+	}
+	identity := &models.Identity{Provider: provider, Subject: claims.Subject, Email: claims.Email, LastLoginAt: now}
+	emailTaken := apierror.New(fiber.StatusBadRequest, apierror.CodeEmailTaken, "An account with this email exists, log in with its password")
+
+	// Linking by email hands the account to whoever controls the address at
+	// the provider, so both sides must have verified it. Otherwise an
+	// attacker could pre-register an address, or claim it at a provider
+	// that does not check ownership, and share the account.
+	existing, err := svc.Users.UserByEmail(ctx, claims.Email)
+	switch {
+	case err == nil:
+		if !claims.EmailVerified || !existing.EmailVerified {
+			return nil, emailTaken
+		}
+		identity.UserID = existing.ID
+		if err := svc.Users.LinkIdentity(ctx, identity); err != nil {
+			return nil, apierror.Internal("Could not link account", err)
+		}
+		return existing, nil
+	case !errors.Is(err, store.ErrNotFound):
+		return nil, apierror.Internal("Could not log in", err)
+	}
+
+	// The account has no password until a password reset sets one; the
+	// provider confirms the user's identity instead, see confirmIdentity
+	user = &models.User{Email: claims.Email, EmailVerified: claims.EmailVerified}
+	if err := svc.Users.CreateUserWithIdentity(ctx, user, identity); err != nil {
+		if errors.Is(err, store.ErrEmailTaken) {
+			return nil, emailTaken
+		}
+		return nil, apierror.Internal("Could not create user", err)
+	}
+
+	if !user.EmailVerified {
+		if err := sendVerificationEmail(ctx, svc, user); err != nil {
+			logging.FromContext(ctx).Error("Could not send verification email", "user_id", user.ID, "error", err)
+		}
+	}
+	return user, nil
+}
+
+// oidcFailed sends the browser to the frontend's callback page with the
+// error code and detail, logging the cause
+func oidcFailed(c *fiber.Ctx, svc Services, err error) error {
+	apiErr := apierror.From(err)
+	logger := logging.FromContext(c.UserContext())
+	if apiErr.Status >= fiber.StatusInternalServerError {
+		logger.Error("External login failed", "code", apiErr.Code, "error", apiErr.Err)
+	} else {
+		logger.Warn("External login failed", "code", apiErr.Code, "error", apiErr.Err)
+	}
+	svc.Metrics.Login(metrics.LoginFailure)
+
+	fragment := url.Values{"error": {string(apiErr.Code)}, "detail": {apiErr.Detail}}
+	return c.Redirect(svc.AppURL+OIDCCallbackPath+"#"+fragment.Encode(), fiber.StatusFound)
+}
+
+func findOIDCProvider(svc Services, name string) *sso.Provider {
+	for _, p := range svc.OIDCProviders {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// setFlowCookie stores the flow for the callback, or expires it when
+// maxAge is negative. Lax lets the cookie through on the provider's
+// redirect back, which is a top-level navigation.
+func setFlowCookie(c *fiber.Ctx, svc Services, value string, maxAge time.Duration) {
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    value,
+		Path:     "/api/auth/oidc",
+		MaxAge:   int(maxAge / time.Second),
+		Secure:   strings.HasPrefix(svc.BaseURL, "https://"),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+func decodeFlow(cookie string) (oidcFlow, bool) {
+	var flow oidcFlow
+	data, err := base64.RawURLEncoding.DecodeString(cookie)
+	if err != nil || json.Unmarshal(data, &flow) != nil || flow.State == "" {
+		return oidcFlow{}, false
+	}
+	return flow, true
+}
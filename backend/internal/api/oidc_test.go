@@ -0,0 +1,293 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/api"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/mail"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/sso"
+	"github.com/yourusername/backend/internal/testutils"
+	"gorm.io/gorm"
+)
+
+// setupOIDCApp returns an app that logs users in with a mock provider
+// named "mock"
+func setupOIDCApp(t *testing.T, mailer *mail.MemoryMailer) (*fiber.App, *testutils.OIDCProvider) {
+	return setupOIDCAppWithDB(t, testutils.SetupTestDB(t), mailer)
+}
+
+func setupOIDCAppWithDB(t *testing.T, db *gorm.DB, mailer *mail.MemoryMailer) (*fiber.App, *testutils.OIDCProvider) {
+	if mailer == nil {
+		mailer = mail.NewMemoryMailer()
+	}
+	mock := testutils.NewOIDCProvider(t)
+	provider := sso.New(sso.Config{
+		Name:         "mock",
+		DisplayName:  "Mock",
+		Issuer:       mock.Issuer(),
+		ClientID:     mock.ClientID,
+		ClientSecret: mock.ClientSecret,
+		Scopes:       []string{"email"},
+	}, api.OIDCRedirectURL("http://localhost:8080", "mock"))
+
+	app := testutils.SetupTestAppWithServices(t, db, api.Services{
+		Mailer:        mailer,
+		OIDCProviders: []*sso.Provider{provider},
+	})
+	return app, mock
+}
+
+// startOIDCLogin starts a login and returns the provider's authorization
+// URL and the flow cookie
+func startOIDCLogin(t *testing.T, app *fiber.App) (string, *http.Cookie) {
+	t.Helper()
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/auth/oidc/mock/login", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusFound || len(resp.Cookies()) != 1 {
+		t.Fatalf("Unexpected login response %d", resp.StatusCode)
+	}
+	return resp.Header.Get("Location"), resp.Cookies()[0]
+}
+
+// authorizeAtProvider follows the authorization URL and returns the
+// callback URL the provider redirects back to
+func authorizeAtProvider(t *testing.T, authURL string) *url.URL {
+	t.Helper()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("Unexpected authorization response %d", resp.StatusCode)
+	}
+	return callback
+}
+
+// finishOIDCLogin sends the browser back to the callback and returns the
+// fragment of the frontend page it ends on
+func finishOIDCLogin(t *testing.T, app *fiber.App, callback *url.URL, cookie *http.Cookie) url.Values {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", callback.RequestURI(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, fiber.StatusFound, resp.StatusCode)
+
+	location := resp.Header.Get("Location")
+	page, fragment, _ := strings.Cut(location, "#")
+	assert.Equal(t, "http://localhost:3000/auth/callback", page)
+	values, _ := url.ParseQuery(fragment)
+	return values
+}
+
+func oidcLogin(t *testing.T, app *fiber.App) url.Values {
+	t.Helper()
+
+	authURL, cookie := startOIDCLogin(t, app)
+	return finishOIDCLogin(t, app, authorizeAtProvider(t, authURL), cookie)
+}
+
+func TestOIDCProviders(t *testing.T) {
+	app, _ := setupOIDCApp(t, nil)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/auth/oidc/providers", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var providers []api.OIDCProviderResponse
+	json.NewDecoder(resp.Body).Decode(&providers)
+	assert.Equal(t, []api.OIDCProviderResponse{{
+		Name:        "mock",
+		DisplayName: "Mock",
+		LoginURL:    "http://localhost:8080/api/auth/oidc/mock/login",
+	}}, providers)
+}
+
+func TestOIDCLoginProvisionsAccount(t *testing.T) {
+	mailer := mail.NewMemoryMailer()
+	app, mock := setupOIDCApp(t, mailer)
+
+	fragment := oidcLogin(t, app)
+	if !assert.NotEmpty(t, fragment.Get("token"), fragment.Get("detail")) {
+		return
+	}
+	assert.NotEmpty(t, fragment.Get("refresh_token"))
+
+	status, me := getMe(t, app, fragment.Get("token"))
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "sso@example.com", me["email"])
+	assert.Equal(t, true, me["email_verified"])
+	userID := me["id"]
+
+	// The provider verified the email, so no verification email is sent
+	_, sent := mailer.Last("sso@example.com")
+	assert.False(t, sent)
+
+	// Logging in again returns to the same account, even after the email
+	// changed at the provider
+	mock.SetUser(testutils.OIDCUser{Subject: "provider-user-1", Email: "renamed@example.com", EmailVerified: true})
+	fragment = oidcLogin(t, app)
+	status, me = getMe(t, app, fragment.Get("token"))
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, userID, me["id"])
+}
+
+func TestOIDCLoginUnverifiedEmail(t *testing.T) {
+	mailer := mail.NewMemoryMailer()
+	app, mock := setupOIDCApp(t, mailer)
+	mock.SetUser(testutils.OIDCUser{Subject: "unverified", Email: "new@example.com"})
+
+	fragment := oidcLogin(t, app)
+	status, me := getMe(t, app, fragment.Get("token"))
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, false, me["email_verified"])
+
+	// The user verifies the address with us instead
+	status, _ = verify(t, app, lastVerificationToken(t, mailer, "new@example.com"))
+	assert.Equal(t, fiber.StatusOK, status)
+}
+
+func TestOIDCLoginLinksExistingAccount(t *testing.T) {
+	mailer := mail.NewMemoryMailer()
+	app, mock := setupOIDCApp(t, mailer)
+	_, userID := registerAndLogin(t, app, "existing@example.com", "password123")
+
+	// Unverified accounts could belong to anyone who typed the address
+	mock.SetUser(testutils.OIDCUser{Subject: "existing", Email: "existing@example.com", EmailVerified: true})
+	fragment := oidcLogin(t, app)
+	assert.Equal(t, "email_taken", fragment.Get("error"))
+	assert.Empty(t, fragment.Get("token"))
+
+	status, _ := verify(t, app, lastVerificationToken(t, mailer, "existing@example.com"))
+	assert.Equal(t, fiber.StatusOK, status)
+
+	// Nor is an address the provider has not verified linked
+	mock.SetUser(testutils.OIDCUser{Subject: "existing", Email: "existing@example.com"})
+	fragment = oidcLogin(t, app)
+	assert.Equal(t, "email_taken", fragment.Get("error"))
+
+	// Both verified: the identity joins the account
+	mock.SetUser(testutils.OIDCUser{Subject: "existing", Email: "existing@example.com", EmailVerified: true})
+	fragment = oidcLogin(t, app)
+	status, me := getMe(t, app, fragment.Get("token"))
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, float64(userID), me["id"])
+
+	// The password keeps working
+	token, _ := registerAndLogin(t, app, "existing@example.com", "password123")
+	assert.NotEmpty(t, token)
+}
+
+func TestOIDCAccountWithoutPassword(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	app, _ := setupOIDCAppWithDB(t, db, nil)
+
+	fragment := oidcLogin(t, app)
+	token := fragment.Get("token")
+	if !assert.NotEmpty(t, token, fragment.Get("detail")) {
+		return
+	}
+
+	// No password logs in to the account
+	var problem map[string]interface{}
+	status := sendJSON(t, app, "POST", "/api/auth/login", "", map[string]string{"email": "sso@example.com", "password": "anything"}, &problem)
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+	assert.Equal(t, string(apierror.CodeInvalidCredentials), problem["code"])
+
+	// Right after logging in at the provider the session stands in for the
+	// password
+	status = sendJSON(t, app, "POST", "/api/me/2fa/setup", token, api.TwoFactorSetupRequest{}, nil)
+	assert.Equal(t, fiber.StatusOK, status)
+
+	// Later on, and with any password, the user has to log in again
+	startedAt := time.Now().Add(-api.RecentLoginWindow - time.Minute)
+	db.Model(&models.Session{}).Where("1 = 1").Update("created_at", startedAt)
+	for _, password := range []string{"", "password123"} {
+		status, problem = deleteAccount(t, app, token, password)
+		assert.Equal(t, fiber.StatusForbidden, status)
+		assert.Equal(t, string(apierror.CodeReauthRequired), problem["code"])
+	}
+
+	fragment = oidcLogin(t, app)
+	status, _ = deleteAccount(t, app, fragment.Get("token"), "")
+	assert.Equal(t, fiber.StatusNoContent, status)
+}
+
+func TestOIDCCallbackRejects(t *testing.T) {
+	app, _ := setupOIDCApp(t, nil)
+
+	t.Run("missing cookie", func(t *testing.T) {
+		authURL, _ := startOIDCLogin(t, app)
+		fragment := finishOIDCLogin(t, app, authorizeAtProvider(t, authURL), nil)
+		assert.Equal(t, "login_failed", fragment.Get("error"))
+		assert.Empty(t, fragment.Get("token"))
+	})
+
+	t.Run("cookie of another login", func(t *testing.T) {
+		authURL, _ := startOIDCLogin(t, app)
+		_, otherCookie := startOIDCLogin(t, app)
+		fragment := finishOIDCLogin(t, app, authorizeAtProvider(t, authURL), otherCookie)
+		assert.Equal(t, "login_failed", fragment.Get("error"))
+	})
+
+	t.Run("cancelled at the provider", func(t *testing.T) {
+		_, cookie := startOIDCLogin(t, app)
+		callback, _ := url.Parse("/api/auth/oidc/mock/callback?error=access_denied")
+		fragment := finishOIDCLogin(t, app, callback, cookie)
+		assert.Equal(t, "login_failed", fragment.Get("error"))
+		assert.Equal(t, "Login was cancelled at the provider", fragment.Get("detail"))
+	})
+
+	t.Run("callback replayed", func(t *testing.T) {
+		authURL, cookie := startOIDCLogin(t, app)
+		callback := authorizeAtProvider(t, authURL)
+		fragment := finishOIDCLogin(t, app, callback, cookie)
+		assert.NotEmpty(t, fragment.Get("token"))
+
+		fragment = finishOIDCLogin(t, app, callback, cookie)
+		assert.Equal(t, "login_failed", fragment.Get("error"))
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/auth/oidc/other/login", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestOIDCProviderUnavailable(t *testing.T) {
+	app, mock := setupOIDCApp(t, nil)
+	mock.Server.Close()
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/auth/oidc/mock/login", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, fiber.StatusBadGateway, resp.StatusCode)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/store"
+)
+
+const (
+	// DefaultPageLimit is the page size used when no limit is requested
+	DefaultPageLimit = 50
+	// MaxPageLimit caps the page size a client may request
+	MaxPageLimit = 100
+)
+
+// ThoughtPage is the response envelope for paginated thought listings
+type ThoughtPage struct {
+	Thoughts   []models.Thought `json:"thoughts"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// pageCursor identifies the last thought of a page. Thoughts are ordered by
+// (created_at, id) descending, so the cursor stays stable while new thoughts
+// are inserted at the head of the list.
+type pageCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint      `json:"id"`
+}
+
+// encodeCursor returns the opaque cursor pointing after the given thought
+func encodeCursor(thought models.Thought) string {
+	data, _ := json.Marshal(pageCursor{CreatedAt: thought.CreatedAt, ID: thought.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor produced by encodeCursor
+func decodeCursor(cursor string) (*store.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var pc pageCursor
+	if err := json.Unmarshal(data, &pc); err != nil {
+		return nil, err
+	}
+	if pc.ID == 0 || pc.CreatedAt.IsZero() {
+		return nil, errors.New("incomplete cursor")
+	}
+
+	return &store.Cursor{CreatedAt: pc.CreatedAt, ID: pc.ID}, nil
+}
+
+// parsePageLimit reads the limit query parameter, applying the default and cap
+func parsePageLimit(c *fiber.Ctx) (int, error) {
+	raw := c.Query("limit")
+	if raw == "" {
+		return DefaultPageLimit, nil
+	}
+
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		return 0, errors.New("invalid limit")
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+
+	return limit, nil
+}
+
+// paginateThoughts lists one page of the user's thoughts and the cursor
+// of the next page, if there is one
+func paginateThoughts(ctx context.Context, thoughts store.ThoughtStore, userID uint, opts store.ListOptions) (*ThoughtPage, error) {
+	// Fetch one extra row to find out whether another page exists
+	limit := opts.Limit
+	opts.Limit++
+	rows, err := thoughts.ListThoughts(ctx, userID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ThoughtPage{Thoughts: rows}
+	if len(rows) > limit {
+		page.Thoughts = rows[:limit]
+		page.NextCursor = encodeCursor(page.Thoughts[limit-1])
+	}
+
+	return page, nil
+}
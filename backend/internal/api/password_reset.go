@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/jobs"
+	"github.com/yourusername/backend/internal/logging"
+	"github.com/yourusername/backend/internal/mail"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/store"
+)
+
+const (
+	// PasswordResetTTL is how long an emailed reset link works
+	PasswordResetTTL = time.Hour
+	// PasswordResetLimit is how many reset emails an account gets per window
+	PasswordResetLimit = 3
+	// PasswordResetWindow is the window PasswordResetLimit applies to
+	PasswordResetWindow = time.Hour
+)
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6"`
+}
+
+// ForgotPassword emails a password reset link. The account is looked up and
+// the link stored and sent in a background job, so the response and its
+// timing are the same whether or not the account exists, and it cannot be
+// used to probe emails.
+func ForgotPassword(c *fiber.Ctx, svc Services) error {
+	var req ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apierror.BadRequest("Invalid request")
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return apierror.Validation(err)
+	}
+
+	err := enqueue(c.UserContext(), svc, "password reset", func(ctx context.Context) error {
+		return requestPasswordReset(ctx, svc, req.Email)
+	})
+	if err != nil {
+		logging.FromContext(c.UserContext()).Error("Could not request password reset", "error", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "If an account exists for that email, a password reset link has been sent",
+	})
+}
+
+// requestPasswordReset emails a reset link to the account with the email,
+// if there is one. Requests over the limit are dropped silently.
+func requestPasswordReset(ctx context.Context, svc Services, email string) error {
+	user, err := svc.Users.UserByEmail(ctx, email)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	recent, err := svc.Users.CountPasswordResets(ctx, user.ID, time.Now().Add(-PasswordResetWindow))
+	if err != nil {
+		return err
+	}
+	if recent >= PasswordResetLimit {
+		return nil
+	}
+
+	return sendPasswordResetEmail(ctx, svc, user)
+}
+
+// enqueue runs the job in the background on svc.Jobs, or right away when
+// there is no job queue
+func enqueue(ctx context.Context, svc Services, name string, job jobs.Job) error {
+	if svc.Jobs == nil {
+		return job(ctx)
+	}
+	return svc.Jobs.Enqueue(ctx, name, job)
+}
+
+// ResetPassword sets a new password using an emailed reset token and logs
+// the user out of every existing session
+func ResetPassword(c *fiber.Ctx, svc Services) error {
+	var req ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apierror.BadRequest("Invalid request")
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return apierror.Validation(err)
+	}
+
+	// Hash up front so the store can swap the password in the same
+	// transaction that claims the token
+	var hashed models.User
+	if err := hashed.SetPassword(c.UserContext(), req.Password); err != nil {
+		return apierror.Internal("Could not reset password", err)
+	}
+
+	err := svc.Users.ResetPassword(c.UserContext(), hashToken(req.Token), hashed.Password)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidToken) {
+			return apierror.New(fiber.StatusBadRequest, apierror.CodeInvalidToken, "Invalid or expired reset token")
+		}
+		return apierror.Internal("Could not reset password", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Password has been reset",
+	})
+}
+
+// sendPasswordResetEmail stores a new reset token for the user and emails
+// the link to the frontend reset page
+func sendPasswordResetEmail(ctx context.Context, svc Services, user *models.User) error {
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	reset := models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(PasswordResetTTL),
+	}
+	if err := svc.Users.CreatePasswordReset(ctx, &reset); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", svc.AppURL, url.QueryEscape(token))
+	return svc.Mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your Thoughts account.\n\n"+
+			"Open the link below to choose a new password:\n\n%s\n\n"+
+			"The link expires in %d minutes and can be used once. If you did not ask for this, you can ignore this email.\n",
+			link, int(PasswordResetTTL.Minutes())),
+	})
+}
@@ -0,0 +1,175 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/api"
+	"github.com/yourusername/backend/internal/jobs"
+	"github.com/yourusername/backend/internal/mail"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+var resetLinkPattern = regexp.MustCompile(`/reset-password\?token=([A-Za-z0-9_-]+)`)
+
+func postJSON(t *testing.T, app *fiber.App, path string, payload interface{}) (int, map[string]string) {
+	t.Helper()
+
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest("POST", path, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result map[string]string
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func resetEmails(mailer *mail.MemoryMailer, to string) []mail.Message {
+	var out []mail.Message
+	for _, msg := range mailer.Messages() {
+		if msg.To == to && msg.Subject == "Reset your password" {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+func lastResetToken(t *testing.T, mailer *mail.MemoryMailer, email string) string {
+	t.Helper()
+
+	msgs := resetEmails(mailer, email)
+	if len(msgs) == 0 {
+		t.Fatalf("No reset email sent to %s", email)
+	}
+	match := resetLinkPattern.FindStringSubmatch(msgs[len(msgs)-1].Body)
+	if match == nil {
+		t.Fatalf("No reset link in email: %s", msgs[len(msgs)-1].Body)
+	}
+	return match[1]
+}
+
+func TestForgotPassword(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	mailer := mail.NewMemoryMailer()
+	app := testutils.SetupTestAppWithServices(t, db, api.Services{Mailer: mailer})
+	registerAndLogin(t, app, "test@example.com", "password123")
+
+	t.Run("known and unknown emails look the same", func(t *testing.T) {
+		status, known := postJSON(t, app, "/api/auth/forgot-password", map[string]string{"email": "test@example.com"})
+		assert.Equal(t, fiber.StatusOK, status)
+
+		status, unknown := postJSON(t, app, "/api/auth/forgot-password", map[string]string{"email": "nobody@example.com"})
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, known, unknown)
+
+		assert.Len(t, resetEmails(mailer, "test@example.com"), 1)
+		assert.Empty(t, resetEmails(mailer, "nobody@example.com"))
+	})
+
+	t.Run("rate limited per email", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			status, _ := postJSON(t, app, "/api/auth/forgot-password", map[string]string{"email": "test@example.com"})
+			assert.Equal(t, fiber.StatusOK, status)
+		}
+		assert.Len(t, resetEmails(mailer, "test@example.com"), api.PasswordResetLimit)
+
+		var stored int64
+		db.Model(&models.PasswordReset{}).Count(&stored)
+		assert.Equal(t, int64(api.PasswordResetLimit), stored)
+	})
+
+	t.Run("invalid email", func(t *testing.T) {
+		status, result := postJSON(t, app, "/api/auth/forgot-password", map[string]string{"email": "not-an-email"})
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Equal(t, "Invalid email format", result["detail"])
+	})
+}
+
+func TestForgotPasswordInBackground(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	mailer := mail.NewMemoryMailer()
+	queue := jobs.NewQueue(10)
+	app := testutils.SetupTestAppWithServices(t, db, api.Services{Mailer: mailer, Jobs: queue})
+	registerAndLogin(t, app, "test@example.com", "password123")
+
+	// The handler does the same for both addresses: queue the lookup
+	_, known := postJSON(t, app, "/api/auth/forgot-password", map[string]string{"email": "test@example.com"})
+	_, unknown := postJSON(t, app, "/api/auth/forgot-password", map[string]string{"email": "nobody@example.com"})
+	assert.Equal(t, known, unknown)
+	var stored int64
+	db.Model(&models.PasswordReset{}).Count(&stored)
+	assert.Zero(t, stored)
+	assert.Empty(t, resetEmails(mailer, "test@example.com"))
+
+	ctx, stop := context.WithCancel(context.Background())
+	stop()
+	queue.Run(ctx)
+	db.Model(&models.PasswordReset{}).Count(&stored)
+	assert.Equal(t, int64(1), stored)
+	assert.Len(t, resetEmails(mailer, "test@example.com"), 1)
+	assert.Empty(t, resetEmails(mailer, "nobody@example.com"))
+}
+
+func TestResetPassword(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	mailer := mail.NewMemoryMailer()
+	app := testutils.SetupTestAppWithServices(t, db, api.Services{Mailer: mailer})
+	registerAndLogin(t, app, "test@example.com", "password123")
+	access, refreshToken := loginTokens(t, app, "test@example.com", "password123")
+
+	postJSON(t, app, "/api/auth/forgot-password", map[string]string{"email": "test@example.com"})
+	token := lastResetToken(t, mailer, "test@example.com")
+
+	t.Run("short password", func(t *testing.T) {
+		status, result := postJSON(t, app, "/api/auth/reset-password", map[string]string{"token": token, "password": "short"})
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Equal(t, "Password must be at least 6 characters", result["detail"])
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		status, result := postJSON(t, app, "/api/auth/reset-password", map[string]string{"token": "bogus", "password": "newpassword"})
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Contains(t, result["detail"], "Invalid or expired reset token")
+	})
+
+	t.Run("successful reset", func(t *testing.T) {
+		status, _ := postJSON(t, app, "/api/auth/reset-password", map[string]string{"token": token, "password": "newpassword"})
+		assert.Equal(t, fiber.StatusOK, status)
+
+		// Existing sessions are revoked
+		status, _ = getMe(t, app, access)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+		status, _ = refresh(t, app, refreshToken)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+
+		status, _ = postJSON(t, app, "/api/auth/login", map[string]string{"email": "test@example.com", "password": "password123"})
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+		loginTokens(t, app, "test@example.com", "newpassword")
+	})
+
+	t.Run("token is single use", func(t *testing.T) {
+		status, _ := postJSON(t, app, "/api/auth/reset-password", map[string]string{"token": token, "password": "anotherpassword"})
+		assert.Equal(t, fiber.StatusBadRequest, status)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		postJSON(t, app, "/api/auth/forgot-password", map[string]string{"email": "test@example.com"})
+		expired := lastResetToken(t, mailer, "test@example.com")
+		db.Model(&models.PasswordReset{}).Where("used_at IS NULL").Update("expires_at", time.Now().Add(-time.Minute))
+
+		status, result := postJSON(t, app, "/api/auth/reset-password", map[string]string{"token": expired, "password": "anotherpassword"})
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Contains(t, result["detail"], "Invalid or expired reset token")
+	})
+}
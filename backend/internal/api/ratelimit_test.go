@@ -0,0 +1,118 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/api"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/ratelimit"
+	"github.com/yourusername/backend/internal/store"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+func login(t *testing.T, app *fiber.App, email, password string) (int, apierror.Problem, string) {
+	t.Helper()
+
+	payload, _ := json.Marshal(map[string]string{"email": email, "password": password})
+	req := httptest.NewRequest("POST", "/api/auth/login", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var problem apierror.Problem
+	json.NewDecoder(resp.Body).Decode(&problem)
+	return resp.StatusCode, problem, resp.Header.Get(fiber.HeaderRetryAfter)
+}
+
+func TestLoginLockout(t *testing.T) {
+	memory := store.NewMemory()
+	app := testutils.SetupTestAppWithServices(t, nil, api.Services{
+		Users:    memory,
+		Thoughts: memory,
+		Lockout: ratelimit.NewLockout(ratelimit.NewMemory(), ratelimit.LockoutPolicy{
+			Threshold:   3,
+			Duration:    time.Minute,
+			MaxDuration: time.Hour,
+		}),
+	})
+	registerAndLogin(t, app, "locked@example.com", "password123")
+
+	// A successful login clears earlier failures
+	login(t, app, "locked@example.com", "wrongpassword")
+	login(t, app, "locked@example.com", "wrongpassword")
+	status, _, _ := login(t, app, "locked@example.com", "password123")
+	assert.Equal(t, fiber.StatusOK, status)
+
+	for i := 0; i < 3; i++ {
+		status, problem, _ := login(t, app, "locked@example.com", "wrongpassword")
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+		assert.Equal(t, apierror.CodeInvalidCredentials, problem.Code)
+	}
+
+	// Locked now, even with the right password and whatever the case
+	status, problem, retryAfter := login(t, app, "Locked@Example.com", "password123")
+	assert.Equal(t, fiber.StatusTooManyRequests, status)
+	assert.Equal(t, apierror.CodeAccountLocked, problem.Code)
+	assert.Equal(t, "60", retryAfter)
+
+	// Unknown accounts lock the same way, so lockouts reveal nothing
+	for i := 0; i < 3; i++ {
+		login(t, app, "nobody@example.com", "wrongpassword")
+	}
+	status, problem, _ = login(t, app, "nobody@example.com", "wrongpassword")
+	assert.Equal(t, fiber.StatusTooManyRequests, status)
+	assert.Equal(t, apierror.CodeAccountLocked, problem.Code)
+
+	// Other accounts are unaffected
+	registerAndLogin(t, app, "free@example.com", "password123")
+}
+
+func TestRouteGroupRateLimits(t *testing.T) {
+	memory := store.NewMemory()
+	app := testutils.SetupTestAppWithServices(t, nil, api.Services{
+		Users:       memory,
+		Thoughts:    memory,
+		RateLimiter: ratelimit.New(ratelimit.NewMemory()),
+		RateLimits: api.RateLimits{
+			Auth:   ratelimit.Limit{Requests: 5, Window: time.Minute},
+			API:    ratelimit.Limit{Requests: 100, Window: time.Minute},
+			Writes: ratelimit.Limit{Requests: 2, Window: time.Minute},
+		},
+	})
+	token, _ := registerAndLogin(t, app, "busy@example.com", "password123")
+	otherToken, _ := registerAndLogin(t, app, "calm@example.com", "password123")
+
+	// Writes are limited per user
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, fiber.StatusCreated, sendJSON(t, app, "POST", "/api/thoughts", token, map[string]string{"content": "Busy"}, nil))
+	}
+	var problem apierror.Problem
+	assert.Equal(t, fiber.StatusTooManyRequests, sendJSON(t, app, "POST", "/api/thoughts", token, map[string]string{"content": "Busy"}, &problem))
+	assert.Equal(t, apierror.CodeRateLimited, problem.Code)
+	assert.Equal(t, fiber.StatusCreated, sendJSON(t, app, "POST", "/api/thoughts", otherToken, map[string]string{"content": "Calm"}, nil))
+
+	// Reads only count towards the wider API limit
+	req := httptest.NewRequest("GET", "/api/thoughts", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "100", resp.Header.Get(ratelimit.HeaderLimit))
+	}
+
+	// The four registrations and logins above used most of the auth bucket
+	status, _, _ := login(t, app, "busy@example.com", "password123")
+	assert.Equal(t, fiber.StatusOK, status)
+	status, problem, retryAfter := login(t, app, "busy@example.com", "password123")
+	assert.Equal(t, fiber.StatusTooManyRequests, status)
+	assert.Equal(t, apierror.CodeRateLimited, problem.Code)
+	assert.Equal(t, "12", retryAfter)
+}
@@ -1,41 +1,204 @@
 package api
 
 import (
+	"log/slog"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/yourusername/backend/internal/auth"
-	"gorm.io/gorm"
+	"github.com/yourusername/backend/internal/health"
+	"github.com/yourusername/backend/internal/jobs"
+	"github.com/yourusername/backend/internal/logging"
+	"github.com/yourusername/backend/internal/mail"
+	"github.com/yourusername/backend/internal/metrics"
+	"github.com/yourusername/backend/internal/ratelimit"
+	"github.com/yourusername/backend/internal/sso"
+	"github.com/yourusername/backend/internal/store"
 )
 
+// Services are the dependencies and settings shared by route handlers
+type Services struct {
+	// Users stores accounts, sessions and password reset tokens
+	Users store.UserStore
+	// Thoughts stores thoughts and their tags
+	Thoughts store.ThoughtStore
+	// Keys signs and verifies access tokens
+	Keys *auth.KeyRing
+	// Mailer delivers account emails such as verification links
+	Mailer mail.Mailer
+	// Jobs runs work that responses must not wait for, or reveal anything
+	// about through their timing; nil runs it before responding
+	Jobs *jobs.Queue
+	// BaseURL is the public URL of the API, used to build links in emails
+	BaseURL string
+	// AppURL is the public URL of the frontend, used for links to its pages
+	AppURL string
+	// RequireVerifiedEmail blocks thought creation until the email is verified
+	RequireVerifiedEmail bool
+	// AccountDeletionGracePeriod delays account deletion so it can be undone
+	// by logging in; zero deletes immediately
+	AccountDeletionGracePeriod time.Duration
+	// Ready reports whether the server accepts traffic; it turns false once
+	// shutdown begins. Nil means always ready.
+	Ready func() bool
+	// Metrics records Prometheus metrics; nil records nothing. They are not
+	// served on the API, since anyone could read them there.
+	Metrics *metrics.Metrics
+	// Health checks the components /health/ready depends on; nil checks
+	// nothing
+	Health *health.Checker
+	// Logger is the base of the per-request loggers handlers log through;
+	// nil uses slog.Default()
+	Logger *slog.Logger
+	// RateLimiter enforces RateLimits; nil disables rate limiting
+	RateLimiter *ratelimit.Limiter
+	RateLimits  RateLimits
+	// Lockout locks accounts after repeated failed logins; nil never locks
+	Lockout *ratelimit.Lockout
+	// OIDCProviders are the OpenID Connect providers users can log in with
+	OIDCProviders []*sso.Provider
+}
+
+// RateLimits are the limits of each route group. Zero limits are disabled.
+type RateLimits struct {
+	// Auth limits the authentication endpoints per client IP
+	Auth ratelimit.Limit
+	// AuthEmail limits logins, registrations and password reset requests
+	// per email address
+	AuthEmail ratelimit.Limit
+	// API limits authenticated requests per user
+	API ratelimit.Limit
+	// Writes limits thought creation and imports per user
+	Writes ratelimit.Limit
+}
+
 // SetupRoutes configures all the routes for the application
-func SetupRoutes(app *fiber.App, db *gorm.DB) {
-	// Health check endpoint
+func SetupRoutes(app *fiber.App, svc Services) {
+	app.Use(logging.Middleware(svc.Logger))
+
+	if svc.Metrics != nil {
+		app.Use(svc.Metrics.Middleware())
+	}
+
+	// Health check endpoints
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.SendString("OK")
 	})
+	app.Get("/health/live", Live)
+	app.Get("/health/ready", func(c *fiber.Ctx) error {
+		return Ready(c, svc)
+	})
+
+	app.Get("/.well-known/jwks.json", func(c *fiber.Ctx) error {
+		return JWKS(c, svc)
+	})
+
+	limiter := svc.RateLimiter
+	authLimit := limiter.Middleware(ratelimit.Rule{Name: "auth", Limit: svc.RateLimits.Auth, Key: ratelimit.ByIP})
+	emailLimit := limiter.Middleware(ratelimit.Rule{Name: "auth_email", Limit: svc.RateLimits.AuthEmail, Key: ratelimit.ByEmail})
+	apiLimit := limiter.Middleware(ratelimit.Rule{Name: "api", Limit: svc.RateLimits.API, Key: ratelimit.ByUser})
+	writeLimit := limiter.Middleware(ratelimit.Rule{Name: "writes", Limit: svc.RateLimits.Writes, Key: ratelimit.ByUser})
 
 	// Auth routes
-	authGroup := app.Group("/api/auth")
-	authGroup.Post("/login", func(c *fiber.Ctx) error {
-		return Login(c, db)
+	authGroup := app.Group("/api/auth", authLimit)
+	authGroup.Post("/login", emailLimit, func(c *fiber.Ctx) error {
+		return Login(c, svc)
+	})
+	authGroup.Post("/login/2fa", func(c *fiber.Ctx) error {
+		return LoginTwoFactor(c, svc)
+	})
+	authGroup.Post("/register", emailLimit, func(c *fiber.Ctx) error {
+		return Register(c, svc)
+	})
+	authGroup.Post("/refresh", func(c *fiber.Ctx) error {
+		return Refresh(c, svc)
+	})
+	authGroup.Post("/logout", auth.Protected(svc.Users, svc.Keys, svc.Metrics), func(c *fiber.Ctx) error {
+		return Logout(c, svc)
+	})
+	authGroup.Post("/forgot-password", emailLimit, func(c *fiber.Ctx) error {
+		return ForgotPassword(c, svc)
+	})
+	authGroup.Post("/reset-password", func(c *fiber.Ctx) error {
+		return ResetPassword(c, svc)
+	})
+	authGroup.Get("/verify", func(c *fiber.Ctx) error {
+		return VerifyEmail(c, svc)
+	})
+	authGroup.Get("/oidc/providers", func(c *fiber.Ctx) error {
+		return OIDCProviders(c, svc)
 	})
-	authGroup.Post("/register", func(c *fiber.Ctx) error {
-		return Register(c, db)
+	authGroup.Get("/oidc/:provider/login", func(c *fiber.Ctx) error {
+		return OIDCLogin(c, svc)
+	})
+	authGroup.Get("/oidc/:provider/callback", func(c *fiber.Ctx) error {
+		return OIDCCallback(c, svc)
+	})
+	authGroup.Post("/resend-verification", auth.Protected(svc.Users, svc.Keys, svc.Metrics), func(c *fiber.Ctx) error {
+		return ResendVerification(c, svc)
 	})
 
 	// Protected routes
-	api := app.Group("/api", auth.Protected())
+	api := app.Group("/api", auth.Protected(svc.Users, svc.Keys, svc.Metrics), apiLimit)
 
 	// User routes
 	api.Get("/me", func(c *fiber.Ctx) error {
-		return GetCurrentUser(c, db)
+		return GetCurrentUser(c, svc)
+	})
+	api.Put("/me/password", func(c *fiber.Ctx) error {
+		return ChangePassword(c, svc)
+	})
+	api.Put("/me/email", func(c *fiber.Ctx) error {
+		return ChangeEmail(c, svc)
+	})
+	api.Delete("/me", func(c *fiber.Ctx) error {
+		return DeleteAccount(c, svc)
+	})
+	api.Get("/me/export", func(c *fiber.Ctx) error {
+		return ExportData(c, svc)
+	})
+	api.Get("/me/2fa", func(c *fiber.Ctx) error {
+		return TwoFactorStatus(c, svc)
+	})
+	api.Post("/me/2fa/setup", func(c *fiber.Ctx) error {
+		return SetupTwoFactor(c, svc)
+	})
+	api.Post("/me/2fa/confirm", func(c *fiber.Ctx) error {
+		return ConfirmTwoFactor(c, svc)
+	})
+	api.Delete("/me/2fa", func(c *fiber.Ctx) error {
+		return DisableTwoFactor(c, svc)
+	})
+
+	// Tag routes
+	api.Get("/tags", func(c *fiber.Ctx) error {
+		return GetTags(c, svc)
 	})
 
 	// Thoughts routes
 	thoughtsGroup := api.Group("/thoughts")
 	thoughtsGroup.Get("", func(c *fiber.Ctx) error {
-		return GetThoughts(c, db)
+		return GetThoughts(c, svc)
+	})
+	thoughtsGroup.Post("", writeLimit, func(c *fiber.Ctx) error {
+		return CreateThought(c, svc)
+	})
+	thoughtsGroup.Post("/import", writeLimit, func(c *fiber.Ctx) error {
+		return ImportThoughts(c, svc)
+	})
+	thoughtsGroup.Get("/search", func(c *fiber.Ctx) error {
+		return SearchThoughts(c, svc)
+	})
+	thoughtsGroup.Get("/:id", func(c *fiber.Ctx) error {
+		return GetThought(c, svc)
+	})
+	thoughtsGroup.Put("/:id", func(c *fiber.Ctx) error {
+		return UpdateThought(c, svc)
+	})
+	thoughtsGroup.Patch("/:id", func(c *fiber.Ctx) error {
+		return UpdateThought(c, svc)
 	})
-	thoughtsGroup.Post("", func(c *fiber.Ctx) error {
-		return CreateThought(c, db)
+	thoughtsGroup.Delete("/:id", func(c *fiber.Ctx) error {
+		return DeleteThought(c, svc)
 	})
 }
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/auth"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/store"
+)
+
+// SearchResult is a thought matching a search query
+type SearchResult struct {
+	Thought models.Thought `json:"thought"`
+	// Snippet is an HTML-escaped excerpt with matches wrapped in <mark> tags
+	Snippet string `json:"snippet"`
+	// Rank is the bm25 score; lower is a better match
+	Rank float64 `json:"rank"`
+}
+
+// SearchResponse is the response body for thought search
+type SearchResponse struct {
+	Results []SearchResult `json:"results"`
+}
+
+// SearchThoughts runs a full-text search over the authenticated user's thoughts
+func SearchThoughts(c *fiber.Ctx, svc Services) error {
+	user, err := auth.GetUserFromContext(c, svc.Users)
+	if err != nil {
+		return apierror.Unauthorized(apierror.CodeUnauthorized, "Unauthorized")
+	}
+
+	terms := parseSearchQuery(c.Query("q"))
+	if len(terms) == 0 {
+		return apierror.Invalid("q", "required", "Search query is required")
+	}
+
+	limit, err := parsePageLimit(c)
+	if err != nil {
+		return apierror.Invalid("limit", "invalid", "Invalid limit")
+	}
+
+	hits, err := svc.Thoughts.SearchThoughts(c.UserContext(), user.ID, terms, limit)
+	if err != nil {
+		return apierror.Internal("Could not search thoughts", err)
+	}
+
+	results := make([]SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		results = append(results, SearchResult{
+			Thought: hit.Thought,
+			Snippet: renderSnippet(hit.Snippet),
+			Rank:    hit.Rank,
+		})
+	}
+
+	return c.JSON(SearchResponse{Results: results})
+}
+
+// parseSearchQuery splits a user query into words and "quoted phrases". A
+// trailing * turns a word or phrase into a prefix match.
+func parseSearchQuery(q string) []store.SearchTerm {
+	var terms []store.SearchTerm
+	runes := []rune(q)
+
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		var text string
+		if runes[i] == '"' {
+			// Phrase runs to the closing quote, or the end of the query
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			text = string(runes[i+1 : end])
+			i = end + 1
+		} else {
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '"' {
+				end++
+			}
+			text = string(runes[i:end])
+			i = end
+		}
+
+		prefix := strings.HasSuffix(text, "*")
+		if i < len(runes) && runes[i] == '*' {
+			prefix = true
+			i++
+		}
+
+		text = strings.Join(strings.Fields(strings.Trim(text, "*")), " ")
+		if text == "" {
+			continue
+		}
+		terms = append(terms, store.SearchTerm{Text: text, Prefix: prefix})
+	}
+
+	return terms
+}
+
+// renderSnippet escapes a snippet for HTML and turns the highlight markers
+// into <mark> tags
+func renderSnippet(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, store.HighlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, store.HighlightEnd, "</mark>")
+}
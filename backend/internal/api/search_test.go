@@ -0,0 +1,159 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/api"
+	"github.com/yourusername/backend/internal/database"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+func TestSearchThoughts(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	app := testutils.SetupTestApp(t, db)
+	fts := database.FullTextSearchEnabled(db)
+	t.Logf("FTS5 enabled: %v", fts)
+
+	token, userID := registerAndLogin(t, app, "test@example.com", "password123")
+	_, otherID := registerAndLogin(t, app, "other@example.com", "password123")
+
+	createTestThought(t, db, userID, "Went hiking in the mountains today")
+	createTestThought(t, db, userID, "The mountains were covered in snow")
+	createTestThought(t, db, userID, "Remember to buy coffee beans")
+	createTestThought(t, db, userID, "<b>coffee</b> with friends")
+	createTestThought(t, db, otherID, "Secret mountains plan")
+
+	// Edits and deletions must be reflected in the index
+	edited := createTestThought(t, db, userID, "Old draft about sailing")
+	db.Model(&edited).Update("content", "New draft about gardening")
+	deleted := createTestThought(t, db, userID, "Temporary note about volcanoes")
+	db.Delete(&deleted)
+
+	search := func(t *testing.T, q string) (int, api.SearchResponse, map[string]string) {
+		req := httptest.NewRequest("GET", "/api/thoughts/search?q="+url.QueryEscape(q), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result api.SearchResponse
+		var errBody map[string]string
+		if resp.StatusCode == fiber.StatusOK {
+			json.NewDecoder(resp.Body).Decode(&result)
+		} else {
+			json.NewDecoder(resp.Body).Decode(&errBody)
+		}
+		return resp.StatusCode, result, errBody
+	}
+
+	contents := func(result api.SearchResponse) []string {
+		var out []string
+		for _, r := range result.Results {
+			out = append(out, r.Thought.Content)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+		ftsOnly  bool
+	}{
+		{
+			name:     "single word",
+			query:    "mountains",
+			expected: []string{"Went hiking in the mountains today", "The mountains were covered in snow"},
+		},
+		{
+			name:     "phrase",
+			query:    `"covered in snow"`,
+			expected: []string{"The mountains were covered in snow"},
+		},
+		{
+			name:     "prefix",
+			query:    "hik*",
+			expected: []string{"Went hiking in the mountains today"},
+			ftsOnly:  true,
+		},
+		{
+			name:     "all terms must match",
+			query:    "coffee beans",
+			expected: []string{"Remember to buy coffee beans"},
+		},
+		{
+			name:     "operators are treated as text",
+			query:    `coffee OR "unterminated`,
+			expected: nil,
+		},
+		{
+			name:     "updated content is indexed",
+			query:    "gardening",
+			expected: []string{"New draft about gardening"},
+		},
+		{
+			name:     "old content is removed from the index",
+			query:    "sailing",
+			expected: nil,
+		},
+		{
+			name:     "deleted thoughts are removed from the index",
+			query:    "volcanoes",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.ftsOnly && !fts {
+				t.Skip("requires FTS5 (-tags sqlite_fts5)")
+			}
+			status, result, _ := search(t, tt.query)
+			assert.Equal(t, fiber.StatusOK, status)
+			assert.ElementsMatch(t, tt.expected, contents(result))
+		})
+	}
+
+	t.Run("snippets are escaped and highlighted", func(t *testing.T) {
+		if !fts {
+			t.Skip("requires FTS5 (-tags sqlite_fts5)")
+		}
+		status, result, _ := search(t, "coffee")
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Len(t, result.Results, 2)
+		for _, r := range result.Results {
+			assert.Contains(t, r.Snippet, "<mark>coffee</mark>")
+			assert.NotContains(t, r.Snippet, "<b>")
+		}
+	})
+
+	t.Run("ranked by bm25", func(t *testing.T) {
+		if !fts {
+			t.Skip("requires FTS5 (-tags sqlite_fts5)")
+		}
+		createTestThought(t, db, userID, "snow snow snow")
+		status, result, _ := search(t, "snow")
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Len(t, result.Results, 2)
+		assert.Equal(t, "snow snow snow", result.Results[0].Thought.Content)
+		assert.LessOrEqual(t, result.Results[0].Rank, result.Results[1].Rank)
+	})
+
+	t.Run("empty query", func(t *testing.T) {
+		status, _, errBody := search(t, "   ")
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Contains(t, errBody["detail"], "Search query is required")
+	})
+
+	t.Run("unauthorized access", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/thoughts/search?q=coffee", nil)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/auth"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/store"
+)
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token pair
+func Refresh(c *fiber.Ctx, svc Services) error {
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apierror.BadRequest("Invalid request")
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return apierror.Invalid("refresh_token", "required", "Refresh token is required")
+	}
+
+	tokens, err := rotateRefreshToken(c.UserContext(), svc, req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrTokenReused):
+			return apierror.Unauthorized(apierror.CodeSessionRevoked, "Refresh token reuse detected, session revoked")
+		case errors.Is(err, store.ErrInvalidToken):
+			return apierror.Unauthorized(apierror.CodeInvalidToken, "Invalid or expired refresh token")
+		default:
+			return apierror.Internal("Could not refresh token", err)
+		}
+	}
+
+	return c.JSON(tokens)
+}
+
+// Logout revokes the session the access token belongs to, invalidating its
+// refresh token and every access token issued for it
+func Logout(c *fiber.Ctx, svc Services) error {
+	sessionID, ok := c.Locals("sessionID").(string)
+	if !ok {
+		return apierror.Unauthorized(apierror.CodeUnauthorized, "Unauthorized")
+	}
+
+	if err := svc.Users.RevokeSessionFamily(c.UserContext(), sessionID); err != nil {
+		return apierror.Internal("Could not log out", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// issueTokens starts a new session for the user and returns its first tokens
+func issueTokens(ctx context.Context, svc Services, userID uint) (*AuthResponse, error) {
+	familyID, _, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, session, err := newSession()
+	if err != nil {
+		return nil, err
+	}
+	session.UserID = userID
+	session.FamilyID = familyID
+	if err := svc.Users.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return signTokens(svc.Keys, session, refreshToken)
+}
+
+// rotateRefreshToken marks the presented refresh token as used and issues its
+// successor. Presenting an already rotated token means it was stolen or
+// replayed, so the store revokes the whole family.
+func rotateRefreshToken(ctx context.Context, svc Services, refreshToken string) (*AuthResponse, error) {
+	nextToken, next, err := newSession()
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.Users.RotateSession(ctx, hashToken(refreshToken), next); err != nil {
+		return nil, err
+	}
+
+	return signTokens(svc.Keys, next, nextToken)
+}
+
+// newSession returns a new refresh token and the unsaved session storing it
+func newSession() (string, *models.Session, error) {
+	refreshToken, refreshHash, err := newOpaqueToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return refreshToken, &models.Session{
+		TokenHash: refreshHash,
+		ExpiresAt: time.Now().Add(RefreshTokenTTL),
+	}, nil
+}
+
+// signTokens signs an access token for the saved session and pairs it with
+// the session's refresh token
+func signTokens(keys *auth.KeyRing, session *models.Session, refreshToken string) (*AuthResponse, error) {
+	accessToken, err := createToken(keys, session.UserID, session.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// newOpaqueToken returns a random URL-safe token and its hash for storage
+func newOpaqueToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+// hashToken hashes an opaque token. Tokens have 256 bits of entropy, so a
+// fast unsalted hash is enough to make a leaked table useless.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
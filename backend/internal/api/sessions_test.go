@@ -0,0 +1,182 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/auth"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+// loginTokens logs in and returns the access and refresh tokens
+func loginTokens(t *testing.T, app *fiber.App, email, password string) (string, string) {
+	t.Helper()
+
+	payload, _ := json.Marshal(map[string]string{"email": email, "password": password})
+	req := httptest.NewRequest("POST", "/api/auth/login", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result map[string]string
+	json.NewDecoder(resp.Body).Decode(&result)
+	if result["token"] == "" || result["refresh_token"] == "" {
+		t.Fatalf("Login did not return both tokens: %v", result)
+	}
+	return result["token"], result["refresh_token"]
+}
+
+func refresh(t *testing.T, app *fiber.App, refreshToken string) (int, map[string]string) {
+	t.Helper()
+
+	payload, _ := json.Marshal(map[string]string{"refresh_token": refreshToken})
+	req := httptest.NewRequest("POST", "/api/auth/refresh", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result map[string]string
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func getMe(t *testing.T, app *fiber.App, token string) (int, map[string]interface{}) {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", "/api/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func TestRefreshToken(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	app := testutils.SetupTestApp(t, db)
+	registerAndLogin(t, app, "test@example.com", "password123")
+
+	t.Run("rotation issues a new pair", func(t *testing.T) {
+		access, refreshToken := loginTokens(t, app, "test@example.com", "password123")
+
+		status, result := refresh(t, app, refreshToken)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.NotEmpty(t, result["token"])
+		assert.NotEmpty(t, result["refresh_token"])
+		assert.NotEqual(t, refreshToken, result["refresh_token"])
+
+		status, _ = getMe(t, app, result["token"])
+		assert.Equal(t, fiber.StatusOK, status)
+		status, _ = getMe(t, app, access)
+		assert.Equal(t, fiber.StatusOK, status)
+
+		// Refresh tokens are only stored hashed
+		var count int64
+		db.Model(&models.Session{}).Where("token_hash = ?", result["refresh_token"]).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("reuse revokes the whole family", func(t *testing.T) {
+		access, first := loginTokens(t, app, "test@example.com", "password123")
+		otherAccess, _ := loginTokens(t, app, "test@example.com", "password123")
+
+		status, rotated := refresh(t, app, first)
+		assert.Equal(t, fiber.StatusOK, status)
+
+		status, result := refresh(t, app, first)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+		assert.Contains(t, result["detail"], "reuse detected")
+
+		status, _ = refresh(t, app, rotated["refresh_token"])
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+
+		for _, token := range []string{access, rotated["token"]} {
+			status, body := getMe(t, app, token)
+			assert.Equal(t, fiber.StatusUnauthorized, status)
+			assert.Equal(t, "Session has been revoked", body["detail"])
+		}
+
+		// Other sessions of the same user are unaffected
+		status, _ = getMe(t, app, otherAccess)
+		assert.Equal(t, fiber.StatusOK, status)
+	})
+
+	t.Run("expired refresh token", func(t *testing.T) {
+		_, refreshToken := loginTokens(t, app, "test@example.com", "password123")
+		db.Model(&models.Session{}).Where("rotated_at IS NULL").Update("expires_at", time.Now().Add(-time.Minute))
+
+		status, result := refresh(t, app, refreshToken)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+		assert.Contains(t, result["detail"], "Invalid or expired refresh token")
+	})
+
+	t.Run("unknown refresh token", func(t *testing.T) {
+		status, result := refresh(t, app, "not-a-token")
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+		assert.Contains(t, result["detail"], "Invalid or expired refresh token")
+	})
+
+	t.Run("missing refresh token", func(t *testing.T) {
+		status, result := refresh(t, app, "")
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Contains(t, result["detail"], "Refresh token is required")
+	})
+
+	t.Run("access token without session", func(t *testing.T) {
+		signed, _ := testutils.Keys.Sign(auth.Claims{
+			UserID: 1,
+			RegisteredClaims: jwt.RegisteredClaims{
+				NotBefore: jwt.NewNumericDate(time.Now()),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+		})
+
+		status, body := getMe(t, app, signed)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+		assert.Equal(t, "Invalid or expired token", body["detail"])
+	})
+}
+
+func TestLogout(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	app := testutils.SetupTestApp(t, db)
+	registerAndLogin(t, app, "test@example.com", "password123")
+
+	access, refreshToken := loginTokens(t, app, "test@example.com", "password123")
+
+	req := httptest.NewRequest("POST", "/api/auth/logout", nil)
+	req.Header.Set("Authorization", "Bearer "+access)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+
+	status, _ := getMe(t, app, access)
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+
+	status, _ = refresh(t, app, refreshToken)
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+
+	req = httptest.NewRequest("POST", "/api/auth/logout", nil)
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+}
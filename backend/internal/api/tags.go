@@ -0,0 +1,127 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/auth"
+	"github.com/yourusername/backend/internal/models"
+)
+
+const (
+	// MaxTagLength is the longest tag name accepted
+	MaxTagLength = 50
+	// MaxTagsPerThought caps how many tags a thought may carry
+	MaxTagsPerThought = 20
+)
+
+var (
+	// hashtagPattern matches #tag at the start of the content or after a
+	// character that cannot be part of a word, so "a#b" and "&#39;" are ignored
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#])#([\p{L}\p{N}_][\p{L}\p{N}_-]*)`)
+	tagNamePattern = regexp.MustCompile(`^[\p{L}\p{N}_][\p{L}\p{N}_-]*$`)
+)
+
+// GetTags lists the authenticated user's tags with thought counts
+func GetTags(c *fiber.Ctx, svc Services) error {
+	user, err := auth.GetUserFromContext(c, svc.Users)
+	if err != nil {
+		return apierror.Unauthorized(apierror.CodeUnauthorized, "Unauthorized")
+	}
+
+	tags, err := svc.Thoughts.TagCounts(c.UserContext(), user.ID)
+	if err != nil {
+		return apierror.Internal("Could not fetch tags", err)
+	}
+
+	return c.JSON(fiber.Map{"tags": tags})
+}
+
+// extractHashtags returns the normalized #hashtags found in content.
+// Hashtags that cannot be tags, such as overly long ones, are left as plain
+// text rather than failing the thought.
+func extractHashtags(content string) []string {
+	var names []string
+	for _, match := range hashtagPattern.FindAllStringSubmatch(content, -1) {
+		if name, err := normalizeTag(match[1]); err == nil {
+			names = append(names, name)
+		}
+	}
+	tags, _ := normalizeTags(names)
+	return tags
+}
+
+// normalizeTags normalizes and de-duplicates tag names, rejecting the first
+// name that is not a valid tag
+func normalizeTags(names []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, name := range names {
+		name, err := normalizeTag(name)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		tags = append(tags, name)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// normalizeTag lowercases a tag name and strips a leading #, rejecting names
+// that could not have come from a hashtag. Blank names normalize to "".
+func normalizeTag(name string) (string, error) {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	if name == "" {
+		return "", nil
+	}
+	if !tagNamePattern.MatchString(name) || len([]rune(name)) > MaxTagLength {
+		return "", fmt.Errorf("Invalid tag: %s", name)
+	}
+	return name, nil
+}
+
+// mergeTags combines the hashtags in content with explicitly requested tags
+func mergeTags(content string, explicit []string) ([]string, error) {
+	tags, err := normalizeTags(append(extractHashtags(content), explicit...))
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) > MaxTagsPerThought {
+		return nil, fmt.Errorf("Too many tags (maximum %d)", MaxTagsPerThought)
+	}
+	return tags, nil
+}
+
+// explicitTags returns the thought's current tags that did not come from
+// hashtags in its content, so they survive a content edit
+func explicitTags(thought *models.Thought) []string {
+	fromContent := make(map[string]bool)
+	for _, name := range extractHashtags(thought.Content) {
+		fromContent[name] = true
+	}
+
+	var names []string
+	for _, tag := range thought.Tags {
+		if !fromContent[tag.Name] {
+			names = append(names, tag.Name)
+		}
+	}
+	return names
+}
+
+// namedTags turns tag names into the unsaved tags the thought store expects
+func namedTags(names []string) []models.Tag {
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, models.Tag{Name: name})
+	}
+	return tags
+}
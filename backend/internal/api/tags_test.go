@@ -0,0 +1,198 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+func tagNames(thought models.Thought) []string {
+	var names []string
+	for _, tag := range thought.Tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func TestThoughtTags(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	app := testutils.SetupTestApp(t, db)
+
+	token, _ := registerAndLogin(t, app, "test@example.com", "password123")
+	otherToken, _ := registerAndLogin(t, app, "other@example.com", "password123")
+
+	send := func(t *testing.T, method, path, token string, payload interface{}) (int, []byte) {
+		var body *bytes.Buffer
+		if payload != nil {
+			data, _ := json.Marshal(payload)
+			body = bytes.NewBuffer(data)
+		} else {
+			body = &bytes.Buffer{}
+		}
+		req := httptest.NewRequest(method, path, body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := new(bytes.Buffer)
+		buf.ReadFrom(resp.Body)
+		return resp.StatusCode, buf.Bytes()
+	}
+
+	create := func(t *testing.T, token string, payload map[string]interface{}) models.Thought {
+		status, body := send(t, "POST", "/api/thoughts", token, payload)
+		if status != fiber.StatusCreated {
+			t.Fatalf("Failed to create thought: %d %s", status, body)
+		}
+		var thought models.Thought
+		json.Unmarshal(body, &thought)
+		return thought
+	}
+
+	t.Run("CreateThought", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			payload        map[string]interface{}
+			expectedStatus int
+			expectedTags   []string
+			expectedError  string
+		}{
+			{
+				name:           "hashtags are extracted",
+				payload:        map[string]interface{}{"content": "Ship it #Work #release-1 #work"},
+				expectedStatus: fiber.StatusCreated,
+				expectedTags:   []string{"release-1", "work"},
+			},
+			{
+				name:           "explicit tags are merged",
+				payload:        map[string]interface{}{"content": "Plan trip #travel", "tags": []string{"#Ideas", "travel"}},
+				expectedStatus: fiber.StatusCreated,
+				expectedTags:   []string{"ideas", "travel"},
+			},
+			{
+				name:           "non-hashtags are ignored",
+				payload:        map[string]interface{}{"content": "email me at a#b or see issue &#39; # alone"},
+				expectedStatus: fiber.StatusCreated,
+				expectedTags:   nil,
+			},
+			{
+				name:           "invalid hashtags are skipped, valid ones kept",
+				payload:        map[string]interface{}{"content": "Ship it #work #" + strings.Repeat("a", 51) + " #release"},
+				expectedStatus: fiber.StatusCreated,
+				expectedTags:   []string{"release", "work"},
+			},
+			{
+				name:           "invalid explicit tag",
+				payload:        map[string]interface{}{"content": "Hello", "tags": []string{"bad tag"}},
+				expectedStatus: fiber.StatusBadRequest,
+				expectedError:  "Invalid tag",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, body := send(t, "POST", "/api/thoughts", token, tt.payload)
+				assert.Equal(t, tt.expectedStatus, status)
+
+				if tt.expectedError != "" {
+					var result map[string]string
+					json.Unmarshal(body, &result)
+					assert.Contains(t, result["detail"], tt.expectedError)
+					return
+				}
+
+				var thought models.Thought
+				json.Unmarshal(body, &thought)
+				assert.Equal(t, tt.expectedTags, tagNames(thought))
+			})
+		}
+	})
+
+	t.Run("too many tags", func(t *testing.T) {
+		var tags []string
+		for i := 0; i < 21; i++ {
+			tags = append(tags, fmt.Sprintf("tag%d", i))
+		}
+		status, body := send(t, "POST", "/api/thoughts", token, map[string]interface{}{"content": "Busy", "tags": tags})
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Contains(t, string(body), "Too many tags")
+	})
+
+	t.Run("UpdateThought", func(t *testing.T) {
+		thought := create(t, token, map[string]interface{}{"content": "Draft #old", "tags": []string{"pinned"}})
+		path := fmt.Sprintf("/api/thoughts/%d", thought.ID)
+
+		// Editing content keeps explicit tags and re-extracts hashtags
+		status, body := send(t, "PATCH", path, token, map[string]interface{}{"content": "Final #new"})
+		assert.Equal(t, fiber.StatusOK, status)
+		var updated models.Thought
+		json.Unmarshal(body, &updated)
+		assert.Equal(t, []string{"new", "pinned"}, tagNames(updated))
+
+		// Replacing tags keeps hashtags from the content
+		status, body = send(t, "PATCH", path, token, map[string]interface{}{"tags": []string{}})
+		assert.Equal(t, fiber.StatusOK, status)
+		updated = models.Thought{}
+		json.Unmarshal(body, &updated)
+		assert.Equal(t, []string{"new"}, tagNames(updated))
+	})
+
+	t.Run("filter and list", func(t *testing.T) {
+		db.Exec("DELETE FROM thought_tags")
+		db.Exec("DELETE FROM thoughts")
+		db.Exec("DELETE FROM tags")
+
+		create(t, token, map[string]interface{}{"content": "Standup #work"})
+		create(t, token, map[string]interface{}{"content": "Review #work #code"})
+		doomed := create(t, token, map[string]interface{}{"content": "Gone #work"})
+		create(t, token, map[string]interface{}{"content": "Groceries #home"})
+		create(t, otherToken, map[string]interface{}{"content": "Not yours #work"})
+
+		status, _ := send(t, "DELETE", fmt.Sprintf("/api/thoughts/%d", doomed.ID), token, nil)
+		assert.Equal(t, fiber.StatusNoContent, status)
+
+		status, body := send(t, "GET", "/api/thoughts?tag=Work", token, nil)
+		assert.Equal(t, fiber.StatusOK, status)
+		var page struct {
+			Thoughts []models.Thought `json:"thoughts"`
+		}
+		json.Unmarshal(body, &page)
+		assert.Len(t, page.Thoughts, 2)
+		for _, thought := range page.Thoughts {
+			assert.Contains(t, tagNames(thought), "work")
+		}
+
+		status, body = send(t, "GET", "/api/tags", token, nil)
+		assert.Equal(t, fiber.StatusOK, status)
+		var result struct {
+			Tags []struct {
+				Name  string `json:"name"`
+				Count int64  `json:"count"`
+			} `json:"tags"`
+		}
+		json.Unmarshal(body, &result)
+		assert.Len(t, result.Tags, 3)
+		assert.Equal(t, "work", result.Tags[0].Name)
+		assert.Equal(t, int64(2), result.Tags[0].Count)
+		assert.Equal(t, "code", result.Tags[1].Name)
+		assert.Equal(t, int64(1), result.Tags[1].Count)
+		assert.Equal(t, "home", result.Tags[2].Name)
+	})
+
+	t.Run("unauthorized access", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/tags", nil)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+}
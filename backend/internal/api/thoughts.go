@@ -1,88 +1,226 @@
 package api
 
 import (
+	"errors"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/backend/internal/apierror"
 	"github.com/yourusername/backend/internal/auth"
 	"github.com/yourusername/backend/internal/models"
-	"gorm.io/gorm"
+	"github.com/yourusername/backend/internal/store"
 )
 
 type CreateThoughtRequest struct {
 	Content string `json:"content" validate:"required,min=1,max=500"`
+	// Tags are added alongside any #hashtags found in the content
+	Tags []string `json:"tags"`
 }
 
 // CreateThought handles creating a new thought
-func CreateThought(c *fiber.Ctx, db *gorm.DB) error {
-	user, err := auth.GetUserFromContext(c, db)
+func CreateThought(c *fiber.Ctx, svc Services) error {
+	user, err := auth.GetUserFromContext(c, svc.Users)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+		return apierror.Unauthorized(apierror.CodeUnauthorized, "Unauthorized")
 	}
 
-	var req CreateThoughtRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request",
-		})
+	if svc.RequireVerifiedEmail && !user.EmailVerified {
+		return apierror.Forbidden(apierror.CodeEmailNotVerified, "Email verification required")
 	}
 
-	// Check if content is provided
-	if req.Content == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Content is required",
-		})
+	var req CreateThoughtRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apierror.BadRequest("Invalid request")
 	}
 
-	// Trim whitespace and validate content
-	trimmedContent := strings.TrimSpace(req.Content)
-	if len(trimmedContent) == 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Content cannot be empty",
-		})
+	content, err := validateContent(req.Content)
+	if err != nil {
+		return apierror.Invalid("content", "invalid", err.Error())
 	}
+	req.Content = content
 
-	// Validate content length
-	if len(trimmedContent) > 1000 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Content too long",
-		})
+	tags, err := mergeTags(req.Content, req.Tags)
+	if err != nil {
+		return apierror.Invalid("tags", "invalid", err.Error())
 	}
 
-	// Update content with trimmed version
-	req.Content = trimmedContent
-
 	thought := models.Thought{
 		Content: req.Content,
 		UserID:  user.ID,
+		Tags:    namedTags(tags),
 	}
 
-	if err := db.Create(&thought).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Could not create thought",
-		})
+	if err := svc.Thoughts.CreateThought(c.UserContext(), &thought); err != nil {
+		return apierror.Internal("Could not create thought", err)
 	}
+	svc.Metrics.ThoughtsCreated(1)
 
 	return c.Status(fiber.StatusCreated).JSON(thought)
 }
 
-// GetThoughts gets all thoughts for the authenticated user
-func GetThoughts(c *fiber.Ctx, db *gorm.DB) error {
-	user, err := auth.GetUserFromContext(c, db)
+// GetThoughts gets a page of thoughts for the authenticated user, newest first
+func GetThoughts(c *fiber.Ctx, svc Services) error {
+	user, err := auth.GetUserFromContext(c, svc.Users)
+	if err != nil {
+		return apierror.Unauthorized(apierror.CodeUnauthorized, "Unauthorized")
+	}
+
+	limit, err := parsePageLimit(c)
+	if err != nil {
+		return apierror.Invalid("limit", "invalid", "Invalid limit")
+	}
+
+	var cursor *store.Cursor
+	if raw := c.Query("cursor"); raw != "" {
+		if cursor, err = decodeCursor(raw); err != nil {
+			return apierror.Invalid("cursor", "invalid", "Invalid cursor")
+		}
+	}
+
+	opts := store.ListOptions{
+		Tag:   strings.ToLower(strings.TrimPrefix(c.Query("tag"), "#")),
+		After: cursor,
+		Limit: limit,
+	}
+
+	page, err := paginateThoughts(c.UserContext(), svc.Thoughts, user.ID, opts)
+	if err != nil {
+		return apierror.Internal("Could not fetch thoughts", err)
+	}
+
+	return c.JSON(page)
+}
+
+// UpdateThoughtRequest is the body for PUT and PATCH on a single thought.
+// Fields are pointers so PATCH can tell an omitted field from an empty one.
+type UpdateThoughtRequest struct {
+	Content *string `json:"content"`
+	// Tags replaces the explicit tags; hashtags in the content are always kept
+	Tags *[]string `json:"tags"`
+}
+
+// GetThought returns a single thought owned by the authenticated user
+func GetThought(c *fiber.Ctx, svc Services) error {
+	user, err := auth.GetUserFromContext(c, svc.Users)
+	if err != nil {
+		return apierror.Unauthorized(apierror.CodeUnauthorized, "Unauthorized")
+	}
+
+	thought, err := findUserThought(c, svc, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(thought)
+}
+
+// UpdateThought replaces (PUT) or partially updates (PATCH) a thought owned
+// by the authenticated user
+func UpdateThought(c *fiber.Ctx, svc Services) error {
+	user, err := auth.GetUserFromContext(c, svc.Users)
+	if err != nil {
+		return apierror.Unauthorized(apierror.CodeUnauthorized, "Unauthorized")
+	}
+
+	thought, err := findUserThought(c, svc, user.ID)
+	if err != nil {
+		return err
+	}
+
+	var req UpdateThoughtRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apierror.BadRequest("Invalid request")
+	}
+
+	// PUT replaces the whole resource, so content is mandatory
+	if req.Content == nil && c.Method() == fiber.MethodPut {
+		return apierror.Invalid("content", "required", "Content is required")
+	}
+
+	// Explicit tags are kept across content edits unless replaced
+	explicit := explicitTags(thought)
+
+	if req.Content != nil {
+		content, err := validateContent(*req.Content)
+		if err != nil {
+			return apierror.Invalid("content", "invalid", err.Error())
+		}
+		thought.Content = content
+	}
+
+	if req.Tags != nil {
+		explicit = *req.Tags
+	}
+	tags, err := mergeTags(thought.Content, explicit)
+	if err != nil {
+		return apierror.Invalid("tags", "invalid", err.Error())
+	}
+
+	thought.Tags = namedTags(tags)
+	if err := svc.Thoughts.UpdateThought(c.UserContext(), thought); err != nil {
+		return apierror.Internal("Could not update thought", err)
+	}
+
+	return c.JSON(thought)
+}
+
+// DeleteThought removes a thought owned by the authenticated user
+func DeleteThought(c *fiber.Ctx, svc Services) error {
+	user, err := auth.GetUserFromContext(c, svc.Users)
+	if err != nil {
+		return apierror.Unauthorized(apierror.CodeUnauthorized, "Unauthorized")
+	}
+
+	thought, err := findUserThought(c, svc, user.ID)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+		return err
 	}
 
-	var thoughts []models.Thought
-	if err := db.Where("user_id = ?", user.ID).Order("created_at DESC").Find(&thoughts).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Could not fetch thoughts",
-		})
+	if err := svc.Thoughts.DeleteThought(c.UserContext(), thought); err != nil {
+		return apierror.Internal("Could not delete thought", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// findUserThought loads the thought named by the :id route parameter.
+// Thoughts belonging to other users are reported as not found so their
+// existence is not leaked.
+func findUserThought(c *fiber.Ctx, svc Services, userID uint) (*models.Thought, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return nil, apierror.BadRequest("Invalid thought ID")
+	}
+
+	thought, err := svc.Thoughts.Thought(c.UserContext(), userID, uint(id))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, apierror.NotFound("Thought not found")
+		}
+		return nil, apierror.Internal("Could not fetch thought", err)
+	}
+
+	return thought, nil
+}
+
+// validateContent trims the content and applies the rules shared by
+// thought creation and updates
+func validateContent(content string) (string, error) {
+	// Check if content is provided
+	if content == "" {
+		return "", errors.New("Content is required")
+	}
+
+	// Trim whitespace and validate content
+	trimmedContent := strings.TrimSpace(content)
+	if len(trimmedContent) == 0 {
+		return "", errors.New("Content cannot be empty")
+	}
+
+	// Validate content length
+	if len(trimmedContent) > 1000 {
+		return "", errors.New("Content too long")
 	}
 
-	return c.JSON(thoughts)
+	return trimmedContent, nil
 }
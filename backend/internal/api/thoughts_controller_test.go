@@ -88,10 +88,10 @@ func TestThoughtsController(t *testing.T) {
 				assert.Equal(t, tt.expectedStatus, resp.StatusCode, "Unexpected status code")
 
 				if tt.expectedError != "" {
-					var result map[string]string
+					var result map[string]any
 					err = json.NewDecoder(resp.Body).Decode(&result)
 					assert.NoError(t, err)
-					assert.Contains(t, result["error"], tt.expectedError, "Unexpected error message")
+					assert.Contains(t, result["detail"], tt.expectedError, "Unexpected error message")
 				}
 
 				if resp.StatusCode == http.StatusCreated {
@@ -169,9 +169,14 @@ func TestThoughtsController(t *testing.T) {
 			t.Logf("Response body: %s", string(body))
 
 			// Parse the response
-			var result []models.Thought
-			err = json.Unmarshal(body, &result)
+			var page struct {
+				Thoughts   []models.Thought `json:"thoughts"`
+				NextCursor string           `json:"next_cursor"`
+			}
+			err = json.Unmarshal(body, &page)
 			assert.NoError(t, err, "Failed to decode response body")
+			assert.Empty(t, page.NextCursor, "Expected a single page")
+			result := page.Thoughts
 
 			t.Logf("Decoded %d thoughts from response", len(result))
 
@@ -3,14 +3,14 @@ package api_test
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/yourusername/backend/internal/models"
 	"github.com/yourusername/backend/internal/testutils"
@@ -183,7 +183,7 @@ func TestCreateThought(t *testing.T) {
 			if tt.expectedError != "" {
 				var result map[string]string
 				json.NewDecoder(resp.Body).Decode(&result)
-				assert.Contains(t, result["error"], tt.expectedError)
+				assert.Contains(t, result["detail"], tt.expectedError)
 			}
 		})
 	}
@@ -239,20 +239,390 @@ func TestGetThoughts(t *testing.T) {
 			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
 
 			if tt.expectedCount > 0 {
-				var result []map[string]interface{}
+				var result struct {
+					Thoughts []map[string]interface{} `json:"thoughts"`
+				}
 				json.NewDecoder(resp.Body).Decode(&result)
-				assert.Len(t, result, tt.expectedCount)
+				assert.Len(t, result.Thoughts, tt.expectedCount)
 			}
 
 			if tt.expectedError != "" {
 				var result map[string]string
 				json.NewDecoder(resp.Body).Decode(&result)
-				assert.Contains(t, result["error"], tt.expectedError)
+				assert.Contains(t, result["detail"], tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestGetThoughtsPagination(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	app := testutils.SetupTestApp(t, db)
+
+	token, userID := registerAndLogin(t, app, "test@example.com", "password123")
+	_, otherID := registerAndLogin(t, app, "other@example.com", "password123")
+	createTestThought(t, db, otherID, "Someone else's thought")
+
+	// Two thoughts share a timestamp to exercise the id tie-breaker
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, offset := range []int{0, 1, 2, 2, 3} {
+		thought := models.Thought{
+			Content:   fmt.Sprintf("Thought %d", i),
+			UserID:    userID,
+			CreatedAt: base.Add(time.Duration(offset) * time.Minute),
+		}
+		if err := db.Create(&thought).Error; err != nil {
+			t.Fatalf("Failed to create test thought: %v", err)
+		}
+	}
+
+	type page struct {
+		Thoughts   []models.Thought `json:"thoughts"`
+		NextCursor string           `json:"next_cursor"`
+	}
+
+	getPage := func(t *testing.T, query string) (*http.Response, page) {
+		req := httptest.NewRequest("GET", "/api/thoughts"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p page
+		json.NewDecoder(resp.Body).Decode(&p)
+		return resp, p
+	}
+
+	t.Run("walks all pages newest first", func(t *testing.T) {
+		var contents []string
+		cursor := ""
+		for pages := 0; ; pages++ {
+			if pages > 5 {
+				t.Fatal("Pagination did not terminate")
+			}
+			query := "?limit=2"
+			if cursor != "" {
+				query += "&cursor=" + cursor
+			}
+			resp, p := getPage(t, query)
+			assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+			assert.LessOrEqual(t, len(p.Thoughts), 2)
+			for _, thought := range p.Thoughts {
+				contents = append(contents, thought.Content)
+			}
+			if p.NextCursor == "" {
+				break
+			}
+			cursor = p.NextCursor
+		}
+
+		assert.Equal(t, []string{"Thought 4", "Thought 3", "Thought 2", "Thought 1", "Thought 0"}, contents)
+	})
+
+	t.Run("new thoughts do not shift later pages", func(t *testing.T) {
+		_, first := getPage(t, "?limit=2")
+		assert.NotEmpty(t, first.NextCursor)
+
+		createTestThought(t, db, userID, "Brand new thought")
+
+		_, second := getPage(t, "?limit=2&cursor="+first.NextCursor)
+		assert.Len(t, second.Thoughts, 2)
+		assert.Equal(t, "Thought 2", second.Thoughts[0].Content)
+		assert.Equal(t, "Thought 1", second.Thoughts[1].Content)
+	})
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "invalid limit",
+			query:          "?limit=abc",
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "Invalid limit",
+		},
+		{
+			name:           "negative limit",
+			query:          "?limit=-1",
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "Invalid limit",
+		},
+		{
+			name:           "malformed cursor",
+			query:          "?cursor=not-a-cursor",
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "Invalid cursor",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/thoughts"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
 			}
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			var result map[string]string
+			json.NewDecoder(resp.Body).Decode(&result)
+			assert.Contains(t, result["detail"], tt.expectedError)
 		})
 	}
 }
 
+func TestGetThought(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	app := testutils.SetupTestApp(t, db)
+
+	token, userID := registerAndLogin(t, app, "test@example.com", "password123")
+	otherToken, _ := registerAndLogin(t, app, "other@example.com", "password123")
+	thought := createTestThought(t, db, userID, "My thought")
+
+	tests := []struct {
+		name           string
+		token          string
+		path           string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "get own thought",
+			token:          token,
+			path:           fmt.Sprintf("/api/thoughts/%d", thought.ID),
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "other user's thought",
+			token:          otherToken,
+			path:           fmt.Sprintf("/api/thoughts/%d", thought.ID),
+			expectedStatus: fiber.StatusNotFound,
+			expectedError:  "Thought not found",
+		},
+		{
+			name:           "non-existent thought",
+			token:          token,
+			path:           "/api/thoughts/9999",
+			expectedStatus: fiber.StatusNotFound,
+			expectedError:  "Thought not found",
+		},
+		{
+			name:           "invalid id",
+			token:          token,
+			path:           "/api/thoughts/abc",
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "Invalid thought ID",
+		},
+		{
+			name:           "unauthorized access",
+			token:          "invalid-token",
+			path:           fmt.Sprintf("/api/thoughts/%d", thought.ID),
+			expectedStatus: fiber.StatusUnauthorized,
+			expectedError:  "Invalid or expired token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			if tt.expectedStatus == fiber.StatusOK {
+				var result models.Thought
+				json.NewDecoder(resp.Body).Decode(&result)
+				assert.Equal(t, thought.ID, result.ID)
+				assert.Equal(t, "My thought", result.Content)
+			}
+
+			if tt.expectedError != "" {
+				var result map[string]string
+				json.NewDecoder(resp.Body).Decode(&result)
+				assert.Contains(t, result["detail"], tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestUpdateThought(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	app := testutils.SetupTestApp(t, db)
+
+	token, userID := registerAndLogin(t, app, "test@example.com", "password123")
+	otherToken, _ := registerAndLogin(t, app, "other@example.com", "password123")
+	thought := createTestThought(t, db, userID, "Original thought")
+	path := fmt.Sprintf("/api/thoughts/%d", thought.ID)
+
+	tests := []struct {
+		name            string
+		method          string
+		token           string
+		payload         map[string]string
+		expectedStatus  int
+		expectedContent string
+		expectedError   string
+	}{
+		{
+			name:            "put replaces content",
+			method:          "PUT",
+			token:           token,
+			payload:         map[string]string{"content": "  Updated thought  "},
+			expectedStatus:  fiber.StatusOK,
+			expectedContent: "Updated thought",
+		},
+		{
+			name:            "patch updates content",
+			method:          "PATCH",
+			token:           token,
+			payload:         map[string]string{"content": "Patched thought"},
+			expectedStatus:  fiber.StatusOK,
+			expectedContent: "Patched thought",
+		},
+		{
+			name:            "patch without fields is a no-op",
+			method:          "PATCH",
+			token:           token,
+			payload:         map[string]string{},
+			expectedStatus:  fiber.StatusOK,
+			expectedContent: "Patched thought",
+		},
+		{
+			name:           "put without content",
+			method:         "PUT",
+			token:          token,
+			payload:        map[string]string{},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "Content is required",
+		},
+		{
+			name:           "whitespace only content",
+			method:         "PATCH",
+			token:          token,
+			payload:        map[string]string{"content": "   \n  "},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "Content cannot be empty",
+		},
+		{
+			name:           "content too long",
+			method:         "PUT",
+			token:          token,
+			payload:        map[string]string{"content": strings.Repeat("a", 1001)},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "Content too long",
+		},
+		{
+			name:           "other user's thought",
+			method:         "PUT",
+			token:          otherToken,
+			payload:        map[string]string{"content": "Hijacked"},
+			expectedStatus: fiber.StatusNotFound,
+			expectedError:  "Thought not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest(tt.method, path, bytes.NewBuffer(payload))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			if tt.expectedContent != "" {
+				var result models.Thought
+				json.NewDecoder(resp.Body).Decode(&result)
+				assert.Equal(t, tt.expectedContent, result.Content)
+
+				var dbThought models.Thought
+				assert.NoError(t, db.First(&dbThought, thought.ID).Error)
+				assert.Equal(t, tt.expectedContent, dbThought.Content)
+			}
+
+			if tt.expectedError != "" {
+				var result map[string]string
+				json.NewDecoder(resp.Body).Decode(&result)
+				assert.Contains(t, result["detail"], tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestDeleteThought(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	app := testutils.SetupTestApp(t, db)
+
+	token, userID := registerAndLogin(t, app, "test@example.com", "password123")
+	otherToken, _ := registerAndLogin(t, app, "other@example.com", "password123")
+	thought := createTestThought(t, db, userID, "Doomed thought")
+	path := fmt.Sprintf("/api/thoughts/%d", thought.ID)
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "other user's thought",
+			token:          otherToken,
+			expectedStatus: fiber.StatusNotFound,
+			expectedError:  "Thought not found",
+		},
+		{
+			name:           "delete own thought",
+			token:          token,
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:           "already deleted",
+			token:          token,
+			expectedStatus: fiber.StatusNotFound,
+			expectedError:  "Thought not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("DELETE", path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			if tt.expectedError != "" {
+				var result map[string]string
+				json.NewDecoder(resp.Body).Decode(&result)
+				assert.Contains(t, result["detail"], tt.expectedError)
+			}
+		})
+	}
+
+	var count int64
+	db.Model(&models.Thought{}).Where("id = ?", thought.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
 // Helper function to register and login a test user
 func registerAndLogin(t *testing.T, app *fiber.App, email, password string) (string, uint) {
 	// Register
@@ -279,18 +649,16 @@ func registerAndLogin(t *testing.T, app *fiber.App, email, password string) (str
 	token := result["token"]
 
 	// Get user ID from token
-	claims := jwt.MapClaims{}
-	_, _ = jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
-
-	userID := uint(claims["user_id"].(float64))
+	claims, err := testutils.Keys.Parse(token)
+	if err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
 
-	return token, userID
+	return token, claims.UserID
 }
 
 // Helper function to create test thoughts
-func createTestThought(t *testing.T, db *gorm.DB, userID uint, content string) {
+func createTestThought(t *testing.T, db *gorm.DB, userID uint, content string) models.Thought {
 	thought := models.Thought{
 		Content: content,
 		UserID:  userID,
@@ -298,4 +666,5 @@ func createTestThought(t *testing.T, db *gorm.DB, userID uint, content string) {
 	if err := db.Create(&thought).Error; err != nil {
 		t.Fatalf("Failed to create test thought: %v", err)
 	}
+	return thought
 }
@@ -0,0 +1,345 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/auth"
+	"github.com/yourusername/backend/internal/logging"
+	"github.com/yourusername/backend/internal/metrics"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/ratelimit"
+	"github.com/yourusername/backend/internal/store"
+)
+
+const (
+	// MFAChallengeTTL is how long a user has to enter the second factor
+	// after the password
+	MFAChallengeTTL = 5 * time.Minute
+	// TOTPIssuer names the account in authenticator apps
+	TOTPIssuer = "Thoughts"
+	// RecoveryCodeCount is how many recovery codes enabling two-factor
+	// authentication hands out
+	RecoveryCodeCount = 10
+)
+
+// errInvalidCode is a second factor that is wrong or already used
+var errInvalidCode = errors.New("invalid code")
+
+type TwoFactorSetupRequest struct {
+	// Password is left empty by accounts that have none, see confirmIdentity
+	Password string `json:"password"`
+}
+
+type TwoFactorConfirmRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type TwoFactorDisableRequest struct {
+	// Password is left empty by accounts that have none, see confirmIdentity
+	Password string `json:"password"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type LoginTwoFactorRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// MFAChallengeResponse is the answer to a correct password for an account
+// with two-factor authentication. The token is exchanged for the access
+// token together with a code at /api/auth/login/2fa.
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type TwoFactorSetupResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type TwoFactorStatusResponse struct {
+	Enabled           bool  `json:"enabled"`
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorStatus reports whether two-factor authentication is enabled and
+// how many recovery codes are left
+func TwoFactorStatus(c *fiber.Ctx, svc Services) error {
+	userID := c.Locals("userID").(uint)
+
+	twoFactor, err := userTwoFactor(c.UserContext(), svc, userID)
+	if err != nil {
+		return apierror.Internal("Could not load two-factor authentication", err)
+	}
+	response := TwoFactorStatusResponse{Enabled: twoFactor.Enabled()}
+	if response.Enabled {
+		if response.RecoveryCodesLeft, err = svc.Users.CountRecoveryCodes(c.UserContext(), userID); err != nil {
+			return apierror.Internal("Could not load two-factor authentication", err)
+		}
+	}
+	return c.JSON(response)
+}
+
+// SetupTwoFactor generates a TOTP secret after confirmIdentity. It
+// stays pending, and logins keep working without it, until ConfirmTwoFactor
+// proves the authenticator app has it.
+func SetupTwoFactor(c *fiber.Ctx, svc Services) error {
+	user, err := auth.GetUserFromContext(c, svc.Users)
+	if err != nil {
+		return apierror.Unauthorized(apierror.CodeUnauthorized, "Unauthorized")
+	}
+
+	var req TwoFactorSetupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apierror.BadRequest("Invalid request")
+	}
+	if err := validate.Struct(req); err != nil {
+		return apierror.Validation(err)
+	}
+
+	if err := confirmIdentity(c, svc, user, req.Password); err != nil {
+		return err
+	}
+
+	secret, uri, err := auth.NewTOTP(TOTPIssuer, user.Email)
+	if err != nil {
+		return apierror.Internal("Could not set up two-factor authentication", err)
+	}
+	if err := svc.Users.SetupTwoFactor(c.UserContext(), user.ID, secret); err != nil {
+		if errors.Is(err, store.ErrTwoFactorEnabled) {
+			return twoFactorEnabled()
+		}
+		return apierror.Internal("Could not set up two-factor authentication", err)
+	}
+
+	return c.JSON(TwoFactorSetupResponse{Secret: secret, URI: uri})
+}
+
+// ConfirmTwoFactor enables two-factor authentication with the first code
+// from the authenticator app and returns the recovery codes. They are shown
+// this once; only their hashes are kept.
+func ConfirmTwoFactor(c *fiber.Ctx, svc Services) error {
+	userID := c.Locals("userID").(uint)
+
+	var req TwoFactorConfirmRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apierror.BadRequest("Invalid request")
+	}
+	if err := validate.Struct(req); err != nil {
+		return apierror.Validation(err)
+	}
+
+	twoFactor, err := userTwoFactor(c.UserContext(), svc, userID)
+	switch {
+	case err != nil:
+		return apierror.Internal("Could not enable two-factor authentication", err)
+	case twoFactor == nil:
+		return apierror.BadRequest("Set up two-factor authentication first")
+	case twoFactor.Enabled():
+		return twoFactorEnabled()
+	}
+
+	now := time.Now()
+	step, ok := auth.VerifyTOTP(twoFactor.Secret, req.Code, now)
+	if !ok {
+		return invalidCode(fiber.StatusBadRequest)
+	}
+
+	codes, err := auth.NewRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		return apierror.Internal("Could not enable two-factor authentication", err)
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = hashRecoveryCode(code)
+	}
+	if err := svc.Users.EnableTwoFactor(c.UserContext(), userID, step, hashes, now); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			// Confirmed by a concurrent request
+			return twoFactorEnabled()
+		}
+		return apierror.Internal("Could not enable two-factor authentication", err)
+	}
+
+	return c.JSON(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor turns two-factor authentication off. Besides
+// confirmIdentity it takes a code, so a stolen password and session are not
+// enough; wrong codes count towards the login lockout.
+func DisableTwoFactor(c *fiber.Ctx, svc Services) error {
+	user, err := auth.GetUserFromContext(c, svc.Users)
+	if err != nil {
+		return apierror.Unauthorized(apierror.CodeUnauthorized, "Unauthorized")
+	}
+
+	var req TwoFactorDisableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apierror.BadRequest("Invalid request")
+	}
+	if err := validate.Struct(req); err != nil {
+		return apierror.Validation(err)
+	}
+
+	lockoutKey := ratelimit.NormalizeEmail(user.Email)
+	if err := checkLockout(c, svc, lockoutKey); err != nil {
+		return err
+	}
+	if err := confirmIdentity(c, svc, user, req.Password); err != nil {
+		return err
+	}
+
+	twoFactor, err := userTwoFactor(c.UserContext(), svc, user.ID)
+	if err != nil {
+		return apierror.Internal("Could not disable two-factor authentication", err)
+	}
+	if !twoFactor.Enabled() {
+		return apierror.BadRequest("Two-factor authentication is not enabled")
+	}
+
+	if err := verifySecondFactor(c.UserContext(), svc, twoFactor, req.Code, time.Now()); err != nil {
+		if errors.Is(err, errInvalidCode) {
+			recordFailedCode(c, svc, lockoutKey)
+			return invalidCode(fiber.StatusBadRequest)
+		}
+		return apierror.Internal("Could not disable two-factor authentication", err)
+	}
+
+	if err := svc.Users.DisableTwoFactor(c.UserContext(), user.ID); err != nil {
+		return apierror.Internal("Could not disable two-factor authentication", err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// LoginTwoFactor completes a login with the MFA challenge token returned
+// for the password and a code from the authenticator app or a recovery
+// code. Wrong codes count towards the login lockout like wrong passwords.
+func LoginTwoFactor(c *fiber.Ctx, svc Services) error {
+	var req LoginTwoFactorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apierror.BadRequest("Invalid request")
+	}
+	if err := validate.Struct(req); err != nil {
+		return apierror.Validation(err)
+	}
+
+	expired := apierror.Unauthorized(apierror.CodeInvalidToken, "Login expired, log in again")
+	claims, err := svc.Keys.ParseChallenge(req.MFAToken)
+	if err != nil {
+		return expired
+	}
+	user, err := svc.Users.UserByID(c.UserContext(), claims.UserID)
+	if err != nil {
+		return expired
+	}
+
+	lockoutKey := ratelimit.NormalizeEmail(user.Email)
+	if err := checkLockout(c, svc, lockoutKey); err != nil {
+		return err
+	}
+
+	twoFactor, err := userTwoFactor(c.UserContext(), svc, user.ID)
+	if err != nil {
+		return apierror.Internal("Could not log in", err)
+	}
+	if !twoFactor.Enabled() {
+		// Disabled since the password was checked
+		return expired
+	}
+
+	if err := verifySecondFactor(c.UserContext(), svc, twoFactor, req.Code, time.Now()); err != nil {
+		if errors.Is(err, errInvalidCode) {
+			svc.Metrics.Login(metrics.LoginFailure)
+			recordFailedCode(c, svc, lockoutKey)
+			return invalidCode(fiber.StatusUnauthorized)
+		}
+		return apierror.Internal("Could not log in", err)
+	}
+
+	return completeLogin(c, svc, user, lockoutKey)
+}
+
+// createChallenge returns an MFA challenge token for the user
+func createChallenge(keys *auth.KeyRing, userID uint) (*MFAChallengeResponse, error) {
+	now := time.Now()
+	token, err := keys.SignChallenge(auth.ChallengeClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(MFAChallengeTTL)),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int(MFAChallengeTTL / time.Second),
+	}, nil
+}
+
+// userTwoFactor returns the user's authenticator, or nil without one
+func userTwoFactor(ctx context.Context, svc Services, userID uint) (*models.TwoFactor, error) {
+	twoFactor, err := svc.Users.TwoFactor(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	return twoFactor, err
+}
+
+// verifySecondFactor accepts a current TOTP code or an unused recovery
+// code and uses it up, so neither works twice. Anything else is
+// errInvalidCode.
+func verifySecondFactor(ctx context.Context, svc Services, twoFactor *models.TwoFactor, code string, now time.Time) error {
+	if step, ok := auth.VerifyTOTP(twoFactor.Secret, code, now); ok {
+		err := svc.Users.UseTOTPStep(ctx, twoFactor.UserID, step)
+		if errors.Is(err, store.ErrCodeUsed) {
+			return errInvalidCode
+		}
+		return err
+	}
+
+	err := svc.Users.UseRecoveryCode(ctx, twoFactor.UserID, hashRecoveryCode(code), now)
+	if errors.Is(err, store.ErrInvalidToken) {
+		return errInvalidCode
+	}
+	if err == nil {
+		logging.FromContext(ctx).Info("Recovery code used", "user_id", twoFactor.UserID)
+	}
+	return err
+}
+
+// hashRecoveryCode hashes a recovery code for storage and lookup. Codes
+// have 80 bits of entropy, which like opaque tokens is enough for a fast
+// hash.
+func hashRecoveryCode(code string) string {
+	return hashToken(auth.NormalizeRecoveryCode(code))
+}
+
+// recordFailedCode counts a wrong second factor towards the lockout of the
+// account
+func recordFailedCode(c *fiber.Ctx, svc Services, lockoutKey string) {
+	if _, err := svc.Lockout.Fail(c.UserContext(), lockoutKey); err != nil {
+		logging.FromContext(c.UserContext()).Error("Could not record failed login", "error", err)
+	}
+}
+
+func invalidCode(status int) *apierror.Error {
+	return apierror.New(status, apierror.CodeInvalidCode, "Code is incorrect or was already used")
+}
+
+func twoFactorEnabled() *apierror.Error {
+	return apierror.New(fiber.StatusBadRequest, apierror.CodeTwoFactorEnabled, "Two-factor authentication is already enabled")
+}
@@ -0,0 +1,279 @@
+package api_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/api"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/auth"
+	"github.com/yourusername/backend/internal/mail"
+	"github.com/yourusername/backend/internal/ratelimit"
+	"github.com/yourusername/backend/internal/store"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+// totpCode returns the current code of the secret, shifted by whole periods
+// so tests can use a fresh code when the current one was already used
+func totpCode(t *testing.T, secret string, periods int) string {
+	t.Helper()
+
+	code, err := auth.GenerateTOTP(secret, time.Now().Add(time.Duration(periods)*auth.TOTPPeriod))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// enableTwoFactor sets up and confirms two-factor authentication with the
+// current code and returns the secret, the recovery codes and the code used
+func enableTwoFactor(t *testing.T, app *fiber.App, token, password string) (string, []string, string) {
+	t.Helper()
+
+	var setup api.TwoFactorSetupResponse
+	status := sendJSON(t, app, "POST", "/api/me/2fa/setup", token, api.TwoFactorSetupRequest{Password: password}, &setup)
+	if status != fiber.StatusOK {
+		t.Fatalf("Unexpected setup response %d", status)
+	}
+
+	var confirmed api.RecoveryCodesResponse
+	code := totpCode(t, setup.Secret, 0)
+	status = sendJSON(t, app, "POST", "/api/me/2fa/confirm", token, api.TwoFactorConfirmRequest{Code: code}, &confirmed)
+	if status != fiber.StatusOK {
+		t.Fatalf("Unexpected confirm response %d", status)
+	}
+	return setup.Secret, confirmed.RecoveryCodes, code
+}
+
+// loginChallenge logs in with a password and returns the MFA challenge
+func loginChallenge(t *testing.T, app *fiber.App, email, password string) api.MFAChallengeResponse {
+	t.Helper()
+
+	var challenge api.MFAChallengeResponse
+	status := sendJSON(t, app, "POST", "/api/auth/login", "", map[string]string{"email": email, "password": password}, &challenge)
+	if status != fiber.StatusOK || !challenge.MFARequired {
+		t.Fatalf("Expected an MFA challenge, got %d", status)
+	}
+	return challenge
+}
+
+// loginTwoFactor completes a login with the challenge token and a code
+func loginTwoFactor(t *testing.T, app *fiber.App, mfaToken, code string) (int, map[string]interface{}) {
+	t.Helper()
+
+	var result map[string]interface{}
+	status := sendJSON(t, app, "POST", "/api/auth/login/2fa", "", api.LoginTwoFactorRequest{MFAToken: mfaToken, Code: code}, &result)
+	return status, result
+}
+
+func TestTwoFactorEnrollment(t *testing.T) {
+	app := testutils.SetupTestApp(t, testutils.SetupTestDB(t))
+	token, _ := registerAndLogin(t, app, "mfa@example.com", "password123")
+
+	var status api.TwoFactorStatusResponse
+	code := sendJSON(t, app, "GET", "/api/me/2fa", token, nil, &status)
+	assert.Equal(t, fiber.StatusOK, code)
+	assert.False(t, status.Enabled)
+
+	var problem apierror.Problem
+	code = sendJSON(t, app, "POST", "/api/me/2fa/setup", token, api.TwoFactorSetupRequest{Password: "wrongpassword"}, &problem)
+	assert.Equal(t, fiber.StatusForbidden, code)
+	assert.Equal(t, apierror.CodeIncorrectPassword, problem.Code)
+
+	// Confirming needs a pending setup
+	code = sendJSON(t, app, "POST", "/api/me/2fa/confirm", token, api.TwoFactorConfirmRequest{Code: "123456"}, &problem)
+	assert.Equal(t, fiber.StatusBadRequest, code)
+
+	var setup api.TwoFactorSetupResponse
+	code = sendJSON(t, app, "POST", "/api/me/2fa/setup", token, api.TwoFactorSetupRequest{Password: "password123"}, &setup)
+	assert.Equal(t, fiber.StatusOK, code)
+	assert.NotEmpty(t, setup.Secret)
+	assert.True(t, strings.HasPrefix(setup.URI, "otpauth://totp/Thoughts:mfa@example.com?"), setup.URI)
+	assert.Contains(t, setup.URI, "secret="+setup.Secret)
+
+	// Until confirmed, logins work with the password alone
+	accessToken, _ := loginTokens(t, app, "mfa@example.com", "password123")
+	assert.NotEmpty(t, accessToken)
+
+	wrong := "000000"
+	if wrong == totpCode(t, setup.Secret, 0) {
+		wrong = "111111"
+	}
+	code = sendJSON(t, app, "POST", "/api/me/2fa/confirm", token, api.TwoFactorConfirmRequest{Code: wrong}, &problem)
+	assert.Equal(t, fiber.StatusBadRequest, code)
+	assert.Equal(t, apierror.CodeInvalidCode, problem.Code)
+
+	var confirmed api.RecoveryCodesResponse
+	code = sendJSON(t, app, "POST", "/api/me/2fa/confirm", token, api.TwoFactorConfirmRequest{Code: totpCode(t, setup.Secret, 0)}, &confirmed)
+	assert.Equal(t, fiber.StatusOK, code)
+	assert.Len(t, confirmed.RecoveryCodes, api.RecoveryCodeCount)
+
+	code = sendJSON(t, app, "GET", "/api/me/2fa", token, nil, &status)
+	assert.Equal(t, fiber.StatusOK, code)
+	assert.Equal(t, api.TwoFactorStatusResponse{Enabled: true, RecoveryCodesLeft: api.RecoveryCodeCount}, status)
+
+	// Enrolling again would replace the secret behind the user's back
+	code = sendJSON(t, app, "POST", "/api/me/2fa/setup", token, api.TwoFactorSetupRequest{Password: "password123"}, &problem)
+	assert.Equal(t, fiber.StatusBadRequest, code)
+	assert.Equal(t, apierror.CodeTwoFactorEnabled, problem.Code)
+	code = sendJSON(t, app, "POST", "/api/me/2fa/confirm", token, api.TwoFactorConfirmRequest{Code: totpCode(t, setup.Secret, 1)}, &problem)
+	assert.Equal(t, fiber.StatusBadRequest, code)
+	assert.Equal(t, apierror.CodeTwoFactorEnabled, problem.Code)
+
+	// Without a session none of it is reachable
+	code = sendJSON(t, app, "GET", "/api/me/2fa", "", nil, nil)
+	assert.Equal(t, fiber.StatusUnauthorized, code)
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	app := testutils.SetupTestApp(t, testutils.SetupTestDB(t))
+	token, _ := registerAndLogin(t, app, "mfa@example.com", "password123")
+	secret, recoveryCodes, used := enableTwoFactor(t, app, token, "password123")
+
+	// The password alone returns a challenge instead of a session
+	var result map[string]interface{}
+	status := sendJSON(t, app, "POST", "/api/auth/login", "", map[string]string{"email": "mfa@example.com", "password": "password123"}, &result)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, true, result["mfa_required"])
+	assert.Equal(t, float64(api.MFAChallengeTTL/time.Second), result["expires_in"])
+	assert.NotContains(t, result, "token")
+	assert.NotContains(t, result, "refresh_token")
+
+	challenge := loginChallenge(t, app, "mfa@example.com", "password123")
+
+	// The challenge is no access token
+	status, _ = getMe(t, app, challenge.MFAToken)
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+
+	// The code used to confirm the setup cannot be replayed
+	status, result = loginTwoFactor(t, app, challenge.MFAToken, used)
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+	assert.Equal(t, string(apierror.CodeInvalidCode), result["code"])
+
+	next := totpCode(t, secret, 1)
+	status, result = loginTwoFactor(t, app, challenge.MFAToken, next)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.NotEmpty(t, result["refresh_token"])
+	accessToken, _ := result["token"].(string)
+	status, me := getMe(t, app, accessToken)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "mfa@example.com", me["email"])
+
+	// Nor can a code that logged in
+	status, result = loginTwoFactor(t, app, challenge.MFAToken, next)
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+	assert.Equal(t, string(apierror.CodeInvalidCode), result["code"])
+
+	// Recovery codes work once, however they are typed
+	recovery := strings.ToUpper(strings.ReplaceAll(recoveryCodes[0], "-", " "))
+	status, result = loginTwoFactor(t, app, challenge.MFAToken, recovery)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.NotEmpty(t, result["token"])
+	status, result = loginTwoFactor(t, app, challenge.MFAToken, recoveryCodes[0])
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+	assert.Equal(t, string(apierror.CodeInvalidCode), result["code"])
+
+	var twoFactor api.TwoFactorStatusResponse
+	sendJSON(t, app, "GET", "/api/me/2fa", token, nil, &twoFactor)
+	assert.Equal(t, int64(api.RecoveryCodeCount-1), twoFactor.RecoveryCodesLeft)
+
+	t.Run("rejects other tokens", func(t *testing.T) {
+		for name, mfaToken := range map[string]string{
+			"garbage":      "not-a-token",
+			"access token": token,
+		} {
+			status, result := loginTwoFactor(t, app, mfaToken, recoveryCodes[1])
+			assert.Equal(t, fiber.StatusUnauthorized, status, name)
+			assert.Equal(t, string(apierror.CodeInvalidToken), result["code"], name)
+		}
+	})
+}
+
+func TestDisableTwoFactor(t *testing.T) {
+	app := testutils.SetupTestApp(t, testutils.SetupTestDB(t))
+	token, _ := registerAndLogin(t, app, "mfa@example.com", "password123")
+
+	var problem apierror.Problem
+	status := sendJSON(t, app, "DELETE", "/api/me/2fa", token, api.TwoFactorDisableRequest{Password: "password123", Code: "123456"}, &problem)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+
+	secret, recoveryCodes, _ := enableTwoFactor(t, app, token, "password123")
+
+	status = sendJSON(t, app, "DELETE", "/api/me/2fa", token, api.TwoFactorDisableRequest{Password: "wrongpassword", Code: totpCode(t, secret, 1)}, &problem)
+	assert.Equal(t, fiber.StatusForbidden, status)
+	assert.Equal(t, apierror.CodeIncorrectPassword, problem.Code)
+
+	status = sendJSON(t, app, "DELETE", "/api/me/2fa", token, api.TwoFactorDisableRequest{Password: "password123", Code: "nonsense"}, &problem)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Equal(t, apierror.CodeInvalidCode, problem.Code)
+
+	status = sendJSON(t, app, "DELETE", "/api/me/2fa", token, api.TwoFactorDisableRequest{Password: "password123", Code: recoveryCodes[0]}, nil)
+	assert.Equal(t, fiber.StatusNoContent, status)
+
+	// Logins go back to the password alone
+	accessToken, _ := loginTokens(t, app, "mfa@example.com", "password123")
+	assert.NotEmpty(t, accessToken)
+
+	// And the old secret and recovery codes are gone with it
+	newSecret, _, _ := enableTwoFactor(t, app, token, "password123")
+	assert.NotEqual(t, secret, newSecret)
+	challenge := loginChallenge(t, app, "mfa@example.com", "password123")
+	status, _ = loginTwoFactor(t, app, challenge.MFAToken, recoveryCodes[1])
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+}
+
+func TestTwoFactorLockout(t *testing.T) {
+	memory := store.NewMemory()
+	app := testutils.SetupTestAppWithServices(t, nil, api.Services{
+		Users:    memory,
+		Thoughts: memory,
+		Lockout: ratelimit.NewLockout(ratelimit.NewMemory(), ratelimit.LockoutPolicy{
+			Threshold:   3,
+			Duration:    time.Minute,
+			MaxDuration: time.Hour,
+		}),
+	})
+	token, _ := registerAndLogin(t, app, "mfa@example.com", "password123")
+	secret, _, _ := enableTwoFactor(t, app, token, "password123")
+	pending := loginChallenge(t, app, "mfa@example.com", "password123")
+
+	// The password is right every time, but guessing codes still locks
+	for i := 0; i < 3; i++ {
+		challenge := loginChallenge(t, app, "mfa@example.com", "password123")
+		status, result := loginTwoFactor(t, app, challenge.MFAToken, "000000")
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+		assert.Equal(t, string(apierror.CodeInvalidCode), result["code"])
+	}
+
+	status, problem, _ := login(t, app, "mfa@example.com", "password123")
+	assert.Equal(t, fiber.StatusTooManyRequests, status)
+	assert.Equal(t, apierror.CodeAccountLocked, problem.Code)
+
+	// Even a right code waits out the lockout
+	status, result := loginTwoFactor(t, app, pending.MFAToken, totpCode(t, secret, 1))
+	assert.Equal(t, fiber.StatusTooManyRequests, status)
+	assert.Equal(t, string(apierror.CodeAccountLocked), result["code"])
+}
+
+func TestOIDCLoginWithTwoFactor(t *testing.T) {
+	mailer := mail.NewMemoryMailer()
+	app, mock := setupOIDCApp(t, mailer)
+	token, _ := registerAndLogin(t, app, "existing@example.com", "password123")
+	verify(t, app, lastVerificationToken(t, mailer, "existing@example.com"))
+	secret, _, _ := enableTwoFactor(t, app, token, "password123")
+
+	// The provider vouches for the password, not the second factor
+	mock.SetUser(testutils.OIDCUser{Subject: "existing", Email: "existing@example.com", EmailVerified: true})
+	fragment := oidcLogin(t, app)
+	assert.Empty(t, fragment.Get("token"))
+	if !assert.NotEmpty(t, fragment.Get("mfa_token"), fragment.Get("detail")) {
+		return
+	}
+
+	status, result := loginTwoFactor(t, app, fragment.Get("mfa_token"), totpCode(t, secret, 1))
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.NotEmpty(t, result["token"])
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/auth"
+	"github.com/yourusername/backend/internal/mail"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/store"
+)
+
+const (
+	// VerificationTokenTTL is how long an emailed verification link works
+	VerificationTokenTTL = 24 * time.Hour
+	// VerificationResendInterval is the minimum gap between verification emails
+	VerificationResendInterval = time.Minute
+)
+
+// VerifyEmail marks the account owning the emailed token as verified
+func VerifyEmail(c *fiber.Ctx, svc Services) error {
+	token := c.Query("token")
+	if token == "" {
+		return apierror.Invalid("token", "required", "Verification token is required")
+	}
+
+	user, err := svc.Users.UserByVerificationToken(c.UserContext(), hashToken(token))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return apierror.New(fiber.StatusBadRequest, apierror.CodeInvalidToken, "Invalid or expired verification token")
+		}
+		return apierror.Internal("Could not verify email", err)
+	}
+
+	if user.VerificationSentAt == nil || time.Since(*user.VerificationSentAt) > VerificationTokenTTL {
+		return apierror.New(fiber.StatusBadRequest, apierror.CodeInvalidToken, "Invalid or expired verification token")
+	}
+
+	if err := svc.Users.MarkEmailVerified(c.UserContext(), user.ID); err != nil {
+		return apierror.Internal("Could not verify email", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Email verified",
+	})
+}
+
+// ResendVerification emails a fresh verification link to the authenticated user
+func ResendVerification(c *fiber.Ctx, svc Services) error {
+	user, err := auth.GetUserFromContext(c, svc.Users)
+	if err != nil {
+		return apierror.Unauthorized(apierror.CodeUnauthorized, "Unauthorized")
+	}
+
+	if user.EmailVerified {
+		return apierror.New(fiber.StatusBadRequest, apierror.CodeEmailAlreadyVerified, "Email already verified")
+	}
+
+	if user.VerificationSentAt != nil && time.Since(*user.VerificationSentAt) < VerificationResendInterval {
+		return apierror.New(fiber.StatusTooManyRequests, apierror.CodeRateLimited, "Verification email recently sent, please wait before retrying")
+	}
+
+	if err := sendVerificationEmail(c.UserContext(), svc, user); err != nil {
+		return apierror.Internal("Could not send verification email", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Verification email sent",
+	})
+}
+
+// sendVerificationEmail stores a new verification token for the user,
+// replacing any previous one, and emails the link
+func sendVerificationEmail(ctx context.Context, svc Services, user *models.User) error {
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	if err := svc.Users.SetVerificationToken(ctx, user.ID, tokenHash, time.Now()); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/auth/verify?token=%s", svc.BaseURL, url.QueryEscape(token))
+	return svc.Mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Welcome to Thoughts!\n\n"+
+			"Please confirm your email address by opening the link below:\n\n%s\n\n"+
+			"The link expires in %d hours. If you did not create an account, you can ignore this email.\n",
+			link, int(VerificationTokenTTL.Hours())),
+	})
+}
@@ -0,0 +1,183 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/api"
+	"github.com/yourusername/backend/internal/mail"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+var verifyLinkPattern = regexp.MustCompile(`/api/auth/verify\?token=([A-Za-z0-9_-]+)`)
+
+// lastVerificationToken extracts the token from the newest verification email
+func lastVerificationToken(t *testing.T, mailer *mail.MemoryMailer, email string) string {
+	t.Helper()
+
+	msg, ok := mailer.Last(email)
+	if !ok {
+		t.Fatalf("No email sent to %s", email)
+	}
+	match := verifyLinkPattern.FindStringSubmatch(msg.Body)
+	if match == nil {
+		t.Fatalf("No verification link in email: %s", msg.Body)
+	}
+	return match[1]
+}
+
+func verify(t *testing.T, app *fiber.App, token string) (int, map[string]string) {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", "/api/auth/verify?token="+token, nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result map[string]string
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func resendVerification(t *testing.T, app *fiber.App, token string) (int, map[string]string) {
+	t.Helper()
+
+	req := httptest.NewRequest("POST", "/api/auth/resend-verification", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result map[string]string
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func TestEmailVerification(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	mailer := mail.NewMemoryMailer()
+	app := testutils.SetupTestAppWithServices(t, db, api.Services{Mailer: mailer})
+
+	token, userID := registerAndLogin(t, app, "test@example.com", "password123")
+
+	msg, ok := mailer.Last("test@example.com")
+	assert.True(t, ok, "Expected a verification email")
+	assert.Equal(t, "Verify your email address", msg.Subject)
+	verificationToken := lastVerificationToken(t, mailer, "test@example.com")
+
+	// Only a hash of the token is stored
+	var user models.User
+	db.First(&user, userID)
+	assert.NotEmpty(t, user.VerificationToken)
+	assert.NotEqual(t, verificationToken, user.VerificationToken)
+
+	t.Run("resend too soon", func(t *testing.T) {
+		status, result := resendVerification(t, app, token)
+		assert.Equal(t, fiber.StatusTooManyRequests, status)
+		assert.Contains(t, result["detail"], "recently sent")
+	})
+
+	t.Run("resend replaces the token", func(t *testing.T) {
+		db.Model(&models.User{}).Where("id = ?", userID).Update("verification_sent_at", time.Now().Add(-2*time.Minute))
+
+		status, _ := resendVerification(t, app, token)
+		assert.Equal(t, fiber.StatusOK, status)
+
+		newToken := lastVerificationToken(t, mailer, "test@example.com")
+		assert.NotEqual(t, verificationToken, newToken)
+
+		status, _ = verify(t, app, verificationToken)
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		verificationToken = newToken
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		db.Model(&models.User{}).Where("id = ?", userID).Update("verification_sent_at", time.Now().Add(-25*time.Hour))
+
+		status, result := verify(t, app, verificationToken)
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Contains(t, result["detail"], "Invalid or expired verification token")
+
+		db.Model(&models.User{}).Where("id = ?", userID).Update("verification_sent_at", time.Now())
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		status, result := verify(t, app, "bogus")
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Contains(t, result["detail"], "Invalid or expired verification token")
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		status, result := verify(t, app, "")
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Contains(t, result["detail"], "Verification token is required")
+	})
+
+	t.Run("successful verification", func(t *testing.T) {
+		status, _ := verify(t, app, verificationToken)
+		assert.Equal(t, fiber.StatusOK, status)
+
+		status, me := getMe(t, app, token)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, true, me["email_verified"])
+
+		// Tokens are single use
+		status, _ = verify(t, app, verificationToken)
+		assert.Equal(t, fiber.StatusBadRequest, status)
+	})
+
+	t.Run("resend after verification", func(t *testing.T) {
+		status, result := resendVerification(t, app, token)
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Contains(t, result["detail"], "Email already verified")
+	})
+
+	t.Run("resend without token", func(t *testing.T) {
+		status, _ := resendVerification(t, app, "")
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+	})
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	mailer := mail.NewMemoryMailer()
+	app := testutils.SetupTestAppWithServices(t, db, api.Services{
+		Mailer:               mailer,
+		RequireVerifiedEmail: true,
+	})
+
+	token, _ := registerAndLogin(t, app, "test@example.com", "password123")
+
+	createThought := func() (int, map[string]string) {
+		payload, _ := json.Marshal(map[string]string{"content": "Hello"})
+		req := httptest.NewRequest("POST", "/api/thoughts", bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result map[string]string
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+
+	status, result := createThought()
+	assert.Equal(t, fiber.StatusForbidden, status)
+	assert.Contains(t, result["detail"], "Email verification required")
+
+	status, _ = verify(t, app, lastVerificationToken(t, mailer, "test@example.com"))
+	assert.Equal(t, fiber.StatusOK, status)
+
+	status, _ = createThought()
+	assert.Equal(t, fiber.StatusCreated, status)
+}
@@ -0,0 +1,195 @@
+// Package apierror defines the errors the API reports to clients. Each has a
+// stable machine-readable code, and they are sent as RFC 7807
+// application/problem+json bodies by Handler, the app's error handler.
+// Handlers return them like any other error. The client package decodes the
+// bodies back into Error values, which errors.Is matches by code.
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Code identifies a kind of error. Codes are part of the API: clients
+// branch on them, so they never change once published.
+type Code string
+
+const (
+	// CodeBadRequest is a malformed request, such as a body that is not JSON
+	// or an invalid query parameter
+	CodeBadRequest Code = "bad_request"
+	// CodeValidationFailed is a well-formed request with invalid fields,
+	// listed in the problem's errors
+	CodeValidationFailed Code = "validation_failed"
+	// CodeUnauthorized is a request without an access token
+	CodeUnauthorized Code = "unauthorized"
+	// CodeInvalidCredentials is a login with a wrong email or password
+	CodeInvalidCredentials Code = "invalid_credentials"
+	// CodeInvalidToken is an invalid or expired access, refresh,
+	// verification or password reset token
+	CodeInvalidToken Code = "invalid_token"
+	// CodeSessionRevoked is a token of a session ended by a logout, a
+	// password change or refresh token reuse
+	CodeSessionRevoked Code = "session_revoked"
+	// CodeIncorrectPassword is a wrong current password confirming a
+	// sensitive change
+	CodeIncorrectPassword Code = "incorrect_password"
+	// CodeEmailNotVerified is an action that needs a verified email
+	CodeEmailNotVerified Code = "email_not_verified"
+	// CodeEmailAlreadyVerified is a verification request for a verified email
+	CodeEmailAlreadyVerified Code = "email_already_verified"
+	// CodeEmailTaken is a sign up or email change to an address in use
+	CodeEmailTaken Code = "email_taken"
+	// CodeForbidden is an authenticated request that is not allowed
+	CodeForbidden Code = "forbidden"
+	// CodeNotFound is a missing resource or route
+	CodeNotFound Code = "not_found"
+	// CodeMethodNotAllowed is a route that exists for other methods
+	CodeMethodNotAllowed Code = "method_not_allowed"
+	// CodePayloadTooLarge is a body over the size limit
+	CodePayloadTooLarge Code = "payload_too_large"
+	// CodeRateLimited is a request over a rate limit
+	CodeRateLimited Code = "rate_limited"
+	// CodeAccountLocked is a login to an account locked after repeated
+	// failed logins
+	CodeAccountLocked Code = "account_locked"
+	// CodeLoginFailed is a login through an external provider that could
+	// not be completed, such as one cancelled at the provider or returning
+	// to another browser than it started in
+	CodeLoginFailed Code = "login_failed"
+	// CodeTwoFactorEnabled is a two-factor setup for an account that has
+	// it enabled already
+	CodeTwoFactorEnabled Code = "two_factor_enabled"
+	// CodeInvalidCode is a wrong, expired or already used authenticator or
+	// recovery code
+	CodeInvalidCode Code = "invalid_code"
+	// CodeReauthRequired is a sensitive change by an account without a
+	// password whose session did not start with a recent login at its
+	// provider
+	CodeReauthRequired Code = "reauth_required"
+	// CodeInternal is a failure on the server's side
+	CodeInternal Code = "internal"
+	// CodeUnavailable is a server that cannot take requests right now
+	CodeUnavailable Code = "unavailable"
+)
+
+// Sentinels for errors.Is. They match any Error with the same code.
+var (
+	ErrBadRequest           = &Error{Code: CodeBadRequest}
+	ErrValidationFailed     = &Error{Code: CodeValidationFailed}
+	ErrUnauthorized         = &Error{Code: CodeUnauthorized}
+	ErrInvalidCredentials   = &Error{Code: CodeInvalidCredentials}
+	ErrInvalidToken         = &Error{Code: CodeInvalidToken}
+	ErrSessionRevoked       = &Error{Code: CodeSessionRevoked}
+	ErrIncorrectPassword    = &Error{Code: CodeIncorrectPassword}
+	ErrEmailNotVerified     = &Error{Code: CodeEmailNotVerified}
+	ErrEmailAlreadyVerified = &Error{Code: CodeEmailAlreadyVerified}
+	ErrEmailTaken           = &Error{Code: CodeEmailTaken}
+	ErrForbidden            = &Error{Code: CodeForbidden}
+	ErrNotFound             = &Error{Code: CodeNotFound}
+	ErrMethodNotAllowed     = &Error{Code: CodeMethodNotAllowed}
+	ErrPayloadTooLarge      = &Error{Code: CodePayloadTooLarge}
+	ErrRateLimited          = &Error{Code: CodeRateLimited}
+	ErrAccountLocked        = &Error{Code: CodeAccountLocked}
+	ErrLoginFailed          = &Error{Code: CodeLoginFailed}
+	ErrTwoFactorEnabled     = &Error{Code: CodeTwoFactorEnabled}
+	ErrInvalidCode          = &Error{Code: CodeInvalidCode}
+	ErrReauthRequired       = &Error{Code: CodeReauthRequired}
+	ErrInternal             = &Error{Code: CodeInternal}
+	ErrUnavailable          = &Error{Code: CodeUnavailable}
+)
+
+// FieldError describes one invalid field of a request
+type FieldError struct {
+	// Field is the JSON name of the field
+	Field string `json:"field"`
+	// Code is the rule the value broke, such as required, email or min
+	Code string `json:"code"`
+	// Message explains the problem to a person
+	Message string `json:"message"`
+}
+
+// Error is an API error. Status and Code are sent to the client together
+// with Detail, a human-readable explanation, and any invalid Fields. Err is
+// the underlying cause; it is logged but never sent.
+type Error struct {
+	Status int
+	Code   Code
+	Detail string
+	Fields []FieldError
+	// RequestID is the ID of the failed request, filled in by the client
+	RequestID string
+	Err       error
+}
+
+// New returns an error with the given status, code and detail
+func New(status int, code Code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// BadRequest returns a 400 error for a malformed request
+func BadRequest(detail string) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, detail)
+}
+
+// Unauthorized returns a 401 error with the given code
+func Unauthorized(code Code, detail string) *Error {
+	return New(http.StatusUnauthorized, code, detail)
+}
+
+// Forbidden returns a 403 error with the given code
+func Forbidden(code Code, detail string) *Error {
+	return New(http.StatusForbidden, code, detail)
+}
+
+// NotFound returns a 404 error
+func NotFound(detail string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, detail)
+}
+
+// Internal returns a 500 error reporting detail to the client and keeping
+// err for the logs
+func Internal(detail string, err error) *Error {
+	e := New(http.StatusInternalServerError, CodeInternal, detail)
+	e.Err = err
+	return e
+}
+
+// LoginFailed returns a 400 error for an external login that could not be
+// completed, keeping err for the logs
+func LoginFailed(detail string, err error) *Error {
+	e := New(http.StatusBadRequest, CodeLoginFailed, detail)
+	e.Err = err
+	return e
+}
+
+func (e *Error) Error() string {
+	msg := string(e.Code)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an Error with the same code, so errors.Is
+// matches the sentinels
+func (e *Error) Is(target error) bool {
+	var t *Error
+	return errors.As(target, &t) && t.Code == e.Code
+}
+
+// Title returns the short summary of the status, such as "Not Found"
+func (e *Error) Title() string {
+	if title := http.StatusText(e.Status); title != "" {
+		return title
+	}
+	return fmt.Sprintf("Status %d", e.Status)
+}
@@ -0,0 +1,65 @@
+package apierror_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/apierror"
+)
+
+func TestIsMatchesByCode(t *testing.T) {
+	err := fmt.Errorf("loading thought: %w", apierror.NotFound("Thought not found"))
+
+	assert.ErrorIs(t, err, apierror.ErrNotFound)
+	assert.NotErrorIs(t, err, apierror.ErrUnauthorized)
+}
+
+func TestInternalKeepsCause(t *testing.T) {
+	cause := errors.New("database is locked")
+	err := apierror.Internal("Could not create thought", cause)
+
+	assert.ErrorIs(t, err, cause)
+	assert.ErrorIs(t, err, apierror.ErrInternal)
+	assert.Equal(t, "Could not create thought", err.Problem().Detail)
+}
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   apierror.Code
+	}{
+		{name: "api error", err: apierror.BadRequest("Invalid cursor"), status: 400, code: apierror.CodeBadRequest},
+		{name: "fiber not found", err: fiber.ErrNotFound, status: 404, code: apierror.CodeNotFound},
+		{name: "fiber body limit", err: fiber.ErrRequestEntityTooLarge, status: 413, code: apierror.CodePayloadTooLarge},
+		{name: "fiber bad request", err: fiber.ErrUnprocessableEntity, status: 422, code: apierror.CodeBadRequest},
+		{name: "plain error", err: errors.New("boom"), status: 500, code: apierror.CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := apierror.From(tt.err)
+			assert.Equal(t, tt.status, err.Status)
+			assert.Equal(t, tt.code, err.Code)
+		})
+	}
+}
+
+func TestProblemRoundTrip(t *testing.T) {
+	err := apierror.Invalid("content", "required", "Content is required")
+	problem := err.Problem()
+
+	assert.Equal(t, "urn:thoughts:problem:validation_failed", problem.Type)
+	assert.Equal(t, "Bad Request", problem.Title)
+	assert.Equal(t, err.Fields, problem.AsError().Fields)
+	assert.ErrorIs(t, problem.AsError(), apierror.ErrValidationFailed)
+}
+
+func TestValidationOfOtherErrors(t *testing.T) {
+	err := apierror.Validation(errors.New("not a validator error"))
+	assert.Equal(t, apierror.CodeBadRequest, err.Code)
+}
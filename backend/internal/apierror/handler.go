@@ -0,0 +1,116 @@
+package apierror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/backend/internal/logging"
+)
+
+// ContentType is the media type of problem bodies
+const ContentType = "application/problem+json"
+
+// typePrefix turns a code into the problem type URI. The URIs only
+// identify the problem type; they do not resolve to documentation.
+const typePrefix = "urn:thoughts:problem:"
+
+// Problem is the RFC 7807 body of an error response, extended with the
+// error code, the request ID and the invalid fields
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// Problem returns the body describing e
+func (e *Error) Problem() Problem {
+	return Problem{
+		Type:   typePrefix + string(e.Code),
+		Title:  e.Title(),
+		Status: e.Status,
+		Detail: e.Detail,
+		Code:   e.Code,
+		Errors: e.Fields,
+	}
+}
+
+// AsError returns the error described by the problem
+func (p Problem) AsError() *Error {
+	return &Error{
+		Status:    p.Status,
+		Code:      p.Code,
+		Detail:    p.Detail,
+		Fields:    p.Errors,
+		RequestID: p.RequestID,
+	}
+}
+
+// From converts any error into an Error. Fiber's errors, such as the 404 for
+// an unknown route, keep their status; anything else is an internal error.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return New(fiberErr.Code, CodeForStatus(fiberErr.Code), fiberErr.Message)
+	}
+	return Internal("Internal server error", err)
+}
+
+// CodeForStatus picks the code of an error that only has a status, such
+// as one of Fiber's or a response without a problem body
+func CodeForStatus(status int) Code {
+	switch status {
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
+// Handler is the Fiber error handler writing every error returned by a
+// handler as a problem. Server errors are logged with their cause, which
+// stays out of the response.
+func Handler(c *fiber.Ctx, err error) error {
+	apiErr := From(err)
+	if apiErr.Status >= http.StatusInternalServerError {
+		cause := apiErr.Err
+		if cause == nil {
+			cause = err
+		}
+		logging.FromContext(c.UserContext()).Error(apiErr.Detail, "error", cause)
+	}
+
+	problem := apiErr.Problem()
+	// The path without the query, which can hold tokens
+	problem.Instance = c.Path()
+	problem.RequestID = logging.RequestID(c)
+
+	if err := c.Status(apiErr.Status).JSON(problem); err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, ContentType)
+	return nil
+}
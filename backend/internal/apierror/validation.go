@@ -0,0 +1,56 @@
+package apierror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Invalid returns a validation error for a single field. rule names the
+// broken rule, such as required, or is "invalid" when no rule fits.
+func Invalid(field, rule, message string) *Error {
+	e := New(http.StatusBadRequest, CodeValidationFailed, message)
+	e.Fields = []FieldError{{Field: field, Code: rule, Message: message}}
+	return e
+}
+
+// Validation turns the errors of validator.Struct into a validation error
+// listing every invalid field. The detail repeats the first field's message
+// for clients that show a single line. The validator should report JSON
+// field names, see RegisterTagNameFunc.
+func Validation(err error) *Error {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) || len(validationErrors) == 0 {
+		return BadRequest("Invalid request")
+	}
+
+	e := New(http.StatusBadRequest, CodeValidationFailed, "")
+	for _, fieldErr := range validationErrors {
+		e.Fields = append(e.Fields, FieldError{
+			Field:   fieldErr.Field(),
+			Code:    fieldErr.Tag(),
+			Message: fieldMessage(fieldErr),
+		})
+	}
+	e.Detail = e.Fields[0].Message
+	return e
+}
+
+// fieldMessage describes a failed rule in words, naming the field as it is
+// declared in Go
+func fieldMessage(fieldErr validator.FieldError) string {
+	name := fieldErr.StructField()
+	switch fieldErr.Tag() {
+	case "required":
+		return name + " is required"
+	case "email":
+		return "Invalid email format"
+	case "min":
+		return name + " must be at least " + fieldErr.Param() + " characters"
+	case "max":
+		return name + " must be at most " + fieldErr.Param() + " characters"
+	default:
+		return "Invalid " + name
+	}
+}
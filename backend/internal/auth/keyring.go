@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ClockSkew is the leeway given to exp and nbf, for verifiers whose clocks
+// are slightly off
+const ClockSkew = 30 * time.Second
+
+// Claims are the claims of an access token. Issuer, audience, expiry and
+// not-before are always set.
+type Claims struct {
+	UserID    uint   `json:"user_id"`
+	SessionID string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// Validate requires the claims the parser only checks when present
+func (c Claims) Validate() error {
+	switch {
+	case c.ExpiresAt == nil:
+		return errors.New("token has no expiry")
+	case c.NotBefore == nil:
+		return errors.New("token has no not-before time")
+	case c.UserID == 0 || c.SessionID == "":
+		return errors.New("token has no user or session")
+	}
+	return nil
+}
+
+// challengeAudience is appended to the audience of MFA challenge tokens,
+// so they are never accepted as access tokens and the other way round
+const challengeAudience = "#mfa"
+
+// ChallengeClaims are the claims of an MFA challenge token, which a login
+// with the right password returns instead of an access token when the
+// account has two-factor authentication enabled. It is exchanged for the
+// access token together with a second factor.
+type ChallengeClaims struct {
+	UserID uint `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+// Validate requires the claims the parser only checks when present
+func (c ChallengeClaims) Validate() error {
+	switch {
+	case c.ExpiresAt == nil:
+		return errors.New("token has no expiry")
+	case c.UserID == 0:
+		return errors.New("token has no user")
+	}
+	return nil
+}
+
+// JWKS is the JSON Web Key Set served to verifiers
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeyRing signs access tokens with its current key and verifies them with
+// any key it holds. Rotating in a new key keeps the old one for verification
+// until the tokens it signed have expired. A KeyRing is safe for concurrent
+// use.
+type KeyRing struct {
+	issuer   string
+	audience string
+
+	mu      sync.RWMutex
+	current *Key
+	// keys are the verification keys by ID, including the current one
+	keys map[string]*Key
+	// retiredUntil holds when each rotated-out key stops verifying. Keys
+	// without an entry verify until removed from the configuration.
+	retiredUntil map[string]time.Time
+}
+
+// NewKeyRing returns a key ring signing with current and also verifying
+// with the retired keys. Tokens name issuer as iss and audience as aud, and
+// tokens naming anything else are rejected.
+func NewKeyRing(issuer, audience string, current *Key, retired ...*Key) (*KeyRing, error) {
+	if !current.CanSign() {
+		return nil, fmt.Errorf("signing key %s has no private key", current.ID)
+	}
+
+	r := &KeyRing{
+		issuer:       issuer,
+		audience:     audience,
+		current:      current,
+		keys:         map[string]*Key{current.ID: current},
+		retiredUntil: make(map[string]time.Time),
+	}
+	for _, key := range retired {
+		r.keys[key.ID] = key
+	}
+	return r, nil
+}
+
+// Current returns the signing key
+func (r *KeyRing) Current() *Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current
+}
+
+// Rotate makes next the signing key. The previous one keeps verifying for
+// retain, which should cover the lifetime of the tokens it signed.
+func (r *KeyRing) Rotate(next *Key, retain time.Duration) error {
+	if !next.CanSign() {
+		return fmt.Errorf("signing key %s has no private key", next.ID)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if next.ID == r.current.ID {
+		return nil
+	}
+	r.retiredUntil[r.current.ID] = time.Now().Add(retain)
+	r.current = next
+	r.keys[next.ID] = next
+	delete(r.retiredUntil, next.ID)
+	return nil
+}
+
+// Sign returns a token for claims, signed with the current key and naming
+// it in the kid header. The issuer and audience are filled in.
+func (r *KeyRing) Sign(claims Claims) (string, error) {
+	claims.Issuer = r.issuer
+	claims.Audience = jwt.ClaimStrings{r.audience}
+	return r.sign(claims)
+}
+
+// SignChallenge returns an MFA challenge token for claims, filling in the
+// issuer and the challenge audience
+func (r *KeyRing) SignChallenge(claims ChallengeClaims) (string, error) {
+	claims.Issuer = r.issuer
+	claims.Audience = jwt.ClaimStrings{r.audience + challengeAudience}
+	return r.sign(claims)
+}
+
+func (r *KeyRing) sign(claims jwt.Claims) (string, error) {
+	key := r.Current()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
+}
+
+// Parse verifies a token and returns its claims. The token must name one of
+// the ring's keys and use that key's algorithm, so a token cannot pick a
+// weaker algorithm or pass a public key off as an HMAC secret.
+func (r *KeyRing) Parse(tokenString string) (*Claims, error) {
+	var claims Claims
+	if err := r.parse(tokenString, &claims, r.audience); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// ParseChallenge verifies an MFA challenge token like Parse does access
+// tokens and returns its claims
+func (r *KeyRing) ParseChallenge(tokenString string) (*ChallengeClaims, error) {
+	var claims ChallengeClaims
+	if err := r.parse(tokenString, &claims, r.audience+challengeAudience); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func (r *KeyRing) parse(tokenString string, claims jwt.Claims, audience string) error {
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key := r.verificationKey(kid)
+		if key == nil {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("key %q is not used with %s", kid, token.Method.Alg())
+		}
+		return key.public, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(r.issuer),
+		jwt.WithAudience(audience),
+		jwt.WithLeeway(ClockSkew),
+	)
+	return err
+}
+
+// verificationKey returns the key with the ID unless it has been retired
+// for longer than it was to be kept
+func (r *KeyRing) verificationKey(id string) *Key {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := r.keys[id]
+	if until, retired := r.retiredUntil[id]; retired && time.Now().After(until) {
+		delete(r.keys, id)
+		delete(r.retiredUntil, id)
+		return nil
+	}
+	return key
+}
+
+// JWKS returns the public keys tokens can be verified with, the current
+// key first
+func (r *KeyRing) JWKS() JWKS {
+	now := time.Now()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var others []JWK
+	for id, key := range r.keys {
+		if id == r.current.ID {
+			continue
+		}
+		if until, retired := r.retiredUntil[id]; retired && now.After(until) {
+			continue
+		}
+		others = append(others, key.JWK())
+	}
+	sort.Slice(others, func(i, j int) bool { return others[i].KeyID < others[j].KeyID })
+	return JWKS{Keys: append([]JWK{r.current.JWK()}, others...)}
+}
@@ -0,0 +1,291 @@
+package auth_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/auth"
+)
+
+const (
+	issuer   = "https://api.example.com"
+	audience = "thoughts"
+)
+
+func claims(lifetime time.Duration) auth.Claims {
+	now := time.Now()
+	return auth.Claims{
+		UserID:    42,
+		SessionID: "session",
+		RegisteredClaims: jwt.RegisteredClaims{
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(lifetime)),
+		},
+	}
+}
+
+func generateKey(t *testing.T) *auth.Key {
+	t.Helper()
+	key, err := auth.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newRing(t *testing.T, current *auth.Key, retired ...*auth.Key) *auth.KeyRing {
+	t.Helper()
+	ring, err := auth.NewKeyRing(issuer, audience, current, retired...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ring
+}
+
+// writePEM writes a PEM block to a file in a temporary directory
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSignAndParse(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPath := writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	edPath := writePEM(t, "PRIVATE KEY", der)
+
+	for alg, path := range map[string]string{"RS256": rsaPath, "EdDSA": edPath} {
+		t.Run(alg, func(t *testing.T) {
+			key, err := auth.LoadKey(path)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, alg, key.Method.Alg())
+			ring := newRing(t, key)
+
+			signed, err := ring.Sign(claims(time.Minute))
+			assert.NoError(t, err)
+
+			parsed, err := ring.Parse(signed)
+			if assert.NoError(t, err) {
+				assert.Equal(t, uint(42), parsed.UserID)
+				assert.Equal(t, "session", parsed.SessionID)
+				assert.Equal(t, issuer, parsed.Issuer)
+				assert.Equal(t, jwt.ClaimStrings{audience}, parsed.Audience)
+			}
+
+			token, _, _ := jwt.NewParser().ParseUnverified(signed, &auth.Claims{})
+			assert.Equal(t, key.ID, token.Header["kid"])
+		})
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	ring := newRing(t, generateKey(t))
+	challenge, err := ring.SignChallenge(auth.ChallengeClaims{
+		UserID:           42,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	parsed, err := ring.ParseChallenge(challenge)
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint(42), parsed.UserID)
+	}
+
+	// Challenges and access tokens are not interchangeable
+	_, err = ring.Parse(challenge)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+	access, _ := ring.Sign(claims(time.Minute))
+	_, err = ring.ParseChallenge(access)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+
+	expired, _ := ring.SignChallenge(auth.ChallengeClaims{
+		UserID:           42,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour))},
+	})
+	_, err = ring.ParseChallenge(expired)
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+}
+
+func TestLoadKeyRejectsWeakKeys(t *testing.T) {
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = auth.LoadKey(writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(small)))
+	assert.ErrorContains(t, err, "at least 2048 are required")
+
+	_, err = auth.ParseKey([]byte("not a key"))
+	assert.Error(t, err)
+}
+
+func TestParseRejects(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(private)
+	key, err := auth.ParseKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring := newRing(t, key)
+
+	sign := func(method jwt.SigningMethod, secret any) string {
+		c := claims(time.Minute)
+		c.Issuer = issuer
+		c.Audience = jwt.ClaimStrings{audience}
+		token := jwt.NewWithClaims(method, c)
+		token.Header["kid"] = key.ID
+		signed, err := token.SignedString(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	signFor := func(iss, aud string) string {
+		other, err := auth.NewKeyRing(iss, aud, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signed, _ := other.Sign(claims(time.Minute))
+		return signed
+	}
+
+	publicDER, _ := x509.MarshalPKIXPublicKey(public)
+	foreign, _ := newRing(t, generateKey(t)).Sign(claims(time.Minute))
+	noNotBefore := claims(time.Minute)
+	noNotBefore.NotBefore = nil
+	withoutNotBefore, _ := ring.Sign(noNotBefore)
+	future := claims(time.Hour)
+	future.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Minute))
+	notYetValid, _ := ring.Sign(future)
+	noSession := claims(time.Minute)
+	noSession.SessionID = ""
+	withoutSession, _ := ring.Sign(noSession)
+	expired, _ := ring.Sign(claims(-time.Minute))
+
+	tests := map[string]string{
+		// An HMAC token keyed with the public key must not pass as signed
+		"algorithm confusion": sign(jwt.SigningMethodHS256, publicDER),
+		"none algorithm":      sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType),
+		"unknown key":         foreign,
+		"wrong issuer":        signFor("https://evil.example.com", audience),
+		"wrong audience":      signFor(issuer, "other-service"),
+		"no not-before":       withoutNotBefore,
+		"not yet valid":       notYetValid,
+		"no session":          withoutSession,
+		"expired":             expired,
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ring.Parse(token)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRotate(t *testing.T) {
+	old, next, newest := generateKey(t), generateKey(t), generateKey(t)
+	ring := newRing(t, old)
+	oldToken, _ := ring.Sign(claims(time.Minute))
+
+	assert.NoError(t, ring.Rotate(next, time.Hour))
+	assert.Equal(t, next.ID, ring.Current().ID)
+
+	// Tokens of the previous key stay valid, new tokens use the new key
+	_, err := ring.Parse(oldToken)
+	assert.NoError(t, err)
+	nextToken, _ := ring.Sign(claims(time.Minute))
+	token, _, _ := jwt.NewParser().ParseUnverified(nextToken, &auth.Claims{})
+	assert.Equal(t, next.ID, token.Header["kid"])
+
+	jwks := ring.JWKS()
+	if assert.Len(t, jwks.Keys, 2) {
+		assert.Equal(t, next.ID, jwks.Keys[0].KeyID)
+		assert.Equal(t, old.ID, jwks.Keys[1].KeyID)
+	}
+
+	// Once retired for longer than retain, a key verifies nothing
+	assert.NoError(t, ring.Rotate(newest, 0))
+	_, err = ring.Parse(nextToken)
+	assert.Error(t, err)
+	_, err = ring.Parse(oldToken)
+	assert.NoError(t, err)
+	assert.Len(t, ring.JWKS().Keys, 2)
+
+	// Verification-only keys cannot sign
+	publicOnly, err := auth.ParseKey(publicPEM(t, old))
+	if assert.NoError(t, err) {
+		assert.False(t, publicOnly.CanSign())
+		assert.Equal(t, old.ID, publicOnly.ID)
+		assert.Error(t, ring.Rotate(publicOnly, time.Hour))
+	}
+}
+
+func TestJWK(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := auth.ParseKey(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwk := key.JWK()
+	assert.Equal(t, "RSA", jwk.KeyType)
+	assert.Equal(t, "RS256", jwk.Algorithm)
+	assert.Equal(t, "sig", jwk.Use)
+	assert.Equal(t, "AQAB", jwk.E)
+	assert.NotEmpty(t, jwk.N)
+	assert.Empty(t, jwk.X)
+
+	// RFC 8037 test vector: the thumbprint of the example Ed25519 key
+	ed, err := auth.ParseKey([]byte(rfc8037PublicKey))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", ed.ID)
+		assert.Equal(t, "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo", ed.JWK().X)
+		assert.Equal(t, "OKP", ed.JWK().KeyType)
+		assert.Equal(t, "Ed25519", ed.JWK().Curve)
+	}
+}
+
+// rfc8037PublicKey is the public key of RFC 8037 appendix A.2
+const rfc8037PublicKey = `-----BEGIN PUBLIC KEY-----
+MCowBQYDK2VwAyEA11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo=
+-----END PUBLIC KEY-----
+`
+
+// publicPEM encodes the public half of key, rebuilt from its JWK
+func publicPEM(t *testing.T, key *auth.Key) []byte {
+	t.Helper()
+	x, err := base64.RawURLEncoding.DecodeString(key.JWK().X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(ed25519.PublicKey(x))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// MinRSAKeyBits is the smallest RSA key accepted for RS256
+const MinRSAKeyBits = 2048
+
+// Key is a token signing key. Keys loaded from a public key can only
+// verify.
+type Key struct {
+	// ID is the RFC 7638 thumbprint of the public key, sent as the kid
+	// header so verifiers can pick the key
+	ID string
+	// Method is the one algorithm the key is used with: RS256 for RSA keys,
+	// EdDSA for Ed25519 keys
+	Method jwt.SigningMethod
+
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// GenerateKey returns a fresh Ed25519 key
+func GenerateKey() (*Key, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return newKey(private, private.Public())
+}
+
+// LoadKey reads a PEM file holding a PKCS#8 or PKCS#1 private key, or a
+// PKIX public key for a key that only verifies
+func LoadKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key: %w", err)
+	}
+	key, err := ParseKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// ParseKey parses the first PEM block of data, see LoadKey
+func ParseKey(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", parsed)
+		}
+		return newKey(signer, signer.Public())
+	case "RSA PRIVATE KEY":
+		private, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newKey(private, private.Public())
+	case "PUBLIC KEY":
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newKey(nil, public)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+func newKey(private crypto.Signer, public crypto.PublicKey) (*Key, error) {
+	key := &Key{private: private, public: public}
+	switch public := public.(type) {
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	case *rsa.PublicKey:
+		if public.N.BitLen() < MinRSAKeyBits {
+			return nil, fmt.Errorf("RSA key has %d bits, at least %d are required", public.N.BitLen(), MinRSAKeyBits)
+		}
+		key.Method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", public)
+	}
+
+	thumbprint := sha256.Sum256(key.thumbprintInput())
+	key.ID = base64.RawURLEncoding.EncodeToString(thumbprint[:])
+	return key, nil
+}
+
+// CanSign reports whether the key holds its private half
+func (k *Key) CanSign() bool {
+	return k.private != nil
+}
+
+// JWK is a public key in the JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	// RSA modulus and exponent
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 curve and public key
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWK returns the public half of the key
+func (k *Key) JWK() JWK {
+	jwk := JWK{KeyID: k.ID, Algorithm: k.Method.Alg(), Use: "sig"}
+	switch public := k.public.(type) {
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	}
+	return jwk
+}
+
+// thumbprintInput is the JSON RFC 7638 hashes: the required members of the
+// JWK in lexicographic order
+func (k *Key) thumbprintInput() []byte {
+	jwk := k.JWK()
+	var members any
+	if jwk.KeyType == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X}
+	}
+	data, _ := json.Marshal(members)
+	return data
+}
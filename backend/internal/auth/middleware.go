@@ -2,54 +2,56 @@ package auth
 
 import (
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/logging"
+	"github.com/yourusername/backend/internal/metrics"
 	"github.com/yourusername/backend/internal/models"
-	"gorm.io/gorm"
-	"os"
+	"github.com/yourusername/backend/internal/store"
 	"strings"
 )
 
-// Protected protects routes
-func Protected() fiber.Handler {
+// Protected protects routes. Besides verifying the JWT it checks that the
+// session the token was issued for has not been revoked by a logout. Tokens
+// must be signed by a key in keys. Rejected tokens are counted in m, which
+// may be nil.
+func Protected(users store.UserStore, keys *KeyRing, m *metrics.Metrics) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Unauthorized",
-			})
+			m.JWTFailure(metrics.JWTMissing)
+			return apierror.Unauthorized(apierror.CodeUnauthorized, "Unauthorized")
 		}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
-
-		if err != nil || !token.Valid {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid or expired token",
-			})
+		claims, err := keys.Parse(strings.TrimPrefix(authHeader, "Bearer "))
+		if err != nil {
+			m.JWTFailure(metrics.JWTInvalid)
+			return apierror.Unauthorized(apierror.CodeInvalidToken, "Invalid or expired token")
 		}
+		userID, sessionID := claims.UserID, claims.SessionID
 
-		claims := token.Claims.(jwt.MapClaims)
-		userID := uint(claims["user_id"].(float64))
+		active, err := users.SessionActive(c.UserContext(), userID, sessionID)
+		if err != nil {
+			return apierror.Internal("Could not verify session", err)
+		}
+		if !active {
+			m.JWTFailure(metrics.JWTRevoked)
+			return apierror.Unauthorized(apierror.CodeSessionRevoked, "Session has been revoked")
+		}
 
 		// Set user ID in locals for use in route handlers
 		c.Locals("userID", userID)
+		c.Locals("sessionID", sessionID)
+		c.SetUserContext(logging.With(c.UserContext(), "user_id", userID))
 		return c.Next()
 	}
 }
 
 // GetUserFromContext gets the user from the context
-func GetUserFromContext(c *fiber.Ctx, db *gorm.DB) (*models.User, error) {
+func GetUserFromContext(c *fiber.Ctx, users store.UserStore) (*models.User, error) {
 	userID, ok := c.Locals("userID").(uint)
 	if !ok {
 		return nil, fiber.ErrUnauthorized
 	}
 
-	var user models.User
-	if err := db.First(&user, userID).Error; err != nil {
-		return nil, err
-	}
-
-	return &user, nil
+	return users.UserByID(c.UserContext(), userID)
 }
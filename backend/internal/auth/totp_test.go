@@ -0,0 +1,84 @@
+package auth_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/auth"
+)
+
+func TestTOTP(t *testing.T) {
+	secret, uri, err := auth.NewTOTP("Thoughts", "user@example.com")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	parsed, err := url.Parse(uri)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "otpauth", parsed.Scheme)
+		assert.Equal(t, "totp", parsed.Host)
+		assert.Equal(t, "/Thoughts:user@example.com", parsed.Path)
+		assert.Equal(t, secret, parsed.Query().Get("secret"))
+		assert.Equal(t, "Thoughts", parsed.Query().Get("issuer"))
+	}
+
+	now := time.Now()
+	code, err := auth.GenerateTOTP(secret, now)
+	if !assert.NoError(t, err) {
+		return
+	}
+	step, ok := auth.VerifyTOTP(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, auth.TOTPStep(now), step)
+
+	// A code from the previous step still works, for slow typists and
+	// clocks that are off, and reports its own step
+	previous, _ := auth.GenerateTOTP(secret, now.Add(-auth.TOTPPeriod))
+	step, ok = auth.VerifyTOTP(secret, previous, now)
+	assert.True(t, ok)
+	assert.Equal(t, auth.TOTPStep(now)-1, step)
+
+	// Older codes do not
+	old, _ := auth.GenerateTOTP(secret, now.Add(-3*auth.TOTPPeriod))
+	if old != code && old != previous {
+		_, ok = auth.VerifyTOTP(secret, old, now)
+		assert.False(t, ok)
+	}
+
+	for _, invalid := range []string{"", "12345", "1234567", "abcdef"} {
+		_, ok = auth.VerifyTOTP(secret, invalid, now)
+		assert.False(t, ok, invalid)
+	}
+}
+
+func TestTOTPVector(t *testing.T) {
+	// RFC 6238 appendix B, SHA-1, truncated to six digits
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	code, err := auth.GenerateTOTP(secret, time.Unix(59, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, "287082", code)
+	code, _ = auth.GenerateTOTP(secret, time.Unix(1111111109, 0))
+	assert.Equal(t, "081804", code)
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := auth.NewRecoveryCodes(10)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, codes, 10)
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		assert.Regexp(t, `^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$`, code)
+		assert.False(t, seen[code])
+		seen[code] = true
+	}
+
+	code := codes[0]
+	assert.Equal(t, auth.NormalizeRecoveryCode(code), auth.NormalizeRecoveryCode(strings.ToUpper(code)))
+	assert.Equal(t, auth.NormalizeRecoveryCode(code), auth.NormalizeRecoveryCode(strings.ReplaceAll(code, "-", " ")))
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/yourusername/backend/internal/apierror"
+)
+
+// Error is the error the server reported for a failed request. Methods
+// return it wrapped, so use errors.As to inspect it or errors.Is with the
+// sentinels below to branch on its code.
+type Error = apierror.Error
+
+// Sentinels matching errors by code, as in errors.Is(err, ErrNotFound)
+var (
+	ErrBadRequest           = apierror.ErrBadRequest
+	ErrValidationFailed     = apierror.ErrValidationFailed
+	ErrUnauthorized         = apierror.ErrUnauthorized
+	ErrInvalidCredentials   = apierror.ErrInvalidCredentials
+	ErrInvalidToken         = apierror.ErrInvalidToken
+	ErrSessionRevoked       = apierror.ErrSessionRevoked
+	ErrIncorrectPassword    = apierror.ErrIncorrectPassword
+	ErrEmailNotVerified     = apierror.ErrEmailNotVerified
+	ErrEmailAlreadyVerified = apierror.ErrEmailAlreadyVerified
+	ErrEmailTaken           = apierror.ErrEmailTaken
+	ErrForbidden            = apierror.ErrForbidden
+	ErrNotFound             = apierror.ErrNotFound
+	ErrMethodNotAllowed     = apierror.ErrMethodNotAllowed
+	ErrPayloadTooLarge      = apierror.ErrPayloadTooLarge
+	ErrRateLimited          = apierror.ErrRateLimited
+	ErrAccountLocked        = apierror.ErrAccountLocked
+	ErrLoginFailed          = apierror.ErrLoginFailed
+	ErrTwoFactorEnabled     = apierror.ErrTwoFactorEnabled
+	ErrInvalidCode          = apierror.ErrInvalidCode
+	ErrInternal             = apierror.ErrInternal
+	ErrUnavailable          = apierror.ErrUnavailable
+)
+
+// ErrMFARequired is returned by Login for accounts with two-factor
+// authentication. It is not a server error: the password was right, and
+// LoginTwoFactor finishes the login.
+var ErrMFARequired = errors.New("two-factor authentication required")
+
+// maxErrorBody bounds how much of an error response is read
+const maxErrorBody = 64 << 10
+
+// decodeError turns a failed response into an *Error. Responses without a
+// problem body, such as those of a proxy in front of the server, get the
+// code matching their status.
+func decodeError(resp *http.Response) *Error {
+	var problem apierror.Problem
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == apierror.ContentType {
+		_ = json.NewDecoder(io.LimitReader(resp.Body, maxErrorBody)).Decode(&problem)
+	}
+
+	if problem.Status == 0 {
+		problem.Status = resp.StatusCode
+	}
+	if problem.Code == "" {
+		problem.Code = apierror.CodeForStatus(resp.StatusCode)
+	}
+	if problem.Detail == "" {
+		problem.Detail = http.StatusText(resp.StatusCode)
+	}
+	return problem.AsError()
+}
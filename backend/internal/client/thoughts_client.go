@@ -2,19 +2,34 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/yourusername/backend/internal/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 type Client struct {
-	BaseURL    string
-	HTTPClient *http.Client
-	Token      string
+	BaseURL      string
+	HTTPClient   *http.Client
+	Token        string
+	RefreshToken string
+	// MFAToken is the challenge a login returns for accounts with
+	// two-factor authentication, until LoginTwoFactor exchanges it
+	MFAToken string
+	// Context is attached to every request. Its trace context is sent in
+	// the traceparent header, so the server's spans join the caller's
+	// trace. Nil means context.Background().
+	Context context.Context
 }
 
 func NewClient(baseURL string) *Client {
@@ -32,10 +47,15 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	MFARequired  bool   `json:"mfa_required"`
+	MFAToken     string `json:"mfa_token"`
 }
 
-// Login authenticates a user and stores the JWT token
+// Login authenticates a user and stores the JWT token. For accounts with
+// two-factor authentication it stores the MFA challenge instead and returns
+// ErrMFARequired; finish the login with LoginTwoFactor.
 func (c *Client) Login(email, password string) error {
 	loginReq := LoginRequest{
 		Email:    email,
@@ -49,7 +69,7 @@ func (c *Client) Login(email, password string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("login failed with status: %s", resp.Status)
+		return fmt.Errorf("login failed: %w", decodeError(resp))
 	}
 
 	var loginResp LoginResponse
@@ -57,14 +77,261 @@ func (c *Client) Login(email, password string) error {
 		return fmt.Errorf("failed to decode login response: %w", err)
 	}
 
+	if loginResp.MFARequired {
+		c.MFAToken = loginResp.MFAToken
+		return ErrMFARequired
+	}
 	c.Token = loginResp.Token
+	c.RefreshToken = loginResp.RefreshToken
 	return nil
 }
 
-// CreateThought creates a new thought
-func (c *Client) CreateThought(content string) (*models.Thought, error) {
-	thought := map[string]string{"content": content}
-	
+// LoginTwoFactor finishes a login that returned ErrMFARequired with a code
+// from the authenticator app or a recovery code
+func (c *Client) LoginTwoFactor(code string) error {
+	resp, err := c.doRequest("POST", "/api/auth/login/2fa", map[string]string{
+		"mfa_token": c.MFAToken,
+		"code":      code,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("login failed: %w", decodeError(resp))
+	}
+
+	var loginResp LoginResponse
+	if err := json.NewDecoder(resp.Body).Decode(&loginResp); err != nil {
+		return fmt.Errorf("failed to decode login response: %w", err)
+	}
+
+	c.Token = loginResp.Token
+	c.RefreshToken = loginResp.RefreshToken
+	c.MFAToken = ""
+	return nil
+}
+
+// Refresh exchanges the stored refresh token for a new token pair. Access
+// tokens are short-lived, so long-running clients call this when a request
+// fails with ErrInvalidToken.
+func (c *Client) Refresh() error {
+	resp, err := c.doRequest("POST", "/api/auth/refresh", map[string]string{
+		"refresh_token": c.RefreshToken,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("refresh failed: %w", decodeError(resp))
+	}
+
+	var loginResp LoginResponse
+	if err := json.NewDecoder(resp.Body).Decode(&loginResp); err != nil {
+		return fmt.Errorf("failed to decode refresh response: %w", err)
+	}
+
+	c.Token = loginResp.Token
+	c.RefreshToken = loginResp.RefreshToken
+	return nil
+}
+
+// Logout revokes the current session and forgets the stored tokens
+func (c *Client) Logout() error {
+	resp, err := c.doRequest("POST", "/api/auth/logout", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("logout failed: %w", decodeError(resp))
+	}
+
+	c.Token = ""
+	c.RefreshToken = ""
+	return nil
+}
+
+// User is the profile of the authenticated user
+type User struct {
+	ID            uint      `json:"id"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ChangePassword replaces the password of the authenticated user. Other
+// sessions are signed out; the client's own session stays valid.
+func (c *Client) ChangePassword(currentPassword, newPassword string) error {
+	resp, err := c.doRequest("PUT", "/api/me/password", map[string]string{
+		"current_password": currentPassword,
+		"new_password":     newPassword,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to change password: %w", decodeError(resp))
+	}
+
+	return nil
+}
+
+// ChangeEmail moves the account to a new email address. The address must be
+// verified again, so the returned user has EmailVerified unset.
+func (c *Client) ChangeEmail(password, email string) (*User, error) {
+	resp, err := c.doRequest("PUT", "/api/me/email", map[string]string{
+		"email":    email,
+		"password": password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to change email: %w", decodeError(resp))
+	}
+
+	var user User
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &user, nil
+}
+
+// DeleteAccount deletes the authenticated user and all of their thoughts.
+// When the server has a deletion grace period the account is only scheduled
+// for deletion and logging in again restores it. Either way the stored
+// tokens are forgotten.
+func (c *Client) DeleteAccount(password string) error {
+	resp, err := c.doRequest("DELETE", "/api/me", map[string]string{
+		"password": password,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to delete account: %w", decodeError(resp))
+	}
+
+	c.Token = ""
+	c.RefreshToken = ""
+	return nil
+}
+
+// TwoFactorSetup is a pending authenticator. URI is the otpauth:// link
+// authenticator apps read from a QR code; Secret is for typing in by hand.
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// SetupTwoFactor starts enabling two-factor authentication. It takes effect
+// once ConfirmTwoFactor sends a code from the authenticator app.
+func (c *Client) SetupTwoFactor(password string) (*TwoFactorSetup, error) {
+	resp, err := c.doRequest("POST", "/api/me/2fa/setup", map[string]string{
+		"password": password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to set up two-factor authentication: %w", decodeError(resp))
+	}
+
+	var setup TwoFactorSetup
+	if err := json.NewDecoder(resp.Body).Decode(&setup); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &setup, nil
+}
+
+// ConfirmTwoFactor enables two-factor authentication with a code from the
+// authenticator app and returns the recovery codes, which the server does
+// not show again
+func (c *Client) ConfirmTwoFactor(code string) ([]string, error) {
+	resp, err := c.doRequest("POST", "/api/me/2fa/confirm", map[string]string{
+		"code": code,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", decodeError(resp))
+	}
+
+	var codesResp struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&codesResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return codesResp.RecoveryCodes, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off. The code may be
+// from the authenticator app or a recovery code.
+func (c *Client) DisableTwoFactor(password, code string) error {
+	resp, err := c.doRequest("DELETE", "/api/me/2fa", map[string]string{
+		"password": password,
+		"code":     code,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", decodeError(resp))
+	}
+
+	return nil
+}
+
+// Export streams all of the user's data to w. Format is "json", "csv" or
+// "markdown" (a zip archive with one file per thought).
+func (c *Client) Export(format string, w io.Writer) error {
+	resp, err := c.doRequest("GET", "/api/me/export?format="+url.QueryEscape(format), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to export: %w", decodeError(resp))
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to read export: %w", err)
+	}
+
+	return nil
+}
+
+// CreateThought creates a new thought. Tags are added alongside any
+// #hashtags found in the content.
+func (c *Client) CreateThought(content string, tags ...string) (*models.Thought, error) {
+	thought := map[string]interface{}{"content": content}
+	if len(tags) > 0 {
+		thought["tags"] = tags
+	}
+
 	resp, err := c.doRequest("POST", "/api/thoughts", thought)
 	if err != nil {
 		return nil, err
@@ -72,7 +339,7 @@ func (c *Client) CreateThought(content string) (*models.Thought, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("failed to create thought: %s", resp.Status)
+		return nil, fmt.Errorf("failed to create thought: %w", decodeError(resp))
 	}
 
 	var createdThought models.Thought
@@ -83,26 +350,297 @@ func (c *Client) CreateThought(content string) (*models.Thought, error) {
 	return &createdThought, nil
 }
 
-// GetThoughts retrieves all thoughts
-func (c *Client) GetThoughts() ([]models.Thought, error) {
-	resp, err := c.doRequest("GET", "/api/thoughts", nil)
+// ThoughtPage is one page of thoughts returned by ListThoughts
+type ThoughtPage struct {
+	Thoughts   []models.Thought `json:"thoughts"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// ListThoughts retrieves a single page of thoughts. An empty cursor requests
+// the first page and a limit of zero uses the server default.
+func (c *Client) ListThoughts(limit int, cursor string) (*ThoughtPage, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+
+	path := "/api/thoughts"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp, err := c.doRequest("GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get thoughts: %s", resp.Status)
+		return nil, fmt.Errorf("failed to get thoughts: %w", decodeError(resp))
 	}
 
-	var thoughts []models.Thought
-	if err := json.NewDecoder(resp.Body).Decode(&thoughts); err != nil {
+	var page ThoughtPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	return &page, nil
+}
+
+// ThoughtIterator walks every thought of the user, fetching pages lazily
+//
+//	it := client.IterateThoughts(100)
+//	for it.Next() {
+//		thought := it.Thought()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type ThoughtIterator struct {
+	client  *Client
+	limit   int
+	cursor  string
+	buf     []models.Thought
+	current models.Thought
+	done    bool
+	err     error
+}
+
+// IterateThoughts returns an iterator over all thoughts, newest first,
+// requesting pages of the given size
+func (c *Client) IterateThoughts(pageSize int) *ThoughtIterator {
+	return &ThoughtIterator{client: c, limit: pageSize}
+}
+
+// Next advances to the next thought, fetching the next page when needed.
+// It returns false when there are no more thoughts or an error occurred.
+func (it *ThoughtIterator) Next() bool {
+	for len(it.buf) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+
+		page, err := it.client.ListThoughts(it.limit, it.cursor)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		it.buf = page.Thoughts
+		it.cursor = page.NextCursor
+		it.done = page.NextCursor == ""
+	}
+
+	it.current = it.buf[0]
+	it.buf = it.buf[1:]
+	return true
+}
+
+// Thought returns the thought at the current position
+func (it *ThoughtIterator) Thought() models.Thought {
+	return it.current
+}
+
+// Err returns the first error encountered while iterating
+func (it *ThoughtIterator) Err() error {
+	return it.err
+}
+
+// GetThoughts retrieves all thoughts, walking every page
+func (c *Client) GetThoughts() ([]models.Thought, error) {
+	var thoughts []models.Thought
+
+	it := c.IterateThoughts(0)
+	for it.Next() {
+		thoughts = append(thoughts, it.Thought())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
 	return thoughts, nil
 }
 
+// GetThought retrieves a single thought by ID
+func (c *Client) GetThought(id uint) (*models.Thought, error) {
+	resp, err := c.doRequest("GET", fmt.Sprintf("/api/thoughts/%d", id), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get thought: %w", decodeError(resp))
+	}
+
+	var thought models.Thought
+	if err := json.NewDecoder(resp.Body).Decode(&thought); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &thought, nil
+}
+
+// UpdateThought replaces the content of an existing thought
+func (c *Client) UpdateThought(id uint, content string) (*models.Thought, error) {
+	thought := map[string]string{"content": content}
+
+	resp, err := c.doRequest("PUT", fmt.Sprintf("/api/thoughts/%d", id), thought)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to update thought: %w", decodeError(resp))
+	}
+
+	var updatedThought models.Thought
+	if err := json.NewDecoder(resp.Body).Decode(&updatedThought); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &updatedThought, nil
+}
+
+// DeleteThought deletes a thought by ID
+func (c *Client) DeleteThought(id uint) error {
+	resp, err := c.doRequest("DELETE", fmt.Sprintf("/api/thoughts/%d", id), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to delete thought: %w", decodeError(resp))
+	}
+
+	return nil
+}
+
+// ImportResult reports what happened to one imported item
+type ImportResult struct {
+	Row    int    `json:"row"`
+	Status string `json:"status"`
+	ID     uint   `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport summarizes an import
+type ImportReport struct {
+	Imported int            `json:"imported"`
+	Rejected int            `json:"rejected"`
+	Results  []ImportResult `json:"results"`
+}
+
+// ImportThoughts uploads a JSON, CSV or plain-text file of thoughts. The
+// format is taken from the filename extension (.json, .csv, anything else
+// is text). Rejected items are listed in the report rather than failing the
+// whole import.
+func (c *Client) ImportThoughts(filename string, r io.Reader) (*ImportReport, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filepath.Base(filename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return nil, fmt.Errorf("failed to read import file: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(c.context(), "POST", c.BaseURL+"/api/thoughts/import", &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to import thoughts: %w", decodeError(resp))
+	}
+
+	var report ImportReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &report, nil
+}
+
+// SearchResult is a thought matching a search query
+type SearchResult struct {
+	Thought models.Thought `json:"thought"`
+	Snippet string         `json:"snippet"`
+	Rank    float64        `json:"rank"`
+}
+
+// SearchThoughts runs a full-text search over the user's thoughts. The query
+// supports "quoted phrases" and prefix* matches; limit zero uses the server
+// default.
+func (c *Client) SearchThoughts(query string, limit int) ([]SearchResult, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	resp, err := c.doRequest("GET", "/api/thoughts/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to search thoughts: %w", decodeError(resp))
+	}
+
+	var searchResp struct {
+		Results []SearchResult `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return searchResp.Results, nil
+}
+
+// TagCount is a tag with the number of thoughts carrying it
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// ListTags retrieves the user's tags with thought counts, most used first
+func (c *Client) ListTags() ([]TagCount, error) {
+	resp, err := c.doRequest("GET", "/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list tags: %w", decodeError(resp))
+	}
+
+	var tagsResp struct {
+		Tags []TagCount `json:"tags"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tagsResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return tagsResp.Tags, nil
+}
+
 // doRequest is a helper method to make HTTP requests
 func (c *Client) doRequest(method, path string, body interface{}) (*http.Response, error) {
 	var reqBody io.Reader = nil
@@ -115,15 +653,30 @@ func (c *Client) doRequest(method, path string, body interface{}) (*http.Respons
 		reqBody = bytes.NewBuffer(jsonData)
 	}
 
-	req, err := http.NewRequest(method, c.BaseURL+path, reqBody)
+	req, err := http.NewRequestWithContext(c.context(), method, c.BaseURL+path, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	return c.do(req)
+}
+
+// do sends a prepared request with the access token and trace context
+// attached
+func (c *Client) do(req *http.Request) (*http.Response, error) {
 	if c.Token != "" {
 		req.Header.Set("Authorization", "Bearer "+c.Token)
 	}
+	otel.GetTextMapPropagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
 
 	return c.HTTPClient.Do(req)
 }
+
+// context returns the context requests are made with
+func (c *Client) context() context.Context {
+	if c.Context == nil {
+		return context.Background()
+	}
+	return c.Context
+}
@@ -0,0 +1,305 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/auth"
+	"github.com/yourusername/backend/internal/client"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/testutils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// setupTestClient starts the API on a random local port and returns a client
+// logged in as a freshly created user
+func setupTestClient(t *testing.T) (*client.Client, *models.User) {
+	t.Helper()
+
+	db := testutils.SetupTestDB(t)
+	app := testutils.SetupTestApp(t, db)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go app.Listener(ln)
+	t.Cleanup(func() { app.Shutdown() })
+
+	user := models.User{Email: "client@example.com", Password: "password123"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	c := client.NewClient("http://" + ln.Addr().String())
+	if err := c.Login("client@example.com", "password123"); err != nil {
+		t.Fatalf("Failed to login: %v", err)
+	}
+
+	return c, &user
+}
+
+func TestThoughtCRUD(t *testing.T) {
+	c, _ := setupTestClient(t)
+
+	created, err := c.CreateThought("First draft")
+	assert.NoError(t, err)
+
+	fetched, err := c.GetThought(created.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "First draft", fetched.Content)
+
+	updated, err := c.UpdateThought(created.ID, "Second draft")
+	assert.NoError(t, err)
+	assert.Equal(t, "Second draft", updated.Content)
+
+	assert.NoError(t, c.DeleteThought(created.ID))
+
+	_, err = c.GetThought(created.ID)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+}
+
+func TestIterateThoughts(t *testing.T) {
+	c, _ := setupTestClient(t)
+
+	for i := 0; i < 7; i++ {
+		if _, err := c.CreateThought(fmt.Sprintf("Thought %d", i)); err != nil {
+			t.Fatalf("Failed to create thought: %v", err)
+		}
+	}
+
+	var contents []string
+	it := c.IterateThoughts(3)
+	for it.Next() {
+		contents = append(contents, it.Thought().Content)
+	}
+	assert.NoError(t, it.Err())
+	assert.Len(t, contents, 7)
+	assert.Equal(t, "Thought 6", contents[0])
+	assert.Equal(t, "Thought 0", contents[6])
+
+	all, err := c.GetThoughts()
+	assert.NoError(t, err)
+	assert.Len(t, all, 7)
+}
+
+func TestSearchThoughts(t *testing.T) {
+	c, _ := setupTestClient(t)
+
+	for _, content := range []string{"Morning coffee", "Evening tea", "Coffee with friends"} {
+		if _, err := c.CreateThought(content); err != nil {
+			t.Fatalf("Failed to create thought: %v", err)
+		}
+	}
+
+	results, err := c.SearchThoughts("coffee", 0)
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	for _, r := range results {
+		assert.Contains(t, r.Thought.Content, "offee")
+	}
+}
+
+func TestTags(t *testing.T) {
+	c, _ := setupTestClient(t)
+
+	thought, err := c.CreateThought("Sprint planning #work", "meetings")
+	assert.NoError(t, err)
+	assert.Len(t, thought.Tags, 2)
+
+	_, err = c.CreateThought("Code review #work")
+	assert.NoError(t, err)
+
+	tags, err := c.ListTags()
+	assert.NoError(t, err)
+	assert.Equal(t, []client.TagCount{{Name: "work", Count: 2}, {Name: "meetings", Count: 1}}, tags)
+}
+
+func TestRefreshAndLogout(t *testing.T) {
+	c, _ := setupTestClient(t)
+
+	oldRefresh := c.RefreshToken
+	assert.NoError(t, c.Refresh())
+	assert.NotEqual(t, oldRefresh, c.RefreshToken)
+
+	_, err := c.CreateThought("Still signed in")
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.Logout())
+	assert.Empty(t, c.Token)
+
+	_, err = c.GetThoughts()
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+}
+
+func TestChangePasswordAndEmail(t *testing.T) {
+	c, user := setupTestClient(t)
+
+	assert.ErrorIs(t, c.ChangePassword("wrongpassword", "newpassword"), client.ErrIncorrectPassword)
+	assert.NoError(t, c.ChangePassword("password123", "newpassword"))
+
+	updated, err := c.ChangeEmail("newpassword", "renamed@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, updated.ID)
+	assert.Equal(t, "renamed@example.com", updated.Email)
+	assert.False(t, updated.EmailVerified)
+
+	fresh := client.NewClient(c.BaseURL)
+	assert.ErrorIs(t, fresh.Login("client@example.com", "newpassword"), client.ErrInvalidCredentials)
+	assert.NoError(t, fresh.Login("renamed@example.com", "newpassword"))
+}
+
+func TestTwoFactor(t *testing.T) {
+	c, _ := setupTestClient(t)
+
+	_, err := c.SetupTwoFactor("wrongpassword")
+	assert.ErrorIs(t, err, client.ErrIncorrectPassword)
+	setup, err := c.SetupTwoFactor("password123")
+	assert.NoError(t, err)
+
+	now := time.Now()
+	code, _ := auth.GenerateTOTP(setup.Secret, now)
+	recoveryCodes, err := c.ConfirmTwoFactor(code)
+	assert.NoError(t, err)
+	assert.Len(t, recoveryCodes, 10)
+
+	fresh := client.NewClient(c.BaseURL)
+	assert.ErrorIs(t, fresh.Login("client@example.com", "password123"), client.ErrMFARequired)
+	assert.Empty(t, fresh.Token)
+	assert.ErrorIs(t, fresh.LoginTwoFactor(code), client.ErrInvalidCode)
+	assert.NoError(t, fresh.LoginTwoFactor(recoveryCodes[0]))
+	assert.Empty(t, fresh.MFAToken)
+	_, err = fresh.GetThoughts()
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, c.DisableTwoFactor("password123", recoveryCodes[0]), client.ErrInvalidCode)
+	next, _ := auth.GenerateTOTP(setup.Secret, now.Add(auth.TOTPPeriod))
+	assert.NoError(t, c.DisableTwoFactor("password123", next))
+	assert.NoError(t, fresh.Login("client@example.com", "password123"))
+}
+
+func TestDeleteAccount(t *testing.T) {
+	c, _ := setupTestClient(t)
+
+	_, err := c.CreateThought("Soon forgotten")
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, c.DeleteAccount("wrongpassword"), client.ErrIncorrectPassword)
+	assert.NoError(t, c.DeleteAccount("password123"))
+	assert.Empty(t, c.Token)
+
+	assert.Error(t, c.Login("client@example.com", "password123"))
+}
+
+func TestExport(t *testing.T) {
+	c, user := setupTestClient(t)
+
+	_, err := c.CreateThought("Take me with you #export")
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, c.Export("json", &buf))
+
+	var data struct {
+		User struct {
+			ID uint `json:"id"`
+		} `json:"user"`
+		Thoughts []struct {
+			Content string   `json:"content"`
+			Tags    []string `json:"tags"`
+		} `json:"thoughts"`
+	}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &data))
+	assert.Equal(t, user.ID, data.User.ID)
+	if assert.Len(t, data.Thoughts, 1) {
+		assert.Equal(t, "Take me with you #export", data.Thoughts[0].Content)
+		assert.Equal(t, []string{"export"}, data.Thoughts[0].Tags)
+	}
+
+	assert.Error(t, c.Export("xml", &buf))
+}
+
+func TestImportThoughts(t *testing.T) {
+	c, _ := setupTestClient(t)
+
+	csv := "content,created_at,tags\nImported,2020-01-02T03:04:05Z,old\n,,\n"
+	report, err := c.ImportThoughts("notes.csv", strings.NewReader(csv))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Imported)
+	assert.Equal(t, 1, report.Rejected)
+	assert.Equal(t, "Content is required", report.Results[1].Error)
+
+	thought, err := c.GetThought(report.Results[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Imported", thought.Content)
+	assert.Equal(t, 2020, thought.CreatedAt.Year())
+}
+
+func TestTraceContextPropagation(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	c := client.NewClient(server.URL)
+	c.Context = trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	assert.NoError(t, c.Logout())
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", traceparent)
+}
+
+func TestTypedErrors(t *testing.T) {
+	c, _ := setupTestClient(t)
+
+	_, err := c.CreateThought("")
+	var apiErr *client.Error
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.ErrorIs(t, err, client.ErrValidationFailed)
+		assert.Equal(t, http.StatusBadRequest, apiErr.Status)
+		assert.Equal(t, "Content is required", apiErr.Detail)
+		assert.NotEmpty(t, apiErr.RequestID)
+		if assert.Len(t, apiErr.Fields, 1) {
+			assert.Equal(t, "content", apiErr.Fields[0].Field)
+		}
+	}
+
+	c.Token = "not-a-token"
+	_, err = c.ListTags()
+	assert.ErrorIs(t, err, client.ErrInvalidToken)
+}
+
+func TestErrorWithoutProblemBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream unavailable", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	err := client.NewClient(server.URL).Logout()
+	var apiErr *client.Error
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.ErrorIs(t, err, client.ErrInternal)
+		assert.Equal(t, http.StatusBadGateway, apiErr.Status)
+		assert.Equal(t, "Bad Gateway", apiErr.Detail)
+	}
+}
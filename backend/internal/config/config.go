@@ -0,0 +1,652 @@
+// Package config loads the server settings. Values come from, in increasing
+// order of precedence: built-in defaults, a YAML or TOML config file,
+// environment variables and command-line flags.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the complete server configuration
+type Config struct {
+	Server    Server    `yaml:"server" toml:"server"`
+	Auth      Auth      `yaml:"auth" toml:"auth"`
+	Database  Database  `yaml:"database" toml:"database"`
+	Mail      Mail      `yaml:"mail" toml:"mail"`
+	Tracing   Tracing   `yaml:"tracing" toml:"tracing"`
+	Logging   Logging   `yaml:"logging" toml:"logging"`
+	RateLimit RateLimit `yaml:"rate_limit" toml:"rate_limit"`
+}
+
+// Server configures the HTTP listener and the URLs the API is reached at
+type Server struct {
+	Port string `yaml:"port" toml:"port"`
+	// PublicURL is the public URL of the API, used to build links in emails.
+	// It defaults to http://localhost:<port>.
+	PublicURL string `yaml:"public_url" toml:"public_url"`
+	// AppURL is the public URL of the frontend
+	AppURL string `yaml:"app_url" toml:"app_url"`
+	// CORSOrigins are the browser origins allowed to call the API
+	CORSOrigins []string `yaml:"cors_origins" toml:"cors_origins"`
+	// ShutdownTimeout bounds how long in-flight requests may take to finish
+	// on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// DrainDelay keeps serving after readiness fails on shutdown, giving load
+	// balancers time to stop sending requests
+	DrainDelay time.Duration `yaml:"drain_delay" toml:"drain_delay"`
+	// MetricsAddr is the host:port of a separate listener serving /metrics,
+	// such as 127.0.0.1:9090. Empty turns metrics off. Keep it off the public
+	// network, since the endpoint needs no authentication.
+	MetricsAddr string `yaml:"metrics_addr" toml:"metrics_addr"`
+	// TrustedProxies are the IPs and CIDR ranges of the reverse proxies in
+	// front of the server. Only requests from them may set the client
+	// address through ProxyHeader.
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+	// ProxyHeader carries the client address set by a trusted proxy, such as
+	// X-Real-IP. Empty uses the address of the connection.
+	ProxyHeader string `yaml:"proxy_header" toml:"proxy_header"`
+}
+
+// Auth configures token signing and account policies
+type Auth struct {
+	// SigningKeyFile is a PEM file with the RSA or Ed25519 private key that
+	// signs access tokens. It is required unless AllowGeneratedKey is set.
+	SigningKeyFile string `yaml:"signing_key_file" toml:"signing_key_file"`
+	// AllowGeneratedKey lets the server generate a signing key at startup
+	// when SigningKeyFile is unset. Tokens then do not survive a restart and
+	// instances do not accept each other's tokens, so it is for development
+	// only.
+	AllowGeneratedKey bool `yaml:"allow_generated_key" toml:"allow_generated_key"`
+	// VerificationKeyFiles are PEM files with keys that no longer sign but
+	// whose tokens are still accepted, such as the key before a rotation
+	VerificationKeyFiles []string `yaml:"verification_key_files" toml:"verification_key_files"`
+	// Issuer is the iss claim of access tokens. It defaults to the public
+	// URL.
+	Issuer string `yaml:"issuer" toml:"issuer"`
+	// Audience is the aud claim of access tokens
+	Audience string `yaml:"audience" toml:"audience"`
+
+	RequireEmailVerification   bool          `yaml:"require_email_verification" toml:"require_email_verification"`
+	AccountDeletionGracePeriod time.Duration `yaml:"account_deletion_grace_period" toml:"account_deletion_grace_period"`
+
+	// OIDCProviders are the OpenID Connect providers users can log in with
+	OIDCProviders []OIDCProvider `yaml:"oidc_providers" toml:"oidc_providers"`
+}
+
+// OIDCProvider registers this application with an OpenID Connect provider.
+// The client secret can be left out of the file and set in
+// OIDC_<NAME>_CLIENT_SECRET instead, with the name upper-cased and dashes
+// turned into underscores.
+type OIDCProvider struct {
+	// Name identifies the provider in URLs and linked identities, so it
+	// must not change once users have logged in with it
+	Name         string `yaml:"name" toml:"name"`
+	DisplayName  string `yaml:"display_name" toml:"display_name"`
+	Issuer       string `yaml:"issuer" toml:"issuer"`
+	ClientID     string `yaml:"client_id" toml:"client_id"`
+	ClientSecret string `yaml:"client_secret" toml:"client_secret"`
+	// Scopes are requested besides openid. They default to email and
+	// profile.
+	Scopes []string `yaml:"scopes" toml:"scopes"`
+}
+
+// SecretEnvVar is the environment variable holding the client secret
+func (p OIDCProvider) SecretEnvVar() string {
+	return "OIDC_" + strings.ToUpper(strings.ReplaceAll(p.Name, "-", "_")) + "_CLIENT_SECRET"
+}
+
+// Database selects the database and tunes its connection pool. Zero pool
+// values keep the defaults of the dialect.
+type Database struct {
+	URL             string        `yaml:"url" toml:"url"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	// MinFreeDiskMB is the free space the SQLite file system needs for the
+	// server to report itself ready
+	MinFreeDiskMB int `yaml:"min_free_disk_mb" toml:"min_free_disk_mb"`
+}
+
+// Mail selects how account emails are delivered
+type Mail struct {
+	// Driver is log, file or smtp
+	Driver       string `yaml:"driver" toml:"driver"`
+	From         string `yaml:"from" toml:"from"`
+	DropDir      string `yaml:"drop_dir" toml:"drop_dir"`
+	SMTPHost     string `yaml:"smtp_host" toml:"smtp_host"`
+	SMTPPort     string `yaml:"smtp_port" toml:"smtp_port"`
+	SMTPUsername string `yaml:"smtp_username" toml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password" toml:"smtp_password"`
+}
+
+// Tracing selects where OpenTelemetry spans are exported
+type Tracing struct {
+	// Exporter is none, otlp, stdout or file
+	Exporter string `yaml:"exporter" toml:"exporter"`
+	// OTLPEndpoint is the OTLP/HTTP collector URL, such as
+	// http://localhost:4318. Empty leaves it to the standard OTEL_EXPORTER_OTLP_*
+	// variables.
+	OTLPEndpoint string `yaml:"otlp_endpoint" toml:"otlp_endpoint"`
+	// File receives one JSON span per line with the file exporter
+	File        string  `yaml:"file" toml:"file"`
+	ServiceName string  `yaml:"service_name" toml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+// Logging selects the log format and the least severe level written
+type Logging struct {
+	// Level is debug, info, warn or error
+	Level string `yaml:"level" toml:"level"`
+	// Format is json or text
+	Format string `yaml:"format" toml:"format"`
+}
+
+// RateLimit throttles each route group and locks accounts after repeated
+// failed logins
+type RateLimit struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// Store keeps the buckets and failure counts; only memory so far
+	Store string `yaml:"store" toml:"store"`
+	// Auth limits the authentication endpoints per client IP
+	Auth Limit `yaml:"auth" toml:"auth"`
+	// AuthEmail limits logins, registrations and password reset requests
+	// per email address
+	AuthEmail Limit `yaml:"auth_email" toml:"auth_email"`
+	// API limits authenticated requests per user
+	API Limit `yaml:"api" toml:"api"`
+	// Writes limits thought creation and imports per user
+	Writes  Limit   `yaml:"writes" toml:"writes"`
+	Lockout Lockout `yaml:"lockout" toml:"lockout"`
+}
+
+// Limit allows Requests per Window, in bursts of up to Requests. Zero
+// Requests disables the limit.
+type Limit struct {
+	Requests int           `yaml:"requests" toml:"requests"`
+	Window   time.Duration `yaml:"window" toml:"window"`
+}
+
+// Lockout locks an account for Duration after Threshold failed logins,
+// doubling with each further failure up to MaxDuration. Zero Threshold
+// disables the lockout.
+type Lockout struct {
+	Threshold   int           `yaml:"threshold" toml:"threshold"`
+	Duration    time.Duration `yaml:"duration" toml:"duration"`
+	MaxDuration time.Duration `yaml:"max_duration" toml:"max_duration"`
+}
+
+// Default returns the configuration used for anything left unset
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Port:            "8080",
+			AppURL:          "http://localhost:3000",
+			CORSOrigins:     []string{"http://localhost:3000", "http://localhost:3001"},
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Auth: Auth{
+			Audience: "thoughts",
+		},
+		Database: Database{
+			URL:           "sqlite://thoughts.db",
+			MinFreeDiskMB: 100,
+		},
+		Mail: Mail{
+			Driver:   "log",
+			From:     "Thoughts <no-reply@localhost>",
+			DropDir:  "mail",
+			SMTPPort: "587",
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			File:        "traces.jsonl",
+			ServiceName: "thoughts-backend",
+			SampleRatio: 1,
+		},
+		Logging: Logging{
+			Level:  "info",
+			Format: "json",
+		},
+		RateLimit: RateLimit{
+			Enabled:   true,
+			Store:     "memory",
+			Auth:      Limit{Requests: 20, Window: time.Minute},
+			AuthEmail: Limit{Requests: 10, Window: 15 * time.Minute},
+			API:       Limit{Requests: 300, Window: time.Minute},
+			Writes:    Limit{Requests: 30, Window: time.Minute},
+			Lockout:   Lockout{Threshold: 5, Duration: time.Minute, MaxDuration: time.Hour},
+		},
+	}
+}
+
+// Load builds the configuration from a config file, the environment and
+// command-line flags. The file is named by the --config flag or CONFIG_FILE;
+// its format follows the extension (.yaml, .yml or .toml). Load does not
+// validate the result, see Validate.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("backend", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	flags := &Config{}
+	fs.StringVar(&flags.Server.Port, "port", "", "port to listen on")
+	fs.StringVar(&flags.Server.PublicURL, "public-url", "", "public URL of the API")
+	fs.StringVar(&flags.Server.AppURL, "app-url", "", "public URL of the frontend")
+	corsOrigins := fs.String("cors-origins", "", "comma-separated origins allowed by CORS")
+	fs.StringVar(&flags.Database.URL, "database-url", "", "database URL (sqlite://, postgres:// or mysql://)")
+	fs.BoolVar(&flags.Auth.RequireEmailVerification, "require-email-verification", false, "block thought creation until the email is verified")
+	fs.DurationVar(&flags.Auth.AccountDeletionGracePeriod, "account-deletion-grace-period", 0, "how long deleted accounts can be restored")
+	fs.StringVar(&flags.Mail.Driver, "mail-driver", "", "mail driver: log, file or smtp")
+	fs.StringVar(&flags.Tracing.Exporter, "tracing-exporter", "", "trace exporter: none, otlp, stdout or file")
+	fs.StringVar(&flags.Logging.Level, "log-level", "", "log level: debug, info, warn or error")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(cfg, *configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := loadEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	// Only flags given on the command line override the other sources
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.Server.Port = flags.Server.Port
+		case "public-url":
+			cfg.Server.PublicURL = flags.Server.PublicURL
+		case "app-url":
+			cfg.Server.AppURL = flags.Server.AppURL
+		case "cors-origins":
+			cfg.Server.CORSOrigins = splitList(*corsOrigins)
+		case "database-url":
+			cfg.Database.URL = flags.Database.URL
+		case "require-email-verification":
+			cfg.Auth.RequireEmailVerification = flags.Auth.RequireEmailVerification
+		case "account-deletion-grace-period":
+			cfg.Auth.AccountDeletionGracePeriod = flags.Auth.AccountDeletionGracePeriod
+		case "mail-driver":
+			cfg.Mail.Driver = flags.Mail.Driver
+		case "tracing-exporter":
+			cfg.Tracing.Exporter = flags.Tracing.Exporter
+		case "log-level":
+			cfg.Logging.Level = flags.Logging.Level
+		}
+	})
+
+	if cfg.Server.PublicURL == "" {
+		cfg.Server.PublicURL = "http://localhost:" + cfg.Server.Port
+	}
+	cfg.Server.PublicURL = strings.TrimSuffix(cfg.Server.PublicURL, "/")
+	cfg.Server.AppURL = strings.TrimSuffix(cfg.Server.AppURL, "/")
+	if cfg.Auth.Issuer == "" {
+		cfg.Auth.Issuer = cfg.Server.PublicURL
+	}
+	for i := range cfg.Auth.OIDCProviders {
+		p := &cfg.Auth.OIDCProviders[i]
+		if p.DisplayName == "" {
+			p.DisplayName = p.Name
+		}
+		if p.Scopes == nil {
+			p.Scopes = []string{"email", "profile"}
+		}
+	}
+
+	return cfg, nil
+}
+
+// loadFile reads a YAML or TOML file over cfg. Unknown keys are rejected so
+// a misspelt setting does not silently fall back to its default.
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("parsing config file %s: unknown setting %q", path, undecoded[0].String())
+		}
+	default:
+		return fmt.Errorf("config file %s must end in .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// envVar applies one environment variable to the configuration
+type envVar struct {
+	name  string
+	apply func(cfg *Config, value string) error
+}
+
+// envVars are the environment variables read by loadEnv. DATABASE_URL comes
+// after DB_PATH so it wins when both are set.
+var envVars = []envVar{
+	{"PORT", setString(func(c *Config) *string { return &c.Server.Port })},
+	{"PUBLIC_URL", setString(func(c *Config) *string { return &c.Server.PublicURL })},
+	{"APP_URL", setString(func(c *Config) *string { return &c.Server.AppURL })},
+	{"CORS_ORIGINS", func(c *Config, v string) error { c.Server.CORSOrigins = splitList(v); return nil }},
+	{"SHUTDOWN_TIMEOUT", setDuration(func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout })},
+	{"SHUTDOWN_DRAIN_DELAY", setDuration(func(c *Config) *time.Duration { return &c.Server.DrainDelay })},
+	{"METRICS_ADDR", setString(func(c *Config) *string { return &c.Server.MetricsAddr })},
+	{"TRUSTED_PROXIES", func(c *Config, v string) error { c.Server.TrustedProxies = splitList(v); return nil }},
+	{"PROXY_HEADER", setString(func(c *Config) *string { return &c.Server.ProxyHeader })},
+	{"JWT_SIGNING_KEY_FILE", setString(func(c *Config) *string { return &c.Auth.SigningKeyFile })},
+	{"JWT_ALLOW_GENERATED_KEY", setBool(func(c *Config) *bool { return &c.Auth.AllowGeneratedKey })},
+	{"JWT_VERIFICATION_KEY_FILES", func(c *Config, v string) error { c.Auth.VerificationKeyFiles = splitList(v); return nil }},
+	{"JWT_ISSUER", setString(func(c *Config) *string { return &c.Auth.Issuer })},
+	{"JWT_AUDIENCE", setString(func(c *Config) *string { return &c.Auth.Audience })},
+	{"REQUIRE_EMAIL_VERIFICATION", setBool(func(c *Config) *bool { return &c.Auth.RequireEmailVerification })},
+	{"ACCOUNT_DELETION_GRACE_PERIOD", setDuration(func(c *Config) *time.Duration { return &c.Auth.AccountDeletionGracePeriod })},
+	{"DB_PATH", func(c *Config, v string) error { c.Database.URL = "sqlite://" + v; return nil }},
+	{"DATABASE_URL", setString(func(c *Config) *string { return &c.Database.URL })},
+	{"DB_MAX_OPEN_CONNS", setInt(func(c *Config) *int { return &c.Database.MaxOpenConns })},
+	{"DB_MAX_IDLE_CONNS", setInt(func(c *Config) *int { return &c.Database.MaxIdleConns })},
+	{"DB_CONN_MAX_LIFETIME", setDuration(func(c *Config) *time.Duration { return &c.Database.ConnMaxLifetime })},
+	{"DB_MIN_FREE_DISK_MB", setInt(func(c *Config) *int { return &c.Database.MinFreeDiskMB })},
+	{"MAIL_DRIVER", setString(func(c *Config) *string { return &c.Mail.Driver })},
+	{"MAIL_FROM", setString(func(c *Config) *string { return &c.Mail.From })},
+	{"MAIL_DROP_DIR", setString(func(c *Config) *string { return &c.Mail.DropDir })},
+	{"SMTP_HOST", setString(func(c *Config) *string { return &c.Mail.SMTPHost })},
+	{"SMTP_PORT", setString(func(c *Config) *string { return &c.Mail.SMTPPort })},
+	{"SMTP_USERNAME", setString(func(c *Config) *string { return &c.Mail.SMTPUsername })},
+	{"SMTP_PASSWORD", setString(func(c *Config) *string { return &c.Mail.SMTPPassword })},
+	{"TRACING_EXPORTER", setString(func(c *Config) *string { return &c.Tracing.Exporter })},
+	{"TRACING_FILE", setString(func(c *Config) *string { return &c.Tracing.File })},
+	{"TRACING_SAMPLE_RATIO", setFloat(func(c *Config) *float64 { return &c.Tracing.SampleRatio })},
+	{"OTEL_EXPORTER_OTLP_ENDPOINT", setString(func(c *Config) *string { return &c.Tracing.OTLPEndpoint })},
+	{"OTEL_SERVICE_NAME", setString(func(c *Config) *string { return &c.Tracing.ServiceName })},
+	{"LOG_LEVEL", setString(func(c *Config) *string { return &c.Logging.Level })},
+	{"LOG_FORMAT", setString(func(c *Config) *string { return &c.Logging.Format })},
+	{"RATE_LIMIT_ENABLED", setBool(func(c *Config) *bool { return &c.RateLimit.Enabled })},
+	{"RATE_LIMIT_STORE", setString(func(c *Config) *string { return &c.RateLimit.Store })},
+	{"LOGIN_LOCKOUT_THRESHOLD", setInt(func(c *Config) *int { return &c.RateLimit.Lockout.Threshold })},
+}
+
+// loadEnv applies every non-empty variable in envVars
+func loadEnv(cfg *Config) error {
+	// Tokens used to be signed with a shared secret. A deployment still
+	// setting it has not been given a key file, so fail loudly rather than
+	// ignore it.
+	if os.Getenv("JWT_SECRET") != "" {
+		return errors.New("JWT_SECRET is no longer supported: tokens are signed with the private key in JWT_SIGNING_KEY_FILE; generate one with `openssl genpkey -algorithm ed25519` and unset JWT_SECRET")
+	}
+	for _, v := range envVars {
+		value := os.Getenv(v.name)
+		if value == "" {
+			continue
+		}
+		if err := v.apply(cfg, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", v.name, err)
+		}
+	}
+	// Providers come from the file, so only their secrets can be set here
+	for i, p := range cfg.Auth.OIDCProviders {
+		if secret := os.Getenv(p.SecretEnvVar()); secret != "" {
+			cfg.Auth.OIDCProviders[i].ClientSecret = secret
+		}
+	}
+	return nil
+}
+
+func setString(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}
+}
+
+func setBool(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		*field(c) = b
+		return err
+	}
+}
+
+func setInt(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		*field(c) = n
+		return err
+	}
+}
+
+func setFloat(field func(*Config) *float64) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		*field(c) = f
+		return err
+	}
+}
+
+func setDuration(field func(*Config) *time.Duration) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		*field(c) = d
+		return err
+	}
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Validate checks the configuration and reports every problem at once
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Auth.SigningKeyFile == "" && !c.Auth.AllowGeneratedKey {
+		errs = append(errs, errors.New("JWT signing key file is required; set JWT_SIGNING_KEY_FILE, or JWT_ALLOW_GENERATED_KEY=true in development"))
+	}
+	if c.Auth.Issuer == "" || c.Auth.Audience == "" {
+		errs = append(errs, errors.New("token issuer and audience are required"))
+	}
+	if c.Auth.AccountDeletionGracePeriod < 0 {
+		errs = append(errs, errors.New("account deletion grace period must not be negative"))
+	}
+	names := make(map[string]bool)
+	for _, p := range c.Auth.OIDCProviders {
+		if !oidcProviderName.MatchString(p.Name) {
+			errs = append(errs, fmt.Errorf("OIDC provider name %q must be lower-case letters, digits and dashes", p.Name))
+		} else if names[p.Name] {
+			errs = append(errs, fmt.Errorf("OIDC provider %q is configured twice", p.Name))
+		}
+		names[p.Name] = true
+		if !isHTTPURL(p.Issuer) {
+			errs = append(errs, fmt.Errorf("OIDC provider %q issuer %q must be an http or https URL", p.Name, p.Issuer))
+		}
+		if p.ClientID == "" {
+			errs = append(errs, fmt.Errorf("OIDC provider %q needs a client ID", p.Name))
+		}
+	}
+
+	if !validPort(c.Server.Port) {
+		errs = append(errs, fmt.Errorf("invalid port %q", c.Server.Port))
+	}
+	for _, u := range []struct{ name, value string }{
+		{"public URL", c.Server.PublicURL},
+		{"app URL", c.Server.AppURL},
+	} {
+		if !isHTTPURL(u.value) {
+			errs = append(errs, fmt.Errorf("%s %q must be an http or https URL", u.name, u.value))
+		}
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown timeout must be positive"))
+	}
+	if c.Server.DrainDelay < 0 {
+		errs = append(errs, errors.New("drain delay must not be negative"))
+	}
+	if c.Server.MetricsAddr != "" {
+		if _, port, err := net.SplitHostPort(c.Server.MetricsAddr); err != nil || !validPort(port) {
+			errs = append(errs, fmt.Errorf("metrics address %q must be host:port", c.Server.MetricsAddr))
+		} else if port == c.Server.Port {
+			errs = append(errs, errors.New("metrics address must not use the API port"))
+		}
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, fmt.Errorf("trusted proxy %q must be an IP address or CIDR range", proxy))
+			}
+		}
+	}
+	if c.Server.ProxyHeader != "" && len(c.Server.TrustedProxies) == 0 {
+		errs = append(errs, errors.New("proxy header needs trusted proxies"))
+	}
+	for _, origin := range c.Server.CORSOrigins {
+		// Credentials are allowed, which browsers refuse for a wildcard origin
+		if !isHTTPURL(origin) {
+			errs = append(errs, fmt.Errorf("CORS origin %q must be an http or https origin", origin))
+		}
+	}
+
+	if err := c.Database.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	switch c.Mail.Driver {
+	case "log", "file":
+	case "smtp":
+		if c.Mail.SMTPHost == "" {
+			errs = append(errs, errors.New("SMTP host is required for the smtp mail driver"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown mail driver %q", c.Mail.Driver))
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "otlp", "stdout":
+	case "file":
+		if c.Tracing.File == "" {
+			errs = append(errs, errors.New("tracing file is required for the file exporter"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown tracing exporter %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.OTLPEndpoint != "" && !isHTTPURL(c.Tracing.OTLPEndpoint) {
+		errs = append(errs, fmt.Errorf("OTLP endpoint %q must be an http or https URL", c.Tracing.OTLPEndpoint))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing sample ratio must be between 0 and 1"))
+	}
+
+	switch strings.ToLower(c.Logging.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("unknown log level %q", c.Logging.Level))
+	}
+	switch strings.ToLower(c.Logging.Format) {
+	case "json", "text":
+	default:
+		errs = append(errs, fmt.Errorf("unknown log format %q", c.Logging.Format))
+	}
+
+	if err := c.RateLimit.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Validate checks the database settings on their own, for commands such as
+// migrate that need nothing else
+func (d Database) Validate() error {
+	var errs []error
+	if d.URL == "" {
+		errs = append(errs, errors.New("database URL is required"))
+	}
+	if d.MaxOpenConns < 0 || d.MaxIdleConns < 0 || d.ConnMaxLifetime < 0 || d.MinFreeDiskMB < 0 {
+		errs = append(errs, errors.New("database pool and disk settings must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the rate limits. Disabled rate limiting is not checked.
+func (r RateLimit) Validate() error {
+	if !r.Enabled {
+		return nil
+	}
+
+	var errs []error
+	if r.Store != "memory" {
+		errs = append(errs, fmt.Errorf("unknown rate limit store %q", r.Store))
+	}
+	for _, l := range []struct {
+		name  string
+		limit Limit
+	}{
+		{"auth", r.Auth},
+		{"auth_email", r.AuthEmail},
+		{"api", r.API},
+		{"writes", r.Writes},
+	} {
+		if l.limit.Requests < 0 || (l.limit.Requests > 0 && l.limit.Window <= 0) {
+			errs = append(errs, fmt.Errorf("%s rate limit needs a positive window and non-negative requests", l.name))
+		}
+	}
+	if r.Lockout.Threshold < 0 {
+		errs = append(errs, errors.New("lockout threshold must not be negative"))
+	}
+	if r.Lockout.Threshold > 0 && (r.Lockout.Duration <= 0 || r.Lockout.MaxDuration < r.Lockout.Duration) {
+		errs = append(errs, errors.New("lockout duration must be positive and no longer than the max duration"))
+	}
+	return errors.Join(errs...)
+}
+
+// oidcProviderName matches names that are safe in URLs and env var names
+var oidcProviderName = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// validPort reports whether s is a TCP port number
+func validPort(s string) bool {
+	port, err := strconv.Atoi(s)
+	return err == nil && port >= 1 && port <= 65535
+}
+
+// isHTTPURL reports whether s is an absolute http or https URL
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
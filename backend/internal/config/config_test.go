@@ -0,0 +1,376 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/config"
+)
+
+// clearEnv unsets every variable Load reads for the duration of the test
+func clearEnv(t *testing.T) {
+	for _, name := range []string{
+		"CONFIG_FILE", "PORT", "PUBLIC_URL", "APP_URL", "CORS_ORIGINS",
+		"SHUTDOWN_TIMEOUT", "SHUTDOWN_DRAIN_DELAY", "METRICS_ADDR", "TRUSTED_PROXIES", "PROXY_HEADER", "JWT_SECRET", "JWT_SIGNING_KEY_FILE", "JWT_ALLOW_GENERATED_KEY", "JWT_VERIFICATION_KEY_FILES",
+		"JWT_ISSUER", "JWT_AUDIENCE", "REQUIRE_EMAIL_VERIFICATION",
+		"ACCOUNT_DELETION_GRACE_PERIOD", "DB_PATH", "DATABASE_URL", "DB_MAX_OPEN_CONNS",
+		"DB_MAX_IDLE_CONNS", "DB_CONN_MAX_LIFETIME", "DB_MIN_FREE_DISK_MB", "MAIL_DRIVER",
+		"MAIL_FROM", "MAIL_DROP_DIR", "SMTP_HOST", "SMTP_PORT", "SMTP_USERNAME", "SMTP_PASSWORD",
+		"TRACING_EXPORTER", "TRACING_FILE", "TRACING_SAMPLE_RATIO", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_SERVICE_NAME",
+		"LOG_LEVEL", "LOG_FORMAT", "RATE_LIMIT_ENABLED", "RATE_LIMIT_STORE", "LOGIN_LOCKOUT_THRESHOLD",
+	} {
+		t.Setenv(name, "")
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	clearEnv(t)
+
+	cfg, err := config.Load(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "8080", cfg.Server.Port)
+	assert.Equal(t, "http://localhost:8080", cfg.Server.PublicURL)
+	assert.Equal(t, []string{"http://localhost:3000", "http://localhost:3001"}, cfg.Server.CORSOrigins)
+	assert.Equal(t, "sqlite://thoughts.db", cfg.Database.URL)
+	assert.Equal(t, "log", cfg.Mail.Driver)
+	assert.Empty(t, cfg.Auth.SigningKeyFile)
+	assert.Empty(t, cfg.Server.MetricsAddr, "metrics are off by default")
+	assert.Equal(t, "http://localhost:8080", cfg.Auth.Issuer)
+	assert.Equal(t, "thoughts", cfg.Auth.Audience)
+	assert.ErrorContains(t, cfg.Validate(), "JWT signing key file is required", "production needs a key file")
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+server:
+  port: "9000"
+  app_url: https://file.example.com/
+  cors_origins: [https://file.example.com]
+auth:
+  signing_key_file: /etc/thoughts/jwt.pem
+  issuer: https://file.example.com
+  account_deletion_grace_period: 720h
+database:
+  url: sqlite://file.db
+`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("PORT", "9100")
+	t.Setenv("CORS_ORIGINS", "https://a.example.com, https://b.example.com")
+	t.Setenv("JWT_VERIFICATION_KEY_FILES", "/etc/thoughts/old.pem,/etc/thoughts/older.pem")
+	t.Setenv("TRUSTED_PROXIES", "127.0.0.1, 172.16.0.0/12")
+	t.Setenv("PROXY_HEADER", "X-Real-IP")
+
+	cfg, err := config.Load([]string{"--port", "9200", "--database-url", "postgres://localhost/thoughts"})
+	assert.NoError(t, err)
+
+	// Flags beat the environment, which beats the file
+	assert.Equal(t, "9200", cfg.Server.Port)
+	assert.Equal(t, "postgres://localhost/thoughts", cfg.Database.URL)
+	assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, cfg.Server.CORSOrigins)
+	assert.Equal(t, "https://file.example.com", cfg.Server.AppURL)
+	assert.Equal(t, "/etc/thoughts/jwt.pem", cfg.Auth.SigningKeyFile)
+	assert.Equal(t, []string{"/etc/thoughts/old.pem", "/etc/thoughts/older.pem"}, cfg.Auth.VerificationKeyFiles)
+	assert.Equal(t, []string{"127.0.0.1", "172.16.0.0/12"}, cfg.Server.TrustedProxies)
+	assert.Equal(t, "X-Real-IP", cfg.Server.ProxyHeader)
+	assert.Equal(t, "https://file.example.com", cfg.Auth.Issuer)
+	assert.Equal(t, 720*time.Hour, cfg.Auth.AccountDeletionGracePeriod)
+	assert.Equal(t, "http://localhost:9200", cfg.Server.PublicURL)
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestLoadTOML(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.toml", `
+[auth]
+signing_key_file = "/etc/thoughts/jwt.pem"
+audience = "thoughts-api"
+require_email_verification = true
+
+[mail]
+driver = "smtp"
+smtp_host = "smtp.example.com"
+
+[rate_limit.writes]
+requests = 5
+window = "10s"
+`)
+
+	cfg, err := config.Load([]string{"--config", path})
+	assert.NoError(t, err)
+	assert.True(t, cfg.Auth.RequireEmailVerification)
+	assert.Equal(t, "thoughts-api", cfg.Auth.Audience)
+	assert.Equal(t, "smtp.example.com", cfg.Mail.SMTPHost)
+	assert.Equal(t, "587", cfg.Mail.SMTPPort)
+	assert.Equal(t, config.Limit{Requests: 5, Window: 10 * time.Second}, cfg.RateLimit.Writes)
+	assert.Equal(t, 300, cfg.RateLimit.API.Requests)
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestLoadOIDCProviders(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+auth:
+  oidc_providers:
+    - name: google
+      display_name: Google
+      issuer: https://accounts.google.com
+      client_id: client-1
+      client_secret: from-file
+    - name: corp-sso
+      issuer: https://sso.example.com
+      client_id: client-2
+      scopes: [email]
+`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("OIDC_CORP_SSO_CLIENT_SECRET", "from-env")
+	t.Setenv("JWT_ALLOW_GENERATED_KEY", "true")
+
+	cfg, err := config.Load(nil)
+	if !assert.NoError(t, err) || !assert.Len(t, cfg.Auth.OIDCProviders, 2) {
+		return
+	}
+	google, corp := cfg.Auth.OIDCProviders[0], cfg.Auth.OIDCProviders[1]
+	assert.Equal(t, "from-file", google.ClientSecret)
+	assert.Equal(t, []string{"email", "profile"}, google.Scopes)
+	assert.Equal(t, "from-env", corp.ClientSecret)
+	assert.Equal(t, "corp-sso", corp.DisplayName)
+	assert.Equal(t, []string{"email"}, corp.Scopes)
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		body string
+		env  map[string]string
+	}{
+		{name: "unknown YAML key", file: "config.yaml", body: "server:\n  prot: 80\n"},
+		{name: "unknown TOML key", file: "config.toml", body: "[server]\nprot = \"80\"\n"},
+		{name: "unsupported extension", file: "config.json", body: "{}"},
+		{name: "invalid duration", env: map[string]string{"ACCOUNT_DELETION_GRACE_PERIOD": "a month"}},
+		{name: "invalid bool", env: map[string]string{"REQUIRE_EMAIL_VERIFICATION": "maybe"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			if tt.file != "" {
+				t.Setenv("CONFIG_FILE", writeFile(t, tt.file, tt.body))
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			_, err := config.Load(nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadRejectsJWTSecret(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("JWT_SIGNING_KEY_FILE", "/etc/thoughts/jwt.pem")
+	t.Setenv("JWT_SECRET", "left-over-from-hs256")
+
+	_, err := config.Load(nil)
+	assert.ErrorContains(t, err, "JWT_SECRET is no longer supported")
+}
+
+func TestDBPathFallback(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DB_PATH", "/data/thoughts.db")
+
+	cfg, err := config.Load(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "sqlite:///data/thoughts.db", cfg.Database.URL)
+
+	// DATABASE_URL wins over DB_PATH
+	t.Setenv("DATABASE_URL", "mysql://localhost/thoughts")
+	cfg, err = config.Load(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "mysql://localhost/thoughts", cfg.Database.URL)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(cfg *config.Config)
+		expectedErr string
+	}{
+		{name: "valid", modify: func(cfg *config.Config) {}},
+		{
+			name:        "no signing key",
+			modify:      func(cfg *config.Config) { cfg.Auth.SigningKeyFile = "" },
+			expectedErr: "JWT signing key file is required",
+		},
+		{
+			name: "generated key in development",
+			modify: func(cfg *config.Config) {
+				cfg.Auth.SigningKeyFile = ""
+				cfg.Auth.AllowGeneratedKey = true
+			},
+		},
+		{
+			name:        "no audience",
+			modify:      func(cfg *config.Config) { cfg.Auth.Audience = "" },
+			expectedErr: "token issuer and audience are required",
+		},
+		{
+			name:        "invalid port",
+			modify:      func(cfg *config.Config) { cfg.Server.Port = "http" },
+			expectedErr: "invalid port",
+		},
+		{
+			name:        "no shutdown timeout",
+			modify:      func(cfg *config.Config) { cfg.Server.ShutdownTimeout = 0 },
+			expectedErr: "shutdown timeout",
+		},
+		{
+			name:   "metrics address",
+			modify: func(cfg *config.Config) { cfg.Server.MetricsAddr = "127.0.0.1:9090" },
+		},
+		{
+			name:        "metrics address without port",
+			modify:      func(cfg *config.Config) { cfg.Server.MetricsAddr = "127.0.0.1" },
+			expectedErr: "metrics address",
+		},
+		{
+			name:        "metrics on the API port",
+			modify:      func(cfg *config.Config) { cfg.Server.MetricsAddr = ":8080" },
+			expectedErr: "must not use the API port",
+		},
+		{
+			name: "trusted proxies",
+			modify: func(cfg *config.Config) {
+				cfg.Server.TrustedProxies = []string{"10.0.0.1", "172.16.0.0/12", "::1"}
+				cfg.Server.ProxyHeader = "X-Real-IP"
+			},
+		},
+		{
+			name:        "invalid trusted proxy",
+			modify:      func(cfg *config.Config) { cfg.Server.TrustedProxies = []string{"nginx"} },
+			expectedErr: "must be an IP address or CIDR range",
+		},
+		{
+			name:        "proxy header without trusted proxies",
+			modify:      func(cfg *config.Config) { cfg.Server.ProxyHeader = "X-Forwarded-For" },
+			expectedErr: "proxy header needs trusted proxies",
+		},
+		{
+			name:        "wildcard origin",
+			modify:      func(cfg *config.Config) { cfg.Server.CORSOrigins = []string{"*"} },
+			expectedErr: "CORS origin",
+		},
+		{
+			name:        "smtp without host",
+			modify:      func(cfg *config.Config) { cfg.Mail.Driver = "smtp" },
+			expectedErr: "SMTP host is required",
+		},
+		{
+			name:        "negative pool",
+			modify:      func(cfg *config.Config) { cfg.Database.MaxOpenConns = -1 },
+			expectedErr: "must not be negative",
+		},
+		{
+			name:        "unknown tracing exporter",
+			modify:      func(cfg *config.Config) { cfg.Tracing.Exporter = "jaeger" },
+			expectedErr: "unknown tracing exporter",
+		},
+		{
+			name:        "sample ratio out of range",
+			modify:      func(cfg *config.Config) { cfg.Tracing.SampleRatio = 2 },
+			expectedErr: "sample ratio must be between 0 and 1",
+		},
+		{
+			name:        "unknown log format",
+			modify:      func(cfg *config.Config) { cfg.Logging.Format = "logfmt" },
+			expectedErr: "unknown log format",
+		},
+		{
+			name:        "rate limit without window",
+			modify:      func(cfg *config.Config) { cfg.RateLimit.API.Window = 0 },
+			expectedErr: "api rate limit needs a positive window",
+		},
+		{
+			name:        "lockout max below duration",
+			modify:      func(cfg *config.Config) { cfg.RateLimit.Lockout.MaxDuration = time.Second },
+			expectedErr: "lockout duration must be positive",
+		},
+		{
+			name: "invalid OIDC provider name",
+			modify: func(cfg *config.Config) {
+				cfg.Auth.OIDCProviders = []config.OIDCProvider{{Name: "Google", Issuer: "https://accounts.google.com", ClientID: "id"}}
+			},
+			expectedErr: "lower-case letters, digits and dashes",
+		},
+		{
+			name: "duplicate OIDC provider",
+			modify: func(cfg *config.Config) {
+				p := config.OIDCProvider{Name: "google", Issuer: "https://accounts.google.com", ClientID: "id"}
+				cfg.Auth.OIDCProviders = []config.OIDCProvider{p, p}
+			},
+			expectedErr: "configured twice",
+		},
+		{
+			name: "OIDC provider without issuer",
+			modify: func(cfg *config.Config) {
+				cfg.Auth.OIDCProviders = []config.OIDCProvider{{Name: "google", ClientID: "id"}}
+			},
+			expectedErr: "must be an http or https URL",
+		},
+		{
+			name: "OIDC provider without client ID",
+			modify: func(cfg *config.Config) {
+				cfg.Auth.OIDCProviders = []config.OIDCProvider{{Name: "google", Issuer: "https://accounts.google.com"}}
+			},
+			expectedErr: "needs a client ID",
+		},
+		{
+			name: "disabled rate limits are not checked",
+			modify: func(cfg *config.Config) {
+				cfg.RateLimit.Enabled = false
+				cfg.RateLimit.Store = "redis"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Server.PublicURL = "http://localhost:8080"
+			cfg.Auth.Issuer = cfg.Server.PublicURL
+			cfg.Auth.SigningKeyFile = "/etc/thoughts/jwt.pem"
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	cfg := config.Default()
+	cfg.Server.Port = "0"
+
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "token issuer and audience are required")
+	assert.ErrorContains(t, err, "invalid port")
+	assert.ErrorContains(t, err, "public URL")
+}
@@ -0,0 +1,68 @@
+package database_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/config"
+	"github.com/yourusername/backend/internal/database"
+)
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name        string
+		url         string
+		expectedErr bool
+		file        string
+	}{
+		{name: "sqlite url", url: "sqlite://" + filepath.Join(dir, "a.db"), file: "a.db"},
+		{name: "sqlite in new directory", url: "sqlite:" + filepath.Join(dir, "data", "b.db"), file: filepath.Join("data", "b.db")},
+		{name: "sqlite without path", url: "sqlite://", expectedErr: true},
+		{name: "unsupported scheme", url: "mongodb://localhost/thoughts", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := database.Open(tt.url)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer func() {
+				sqlDB, _ := db.DB()
+				sqlDB.Close()
+			}()
+
+			assert.Equal(t, "sqlite", db.Dialector.Name())
+			assert.NoError(t, database.Migrate(db))
+			_, err = os.Stat(filepath.Join(dir, tt.file))
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestConnectAppliesPoolSettings(t *testing.T) {
+	dir := t.TempDir()
+
+	db, err := database.Connect(config.Database{
+		URL:          "sqlite://" + filepath.Join(dir, "url.db"),
+		MaxOpenConns: 3,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+
+	assert.NoError(t, sqlDB.Ping())
+	assert.Equal(t, 3, sqlDB.Stats().MaxOpenConnections)
+	_, err = os.Stat(filepath.Join(dir, "url.db"))
+	assert.NoError(t, err)
+}
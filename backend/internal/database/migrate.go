@@ -0,0 +1,438 @@
+package database
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SchemaMigrationsTable records which migrations have been applied
+const SchemaMigrationsTable = "schema_migrations"
+
+// migrationLockName identifies the lock held while migrating. The Postgres
+// advisory lock key is derived from the same name.
+const migrationLockName = "thoughts_schema_migrations"
+
+// migrationLockTimeout is how long MySQL waits for another replica to finish
+// migrating
+const migrationLockTimeout = 5 * time.Minute
+
+// migrationFiles holds the SQL migrations, one directory per dialect. Files
+// are named NNNN_name.up.sql and NNNN_name.down.sql; each statement ends with
+// a semicolon at the end of a line, and a CREATE TRIGGER runs to its END;
+// line. An up file starting with "-- requires: <feature>" only runs where
+// the database supports that feature.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var migrationRequiresPattern = regexp.MustCompile(`^-- requires: (\w+)`)
+
+// migrationRequirements reports whether a database supports an optional
+// feature a migration requires
+var migrationRequirements = map[string]func(*gorm.DB) bool{
+	"fts5": fts5Available,
+}
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+	// Requires names a feature the database must support, if any
+	Requires string
+}
+
+// MigrationState is a migration known to the code or the database
+type MigrationState struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	// Dirty is set when the applied migration no longer matches its file
+	Dirty bool
+	// Missing is set when the database has a migration the code doesn't know
+	Missing bool
+	// Unsupported is set when the database lacks a feature the migration
+	// requires, so it is skipped rather than pending
+	Unsupported bool
+}
+
+// schemaMigration is a row of the schema_migrations table
+type schemaMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Migrations returns the migrations for the database's dialect, oldest first
+func Migrations(db *gorm.DB) ([]Migration, error) {
+	return loadMigrations(db.Dialector.Name())
+}
+
+func loadMigrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %q", dialect)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		data, err := migrationFiles.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files with different names", version)
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+			sum := sha256.Sum256(data)
+			m.Checksum = hex.EncodeToString(sum[:])
+			if requires := migrationRequiresPattern.FindStringSubmatch(m.Up); requires != nil {
+				if _, ok := migrationRequirements[requires[1]]; !ok {
+					return nil, fmt.Errorf("migration %d requires unknown feature %q", version, requires[1])
+				}
+				m.Requires = requires[1]
+			}
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up file", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// MigrateUp applies every pending migration and returns how many ran
+func MigrateUp(db *gorm.DB) (int, error) {
+	migrations, err := Migrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	err = withMigrationLock(db, func(conn *gorm.DB, inTx func(func(*gorm.DB) error) error) error {
+		if err := adoptLegacySchema(conn, migrations, inTx); err != nil {
+			return err
+		}
+
+		done, err := appliedMigrations(conn, migrations)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if _, ok := done[m.Version]; ok || !supports(conn, m) {
+				continue
+			}
+			err := inTx(func(tx *gorm.DB) error {
+				if err := execStatements(tx, m.Up); err != nil {
+					return err
+				}
+				return recordMigration(tx, m)
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown rolls back the given number of most recently applied
+// migrations and returns how many were rolled back
+func MigrateDown(db *gorm.DB, steps int) (int, error) {
+	migrations, err := Migrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	rolledBack := 0
+	err = withMigrationLock(db, func(conn *gorm.DB, inTx func(func(*gorm.DB) error) error) error {
+		done, err := appliedMigrations(conn, migrations)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && rolledBack < steps; i-- {
+			m := migrations[i]
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+			if m.Down == "" {
+				return fmt.Errorf("migration %d_%s cannot be rolled back", m.Version, m.Name)
+			}
+			if !supports(conn, m) {
+				return fmt.Errorf("migration %d_%s requires %s to be rolled back", m.Version, m.Name, m.Requires)
+			}
+			err := inTx(func(tx *gorm.DB) error {
+				if err := execStatements(tx, m.Down); err != nil {
+					return err
+				}
+				return tx.Table(SchemaMigrationsTable).Where("version = ?", m.Version).Delete(&schemaMigration{}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("rollback of %d_%s failed: %w", m.Version, m.Name, err)
+			}
+			rolledBack++
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// MigrationStatus lists every migration with whether it has been applied
+func MigrationStatus(db *gorm.DB) ([]MigrationState, error) {
+	migrations, err := Migrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []schemaMigration
+	if db.Migrator().HasTable(SchemaMigrationsTable) {
+		if err := db.Table(SchemaMigrationsTable).Order("version").Find(&rows).Error; err != nil {
+			return nil, err
+		}
+	}
+	applied := make(map[int64]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+
+	known := make(map[int64]bool, len(migrations))
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+		state := MigrationState{Version: m.Version, Name: m.Name, Unsupported: !supports(db, m)}
+		if row, ok := applied[m.Version]; ok {
+			appliedAt := row.AppliedAt
+			state.AppliedAt = &appliedAt
+			state.Dirty = row.Checksum != m.Checksum
+		}
+		states = append(states, state)
+	}
+	for _, row := range rows {
+		if !known[row.Version] {
+			appliedAt := row.AppliedAt
+			states = append(states, MigrationState{Version: row.Version, Name: row.Name, AppliedAt: &appliedAt, Missing: true})
+		}
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Version < states[j].Version
+	})
+	return states, nil
+}
+
+// PendingMigrations returns how many migrations have not been applied yet
+func PendingMigrations(db *gorm.DB) (int, error) {
+	states, err := MigrationStatus(db)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, state := range states {
+		if state.AppliedAt == nil && !state.Unsupported {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// supports reports whether the database has the feature m requires
+func supports(db *gorm.DB, m Migration) bool {
+	return m.Requires == "" || migrationRequirements[m.Requires](db)
+}
+
+// recordMigration marks m as applied
+func recordMigration(tx *gorm.DB, m Migration) error {
+	return tx.Table(SchemaMigrationsTable).Create(&schemaMigration{
+		Version:   m.Version,
+		Name:      m.Name,
+		Checksum:  m.Checksum,
+		AppliedAt: time.Now().UTC(),
+	}).Error
+}
+
+// appliedMigrations reads the applied versions and verifies their checksums
+// so a migration edited after it ran is caught instead of silently skipped
+func appliedMigrations(conn *gorm.DB, migrations []Migration) (map[int64]schemaMigration, error) {
+	var rows []schemaMigration
+	if err := conn.Table(SchemaMigrationsTable).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]Migration, len(migrations))
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+
+	applied := make(map[int64]schemaMigration, len(rows))
+	for _, row := range rows {
+		m, ok := byVersion[row.Version]
+		if !ok {
+			return nil, fmt.Errorf("database has migration %d_%s which this build does not know", row.Version, row.Name)
+		}
+		if row.Checksum != m.Checksum {
+			return nil, fmt.Errorf("migration %d_%s was changed after it was applied (checksum mismatch)", row.Version, row.Name)
+		}
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// withMigrationLock runs fn while holding a lock that keeps concurrent
+// replicas from migrating at the same time. inTx runs one migration
+// atomically: Postgres and MySQL take a lock on a dedicated connection and
+// use a transaction per migration (MySQL commits DDL implicitly, so there a
+// failed migration may be left half applied). SQLite runs the whole batch
+// in one IMMEDIATE transaction, which is both the lock and the transaction.
+func withMigrationLock(db *gorm.DB, fn func(conn *gorm.DB, inTx func(func(*gorm.DB) error) error) error) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		createTable := "CREATE TABLE IF NOT EXISTS " + SchemaMigrationsTable + ` (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)`
+
+		switch db.Dialector.Name() {
+		case "sqlite":
+			// GORM's implicit per-write transactions can't nest inside ours
+			conn = conn.Session(&gorm.Session{SkipDefaultTransaction: true})
+			if err := conn.Exec("BEGIN IMMEDIATE").Error; err != nil {
+				return fmt.Errorf("could not lock database for migration: %w", err)
+			}
+			err := conn.Exec(createTable).Error
+			if err == nil {
+				err = fn(conn, func(step func(*gorm.DB) error) error { return step(conn) })
+			}
+			if err != nil {
+				conn.Exec("ROLLBACK")
+				return err
+			}
+			return conn.Exec("COMMIT").Error
+
+		case "postgres":
+			if err := conn.Exec("SELECT pg_advisory_lock(hashtext(?))", migrationLockName).Error; err != nil {
+				return fmt.Errorf("could not lock database for migration: %w", err)
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(hashtext(?))", migrationLockName)
+
+		case "mysql":
+			var locked int
+			if err := conn.Raw("SELECT GET_LOCK(?, ?)", migrationLockName, int(migrationLockTimeout.Seconds())).Scan(&locked).Error; err != nil {
+				return fmt.Errorf("could not lock database for migration: %w", err)
+			}
+			if locked != 1 {
+				return errors.New("timed out waiting for another migration to finish")
+			}
+			defer conn.Exec("SELECT RELEASE_LOCK(?)", migrationLockName)
+
+		default:
+			return fmt.Errorf("migrations are not supported for %s", db.Dialector.Name())
+		}
+
+		if err := conn.Exec(createTable).Error; err != nil {
+			return err
+		}
+		return fn(conn, func(step func(*gorm.DB) error) error { return conn.Transaction(step) })
+	})
+}
+
+// execStatements runs a migration file statement by statement. Statements
+// end with a semicolon at the end of a line, except that a CREATE TRIGGER
+// body runs to its END; line; -- comment lines are skipped.
+func execStatements(tx *gorm.DB, script string) error {
+	var stmt strings.Builder
+	trigger := false
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		if stmt.Len() == 0 {
+			trigger = strings.HasPrefix(strings.ToUpper(trimmed), "CREATE TRIGGER")
+		}
+		stmt.WriteString(line)
+		stmt.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") && (!trigger || strings.EqualFold(trimmed, "END;")) {
+			if err := tx.Exec(stmt.String()).Error; err != nil {
+				return err
+			}
+			stmt.Reset()
+		}
+	}
+	if strings.TrimSpace(stmt.String()) != "" {
+		return tx.Exec(stmt.String()).Error
+	}
+	return nil
+}
+
+// adoptLegacySchema takes over databases created by GORM AutoMigrate before
+// versioned migrations existed. The baseline migration creates whatever
+// baseline tables and indexes are missing and is recorded as applied; the
+// migrations after it then run as usual.
+func adoptLegacySchema(conn *gorm.DB, migrations []Migration, inTx func(func(*gorm.DB) error) error) error {
+	var recorded int64
+	if err := conn.Table(SchemaMigrationsTable).Count(&recorded).Error; err != nil {
+		return err
+	}
+	if recorded > 0 || !conn.Migrator().HasTable("users") {
+		return nil
+	}
+
+	baseline := migrations[0]
+	if baseline.Version != 1 {
+		return errors.New("no baseline migration to adopt the existing schema with")
+	}
+	err := inTx(func(tx *gorm.DB) error {
+		if err := execStatements(tx, baseline.Up); err != nil {
+			return err
+		}
+		return recordMigration(tx, baseline)
+	})
+	if err != nil {
+		return fmt.Errorf("could not adopt the existing schema: %w", err)
+	}
+	return nil
+}
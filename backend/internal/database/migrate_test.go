@@ -0,0 +1,194 @@
+package database_test
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/database"
+	"github.com/yourusername/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T, path string) *gorm.DB {
+	t.Helper()
+
+	db, err := database.Open("sqlite://" + path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	return db
+}
+
+// supportedMigrations counts the migrations the test database can apply;
+// full-text search needs the sqlite_fts5 build tag
+func supportedMigrations(t *testing.T, db *gorm.DB) int {
+	t.Helper()
+
+	states, err := database.MigrationStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	supported := 0
+	for _, state := range states {
+		if !state.Unsupported {
+			supported++
+		}
+	}
+	return supported
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "migrate.db"))
+
+	migrations, err := database.Migrations(db)
+	if !assert.NoError(t, err) || !assert.NotEmpty(t, migrations) {
+		return
+	}
+	supported := supportedMigrations(t, db)
+
+	pending, err := database.PendingMigrations(db)
+	assert.NoError(t, err)
+	assert.Equal(t, supported, pending)
+
+	applied, err := database.MigrateUp(db)
+	assert.NoError(t, err)
+	assert.Equal(t, supported, applied)
+	for _, table := range []string{"users", "thoughts", "tags", "thought_tags", "sessions", "password_resets", "identities", "two_factors", "recovery_codes"} {
+		assert.True(t, db.Migrator().HasTable(table), table)
+	}
+
+	// The schema works with the models
+	user := models.User{Email: "test@example.com", Password: "password123"}
+	assert.NoError(t, db.Create(&user).Error)
+	assert.NoError(t, db.Create(&models.Thought{Content: "Hello", UserID: user.ID}).Error)
+	assert.NoError(t, db.Create(&models.Identity{UserID: user.ID, Provider: "google", Subject: "1", LastLoginAt: time.Now()}).Error)
+	assert.NoError(t, db.Create(&models.TwoFactor{UserID: user.ID, Secret: "JBSWY3DPEHPK3PXP"}).Error)
+	assert.NoError(t, db.Create(&models.RecoveryCode{UserID: user.ID, CodeHash: "hash"}).Error)
+
+	// Running again is a no-op
+	applied, err = database.MigrateUp(db)
+	assert.NoError(t, err)
+	assert.Zero(t, applied)
+
+	states, err := database.MigrationStatus(db)
+	assert.NoError(t, err)
+	for _, state := range states {
+		assert.Equal(t, !state.Unsupported, state.AppliedAt != nil, state.Name)
+		assert.False(t, state.Dirty)
+		if state.Name == "full_text_search" {
+			assert.Equal(t, !state.Unsupported, database.FullTextSearchEnabled(db))
+		}
+	}
+
+	rolledBack, err := database.MigrateDown(db, len(migrations))
+	assert.NoError(t, err)
+	assert.Equal(t, supported, rolledBack)
+	assert.False(t, db.Migrator().HasTable("users"))
+	assert.False(t, db.Migrator().HasTable(database.ThoughtsFTSTable))
+
+	pending, err = database.PendingMigrations(db)
+	assert.NoError(t, err)
+	assert.Equal(t, supported, pending)
+}
+
+func TestMigrateChecksumMismatch(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "checksum.db"))
+
+	_, err := database.MigrateUp(db)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Simulate a migration file edited after it was applied
+	db.Exec("UPDATE schema_migrations SET checksum = 'changed' WHERE version = 1")
+
+	_, err = database.MigrateUp(db)
+	assert.ErrorContains(t, err, "checksum mismatch")
+	_, err = database.MigrateDown(db, 1)
+	assert.ErrorContains(t, err, "checksum mismatch")
+
+	states, err := database.MigrationStatus(db)
+	assert.NoError(t, err)
+	assert.True(t, states[0].Dirty)
+}
+
+func TestMigrateUnknownVersion(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "unknown.db"))
+
+	_, err := database.MigrateUp(db)
+	if !assert.NoError(t, err) {
+		return
+	}
+	db.Exec("INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (99999, 'from_the_future', 'x', CURRENT_TIMESTAMP)")
+
+	_, err = database.MigrateUp(db)
+	assert.ErrorContains(t, err, "does not know")
+
+	states, err := database.MigrationStatus(db)
+	assert.NoError(t, err)
+	last := states[len(states)-1]
+	assert.Equal(t, int64(99999), last.Version)
+	assert.True(t, last.Missing)
+}
+
+func TestMigrateAdoptsAutoMigratedSchema(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "legacy.db"))
+
+	// A database created by AutoMigrate, missing a baseline table
+	assert.NoError(t, db.AutoMigrate(&models.User{}, &models.Thought{}, &models.Tag{}, &models.Session{}))
+	assert.NoError(t, db.Exec("INSERT INTO users (email, password) VALUES (?, ?)", "old@example.com", "hash").Error)
+
+	assert.NoError(t, database.Migrate(db))
+
+	// The baseline fills in what is missing and later migrations run
+	assert.True(t, db.Migrator().HasTable("password_resets"))
+	assert.True(t, db.Migrator().HasTable("identities"))
+	states, err := database.MigrationStatus(db)
+	assert.NoError(t, err)
+	for _, state := range states {
+		assert.Equal(t, !state.Unsupported, state.AppliedAt != nil, state.Name)
+	}
+	pending, err := database.PendingMigrations(db)
+	assert.NoError(t, err)
+	assert.Zero(t, pending)
+
+	var count int64
+	db.Model(&models.User{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestMigrateConcurrently(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "concurrent.db")
+
+	const replicas = 4
+	dbs := make([]*gorm.DB, replicas)
+	for i := range dbs {
+		dbs[i] = openTestDB(t, path)
+	}
+
+	var wg sync.WaitGroup
+	applied := make([]int, replicas)
+	errs := make([]error, replicas)
+	for i, db := range dbs {
+		wg.Add(1)
+		go func(i int, db *gorm.DB) {
+			defer wg.Done()
+			applied[i], errs[i] = database.MigrateUp(db)
+		}(i, db)
+	}
+	wg.Wait()
+
+	total := 0
+	for i := range dbs {
+		assert.NoError(t, errs[i])
+		total += applied[i]
+	}
+	assert.Equal(t, supportedMigrations(t, dbs[0]), total)
+}
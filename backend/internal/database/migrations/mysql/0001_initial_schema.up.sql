@@ -0,0 +1,66 @@
+CREATE TABLE IF NOT EXISTS users (
+    id bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    created_at datetime(3),
+    updated_at datetime(3),
+    deleted_at datetime(3),
+    email varchar(191) NOT NULL UNIQUE,
+    password longtext NOT NULL,
+    email_verified boolean DEFAULT false,
+    verification_token varchar(255),
+    verification_sent_at datetime(3),
+    deletion_due_at datetime(3),
+    INDEX idx_users_deleted_at (deleted_at),
+    INDEX idx_users_verification_token (verification_token),
+    INDEX idx_users_deletion_due_at (deletion_due_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS thoughts (
+    id bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    content longtext NOT NULL,
+    user_id bigint unsigned NOT NULL,
+    created_at datetime(3),
+    updated_at datetime(3),
+    INDEX idx_thoughts_user_created (user_id, created_at),
+    CONSTRAINT fk_users_thoughts FOREIGN KEY (user_id) REFERENCES users (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS tags (
+    id bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    name varchar(191) NOT NULL,
+    user_id bigint unsigned NOT NULL,
+    created_at datetime(3),
+    UNIQUE INDEX idx_tags_user_name (user_id, name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS thought_tags (
+    thought_id bigint unsigned NOT NULL,
+    tag_id bigint unsigned NOT NULL,
+    PRIMARY KEY (thought_id, tag_id),
+    CONSTRAINT fk_thought_tags_thought FOREIGN KEY (thought_id) REFERENCES thoughts (id),
+    CONSTRAINT fk_thought_tags_tag FOREIGN KEY (tag_id) REFERENCES tags (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    user_id bigint unsigned NOT NULL,
+    family_id varchar(64) NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    expires_at datetime(3) NOT NULL,
+    rotated_at datetime(3),
+    revoked_at datetime(3),
+    created_at datetime(3),
+    INDEX idx_sessions_user_id (user_id),
+    INDEX idx_sessions_family_id (family_id),
+    UNIQUE INDEX idx_sessions_token_hash (token_hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS password_resets (
+    id bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    user_id bigint unsigned NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    expires_at datetime(3) NOT NULL,
+    used_at datetime(3),
+    created_at datetime(3),
+    INDEX idx_password_resets_user_id (user_id),
+    UNIQUE INDEX idx_password_resets_token_hash (token_hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
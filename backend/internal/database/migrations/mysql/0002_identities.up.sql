@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS identities (
+    id bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    user_id bigint unsigned NOT NULL,
+    provider varchar(64) NOT NULL,
+    subject varchar(255) NOT NULL,
+    email varchar(255),
+    last_login_at datetime(3) NOT NULL,
+    created_at datetime(3),
+    INDEX idx_identities_user_id (user_id),
+    UNIQUE INDEX idx_identities_provider_subject (provider, subject)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS two_factors (
+    user_id bigint unsigned PRIMARY KEY,
+    secret varchar(64) NOT NULL,
+    confirmed_at datetime(3),
+    last_step bigint NOT NULL DEFAULT 0,
+    created_at datetime(3)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    user_id bigint unsigned NOT NULL,
+    code_hash varchar(64) NOT NULL,
+    used_at datetime(3),
+    created_at datetime(3),
+    INDEX idx_recovery_codes_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,63 @@
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    email text NOT NULL UNIQUE,
+    password text NOT NULL,
+    email_verified boolean DEFAULT false,
+    verification_token varchar(255),
+    verification_sent_at timestamptz,
+    deletion_due_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_users_verification_token ON users (verification_token);
+CREATE INDEX IF NOT EXISTS idx_users_deletion_due_at ON users (deletion_due_at);
+
+CREATE TABLE IF NOT EXISTS thoughts (
+    id bigserial PRIMARY KEY,
+    content text NOT NULL,
+    user_id bigint NOT NULL REFERENCES users (id),
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_thoughts_user_created ON thoughts (user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    user_id bigint NOT NULL,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags (user_id, name);
+
+CREATE TABLE IF NOT EXISTS thought_tags (
+    thought_id bigint NOT NULL REFERENCES thoughts (id),
+    tag_id bigint NOT NULL REFERENCES tags (id),
+    PRIMARY KEY (thought_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    family_id varchar(64) NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    expires_at timestamptz NOT NULL,
+    rotated_at timestamptz,
+    revoked_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_family_id ON sessions (family_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_token_hash ON sessions (token_hash);
+
+CREATE TABLE IF NOT EXISTS password_resets (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_password_resets_token_hash ON password_resets (token_hash);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS identities (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    provider varchar(64) NOT NULL,
+    subject varchar(255) NOT NULL,
+    email varchar(255),
+    last_login_at timestamptz NOT NULL,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_identities_user_id ON identities (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_identities_provider_subject ON identities (provider, subject);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS two_factors (
+    user_id bigint PRIMARY KEY,
+    secret varchar(64) NOT NULL,
+    confirmed_at timestamptz,
+    last_step bigint NOT NULL DEFAULT 0,
+    created_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    code_hash varchar(64) NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id);
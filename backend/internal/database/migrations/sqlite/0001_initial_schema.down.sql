@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS thoughts_fts;
+DROP TABLE IF EXISTS password_resets;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS thought_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS thoughts;
+DROP TABLE IF EXISTS users;
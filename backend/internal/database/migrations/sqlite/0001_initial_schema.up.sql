@@ -0,0 +1,63 @@
+CREATE TABLE IF NOT EXISTS users (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    email text NOT NULL UNIQUE,
+    password text NOT NULL,
+    email_verified numeric DEFAULT false,
+    verification_token varchar(255),
+    verification_sent_at datetime,
+    deletion_due_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_users_verification_token ON users (verification_token);
+CREATE INDEX IF NOT EXISTS idx_users_deletion_due_at ON users (deletion_due_at);
+
+CREATE TABLE IF NOT EXISTS thoughts (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    content text NOT NULL,
+    user_id integer NOT NULL REFERENCES users (id),
+    created_at datetime,
+    updated_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_thoughts_user_created ON thoughts (user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL,
+    user_id integer NOT NULL,
+    created_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags (user_id, name);
+
+CREATE TABLE IF NOT EXISTS thought_tags (
+    thought_id integer NOT NULL REFERENCES thoughts (id),
+    tag_id integer NOT NULL REFERENCES tags (id),
+    PRIMARY KEY (thought_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    user_id integer NOT NULL,
+    family_id varchar(64) NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    expires_at datetime NOT NULL,
+    rotated_at datetime,
+    revoked_at datetime,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_family_id ON sessions (family_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_token_hash ON sessions (token_hash);
+
+CREATE TABLE IF NOT EXISTS password_resets (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    user_id integer NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    expires_at datetime NOT NULL,
+    used_at datetime,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_password_resets_token_hash ON password_resets (token_hash);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS identities (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    user_id integer NOT NULL,
+    provider varchar(64) NOT NULL,
+    subject varchar(255) NOT NULL,
+    email varchar(255),
+    last_login_at datetime NOT NULL,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_identities_user_id ON identities (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_identities_provider_subject ON identities (provider, subject);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS two_factors (
+    user_id integer PRIMARY KEY,
+    secret varchar(64) NOT NULL,
+    confirmed_at datetime,
+    last_step integer NOT NULL DEFAULT 0,
+    created_at datetime
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    user_id integer NOT NULL,
+    code_hash varchar(64) NOT NULL,
+    used_at datetime,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id);
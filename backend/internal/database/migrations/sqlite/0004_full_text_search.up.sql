@@ -0,0 +1,23 @@
+-- requires: fts5
+CREATE VIRTUAL TABLE IF NOT EXISTS thoughts_fts USING fts5(
+    content,
+    content='thoughts',
+    content_rowid='id',
+    tokenize='unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS thoughts_fts_ai AFTER INSERT ON thoughts BEGIN
+    INSERT INTO thoughts_fts(rowid, content) VALUES (new.id, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS thoughts_fts_ad AFTER DELETE ON thoughts BEGIN
+    INSERT INTO thoughts_fts(thoughts_fts, rowid, content) VALUES ('delete', old.id, old.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS thoughts_fts_au AFTER UPDATE OF content ON thoughts BEGIN
+    INSERT INTO thoughts_fts(thoughts_fts, rowid, content) VALUES ('delete', old.id, old.content);
+    INSERT INTO thoughts_fts(rowid, content) VALUES (new.id, new.content);
+END;
+
+-- Index thoughts written before the index existed
+INSERT INTO thoughts_fts(thoughts_fts) VALUES ('rebuild');
@@ -0,0 +1,28 @@
+package database
+
+import "gorm.io/gorm"
+
+// ThoughtsFTSTable is the FTS5 index over thoughts.content. The SQLite
+// migration that creates it and its sync triggers only runs where FTS5 is
+// compiled in (go-sqlite3 needs the sqlite_fts5 build tag); elsewhere search
+// falls back to LIKE matching.
+const ThoughtsFTSTable = "thoughts_fts"
+
+// FullTextSearchEnabled reports whether the FTS5 index exists and can be
+// queried by this build
+func FullTextSearchEnabled(db *gorm.DB) bool {
+	return fts5Available(db) && db.Migrator().HasTable(ThoughtsFTSTable)
+}
+
+// fts5Available reports whether the database is SQLite compiled with FTS5
+func fts5Available(db *gorm.DB) bool {
+	if db.Dialector.Name() != "sqlite" {
+		return false
+	}
+
+	var enabled int
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled).Error; err != nil {
+		return false
+	}
+	return enabled == 1
+}
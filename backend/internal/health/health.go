@@ -0,0 +1,164 @@
+// Package health runs the readiness checks behind /health/ready: database
+// connectivity, schema migrations and free disk space. Each check reports its
+// own status and latency so a probe failure points at the broken component.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/yourusername/backend/internal/database"
+	"gorm.io/gorm"
+)
+
+// Component and overall statuses
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// DefaultTimeout bounds each check when the Checker sets no timeout
+const DefaultTimeout = 2 * time.Second
+
+// Check is a named probe of one component. Run returns nil when the
+// component is healthy.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Component is the result of one check
+type Component struct {
+	Status string `json:"status"`
+	// LatencyMS is how long the check took, in milliseconds
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the result of all checks. Status is up only when every
+// component is up.
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+// Healthy reports whether every component is up
+func (r Report) Healthy() bool {
+	return r.Status == StatusUp
+}
+
+// Checker runs a fixed set of checks
+type Checker struct {
+	checks []Check
+	// Timeout bounds each check
+	Timeout time.Duration
+}
+
+// NewChecker returns a checker running the given checks
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{checks: checks, Timeout: DefaultTimeout}
+}
+
+// Run runs every check concurrently and collects the results
+func (c *Checker) Run(ctx context.Context) Report {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	report := Report{Status: StatusUp, Components: make(map[string]Component, len(c.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Run(ctx)
+			component := Component{
+				Status:    StatusUp,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				component.Status = StatusDown
+				component.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[check.Name] = component
+			if err != nil {
+				report.Status = StatusDown
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// Database checks that a connection to the database can be made
+func Database(db *gorm.DB) Check {
+	return Check{
+		Name: "database",
+		Run: func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		},
+	}
+}
+
+// Migrations checks that every schema migration has been applied, so an
+// instance running newer code than its database is not sent traffic
+func Migrations(db *gorm.DB) Check {
+	return Check{
+		Name: "migrations",
+		Run: func(ctx context.Context) error {
+			pending, err := database.PendingMigrations(db.WithContext(ctx))
+			if err != nil {
+				return err
+			}
+			if pending > 0 {
+				return fmt.Errorf("%d pending migrations", pending)
+			}
+			return nil
+		},
+	}
+}
+
+// errDiskUnsupported is returned by freeDiskSpace on platforms where it
+// cannot be measured
+var errDiskUnsupported = errors.New("free disk space is not available on this platform")
+
+// DiskSpace checks that the file system holding path has at least minFree
+// bytes available. Platforms without a way to measure it always pass.
+func DiskSpace(path string, minFree uint64) Check {
+	dir := filepath.Dir(path)
+	return Check{
+		Name: "disk",
+		Run: func(ctx context.Context) error {
+			free, err := freeDiskSpace(dir)
+			if errors.Is(err, errDiskUnsupported) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if free < minFree {
+				return fmt.Errorf("%d MB free, need %d MB", free>>20, minFree>>20)
+			}
+			return nil
+		},
+	}
+}
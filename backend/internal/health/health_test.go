@@ -0,0 +1,79 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/database"
+	"github.com/yourusername/backend/internal/health"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+func TestChecker(t *testing.T) {
+	checker := health.NewChecker(
+		health.Check{Name: "ok", Run: func(ctx context.Context) error { return nil }},
+		health.Check{Name: "broken", Run: func(ctx context.Context) error { return errors.New("connection refused") }},
+		health.Check{Name: "slow", Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	)
+	checker.Timeout = 50 * time.Millisecond
+
+	report := checker.Run(context.Background())
+	assert.False(t, report.Healthy())
+	assert.Equal(t, health.StatusDown, report.Status)
+	assert.Equal(t, health.StatusUp, report.Components["ok"].Status)
+	assert.Equal(t, "connection refused", report.Components["broken"].Error)
+
+	// A hanging check is cut off at the timeout
+	slow := report.Components["slow"]
+	assert.Equal(t, health.StatusDown, slow.Status)
+	assert.GreaterOrEqual(t, slow.LatencyMS, float64(50))
+	assert.Less(t, slow.LatencyMS, float64(time.Second/time.Millisecond))
+}
+
+func TestDatabaseChecks(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	checker := health.NewChecker(health.Database(db), health.Migrations(db))
+
+	report := checker.Run(context.Background())
+	assert.True(t, report.Healthy())
+	assert.Len(t, report.Components, 2)
+
+	// Rolling back a migration leaves the schema behind the code
+	_, err := database.MigrateDown(db, 1)
+	assert.NoError(t, err)
+	report = checker.Run(context.Background())
+	assert.False(t, report.Healthy())
+	assert.Equal(t, health.StatusUp, report.Components["database"].Status)
+	assert.Equal(t, "1 pending migrations", report.Components["migrations"].Error)
+
+	// A closed connection fails the ping
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	report = checker.Run(context.Background())
+	assert.Equal(t, health.StatusDown, report.Components["database"].Status)
+}
+
+func TestDiskSpace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thoughts.db")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	report := health.NewChecker(health.DiskSpace(path, 1)).Run(context.Background())
+	assert.True(t, report.Healthy())
+
+	report = health.NewChecker(health.DiskSpace(path, math.MaxUint64)).Run(context.Background())
+	if report.Healthy() {
+		t.Skip("free disk space cannot be measured on this platform")
+	}
+	assert.Contains(t, report.Components["disk"].Error, "MB free")
+}
@@ -0,0 +1,79 @@
+// Package jobs runs work that a request starts but does not wait for
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yourusername/backend/internal/logging"
+)
+
+// jobTimeout bounds how long one job may take
+const jobTimeout = time.Minute
+
+// ErrQueueFull is returned by Queue.Enqueue when the backlog is full
+var ErrQueueFull = errors.New("job queue is full")
+
+// Job is a unit of background work
+type Job func(ctx context.Context) error
+
+// Queue runs jobs one at a time in the background, so the request that
+// queued one neither waits for it nor shows in its timing what the job did.
+// Failed jobs are logged with the request that queued them.
+type Queue struct {
+	jobs chan queuedJob
+}
+
+type queuedJob struct {
+	ctx  context.Context
+	name string
+	job  Job
+}
+
+// NewQueue returns a queue holding up to size waiting jobs. Nothing runs
+// until Run is called.
+func NewQueue(size int) *Queue {
+	return &Queue{jobs: make(chan queuedJob, size)}
+}
+
+// Enqueue queues the job and returns at once. The job gets the values of
+// ctx, such as its logger, but not its cancellation, since the request
+// usually ends before the job runs. name identifies the job in logs.
+func (q *Queue) Enqueue(ctx context.Context, name string, job Job) error {
+	select {
+	case q.jobs <- queuedJob{ctx: context.WithoutCancel(ctx), name: name, job: job}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run runs queued jobs until ctx is done, then runs what is left in the
+// queue before returning
+func (q *Queue) Run(ctx context.Context) {
+	for {
+		select {
+		case queued := <-q.jobs:
+			q.run(queued)
+		case <-ctx.Done():
+			for {
+				select {
+				case queued := <-q.jobs:
+					q.run(queued)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (q *Queue) run(queued queuedJob) {
+	ctx, cancel := context.WithTimeout(queued.ctx, jobTimeout)
+	defer cancel()
+
+	if err := queued.job(ctx); err != nil {
+		logging.FromContext(ctx).Error("Background job failed", "job", queued.name, "error", err)
+	}
+}
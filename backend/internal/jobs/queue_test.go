@@ -0,0 +1,37 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/jobs"
+)
+
+func TestQueue(t *testing.T) {
+	queue := jobs.NewQueue(2)
+	var ran []string
+	job := func(name string, err error) jobs.Job {
+		return func(ctx context.Context) error {
+			// The request ending does not cancel its jobs
+			assert.NoError(t, ctx.Err())
+			ran = append(ran, name)
+			return err
+		}
+	}
+
+	// Enqueueing does not wait for the job
+	requestCtx, cancelRequest := context.WithCancel(context.Background())
+	assert.NoError(t, queue.Enqueue(requestCtx, "first", job("first", errors.New("failed"))))
+	assert.NoError(t, queue.Enqueue(requestCtx, "second", job("second", nil)))
+	assert.ErrorIs(t, queue.Enqueue(requestCtx, "third", job("third", nil)), jobs.ErrQueueFull)
+	cancelRequest()
+	assert.Empty(t, ran)
+
+	// Stopping runs what is still queued, past a failed job
+	ctx, stop := context.WithCancel(context.Background())
+	stop()
+	queue.Run(ctx)
+	assert.Equal(t, []string{"first", "second"}, ran)
+}
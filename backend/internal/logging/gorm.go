@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// SlowQueryThreshold is the duration above which queries are logged as slow
+const SlowQueryThreshold = 200 * time.Millisecond
+
+// gormLogger sends GORM's logs through slog, using the logger of the query
+// context when there is one so entries carry the request ID
+type gormLogger struct {
+	base *slog.Logger
+}
+
+// GormLogger returns a GORM logger writing to logger. Failed and slow
+// queries are logged as warnings and every query at debug level.
+func GormLogger(logger *slog.Logger) gormlogger.Interface {
+	return gormLogger{base: logger}
+}
+
+// LogMode is ignored; the slog level decides what is written
+func (l gormLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface {
+	return l
+}
+
+func (l gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	l.logger(ctx).InfoContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	l.logger(ctx).WarnContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	l.logger(ctx).ErrorContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	logger := l.logger(ctx)
+	elapsed := time.Since(begin)
+
+	var level slog.Level
+	var msg string
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, msg = slog.LevelWarn, "Query failed"
+	case elapsed > SlowQueryThreshold:
+		level, msg = slog.LevelWarn, "Slow query"
+	default:
+		level, msg = slog.LevelDebug, "Query"
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Duration("duration", elapsed),
+	}
+	if level == slog.LevelWarn && err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// ParamsFilter drops the bound values, so passwords, tokens and thought
+// contents never reach the logs; the SQL keeps its placeholders
+func (l gormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
+
+func (l gormLogger) logger(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return l.base
+}
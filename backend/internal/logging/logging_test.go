@@ -0,0 +1,93 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/config"
+	"github.com/yourusername/backend/internal/logging"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(config.Logging{Level: "warn", Format: "json"}, &buf)
+	if assert.NoError(t, err) {
+		logger.Info("hidden")
+		logger.Warn("shown")
+		assert.NotContains(t, buf.String(), "hidden")
+		assert.Contains(t, buf.String(), `"msg":"shown"`)
+	}
+
+	_, err = logging.New(config.Logging{Level: "loud", Format: "json"}, &buf)
+	assert.Error(t, err)
+	_, err = logging.New(config.Logging{Level: "info", Format: "xml"}, &buf)
+	assert.Error(t, err)
+}
+
+func TestMiddlewareRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	app := fiber.New()
+	app.Use(logging.Middleware(slog.New(slog.NewJSONHandler(&logs, nil))))
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.SetUserContext(logging.With(c.UserContext(), "user_id", 7))
+		logging.FromContext(c.UserContext()).Info("handled")
+		return c.SendString(logging.RequestID(c))
+	})
+
+	tests := []struct {
+		name     string
+		incoming string
+		reused   bool
+	}{
+		{name: "generated", incoming: ""},
+		{name: "propagated", incoming: "lb-5f1c2e:01", reused: true},
+		{name: "unsafe characters", incoming: "abc\" injected=1"},
+		{name: "too long", incoming: strings.Repeat("a", 200)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(logging.RequestIDHeader, tt.incoming)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			id := resp.Header.Get(logging.RequestIDHeader)
+			if tt.reused {
+				assert.Equal(t, tt.incoming, id)
+			} else {
+				assert.Len(t, id, 32)
+			}
+			assert.Contains(t, logs.String(), `"msg":"handled","request_id":"`+id+`","user_id":7`)
+			assert.Contains(t, logs.String(), `"msg":"request","request_id":"`+id+`","user_id":7,"method":"GET","path":"/","status":200`)
+		})
+	}
+}
+
+func TestFromContextFallsBackToDefault(t *testing.T) {
+	assert.Equal(t, slog.Default(), logging.FromContext(context.Background()))
+}
+
+func TestGormLoggerHidesValues(t *testing.T) {
+	var logs bytes.Buffer
+	db := testutils.SetupTestDB(t)
+	db.Logger = logging.GormLogger(slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	db.Create(&models.User{Email: "secret@example.com", Password: "password123"})
+
+	assert.Contains(t, logs.String(), "INSERT INTO")
+	assert.NotContains(t, logs.String(), "secret@example.com")
+}
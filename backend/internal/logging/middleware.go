@@ -0,0 +1,107 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs accepted from callers
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// Middleware assigns every request an ID, reusing a well-formed
+// X-Request-ID from the caller such as a load balancer, and returns it in
+// the response header. The request context carries a logger tagged with the
+// ID, and a line is logged for each request once it completes.
+func Middleware(logger *slog.Logger) fiber.Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		id := c.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		} else {
+			// Fiber's strings point into buffers reused by later requests
+			id = utils.CopyString(id)
+		}
+		c.Locals(requestIDKey{}, id)
+		c.Set(RequestIDHeader, id)
+
+		args := []any{slog.String("request_id", id)}
+		if span := trace.SpanContextFromContext(c.UserContext()); span.IsValid() {
+			args = append(args, slog.String("trace_id", span.TraceID().String()))
+		}
+		c.SetUserContext(WithLogger(c.UserContext(), logger.With(args...)))
+
+		// The error handler runs here, inside the request, so it logs with
+		// the request's logger and the line below has the final status
+		if err := c.Next(); err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+		status := c.Response().StatusCode()
+
+		// The context logger picks up the user ID once the request is
+		// authenticated
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		FromContext(c.UserContext()).LogAttrs(c.UserContext(), level, "request",
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("ip", c.IP()),
+		)
+		return nil
+	}
+}
+
+// RequestID returns the ID Middleware assigned to the request, or an empty
+// string outside it
+func RequestID(c *fiber.Ctx) string {
+	id, _ := c.Locals(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID accepts short IDs of letters, digits and the separators
+// common in generated IDs, so callers cannot inject anything into the logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns 16 random bytes in hex
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,41 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer drops each message as an .eml file into a directory, for local
+// development and end-to-end tests
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// NewFileMailer creates the drop directory if needed
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{Dir: dir, From: from}, nil
+}
+
+// Send writes the message to a new file
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if msg.From == "" {
+		msg.From = m.From
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+
+	return os.WriteFile(filepath.Join(m.Dir, name), format(msg), 0644)
+}
@@ -0,0 +1,22 @@
+package mail
+
+import (
+	"context"
+
+	"github.com/yourusername/backend/internal/logging"
+)
+
+// LogMailer prints messages to the server log instead of sending them
+type LogMailer struct {
+	From string
+}
+
+// Send logs the message with the logger of ctx, so it is tagged with the
+// request that sent it
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	if msg.From == "" {
+		msg.From = m.From
+	}
+	logging.FromContext(ctx).Info("mail", "from", msg.From, "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
@@ -0,0 +1,66 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"time"
+
+	"github.com/yourusername/backend/internal/config"
+)
+
+// Message is a plain-text email
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New builds the mailer selected by the configured driver:
+//   - smtp: delivers through the SMTP server with the configured credentials
+//   - file: writes .eml files into the drop directory
+//   - log: prints messages to the server log
+//
+// The From address is used as the sender for every driver.
+func New(cfg config.Mail) (Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP host is required for the smtp mail driver")
+		}
+		return &SMTPMailer{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		}, nil
+	case "file":
+		return NewFileMailer(cfg.DropDir, cfg.From)
+	case "", "log":
+		return &LogMailer{From: cfg.From}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+// format renders the message in RFC 5322 form
+func format(msg Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(msg.Body)
+	return buf.Bytes()
+}
@@ -0,0 +1,110 @@
+package mail_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/config"
+	"github.com/yourusername/backend/internal/mail"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	mailer, err := mail.NewFileMailer(dir, "Thoughts <no-reply@example.com>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mailer.Send(context.Background(), mail.Message{
+		To:      "user@example.com",
+		Subject: "Grüße",
+		Body:    "Hello there",
+	})
+	assert.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	data, err := os.ReadFile(files[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "From: Thoughts <no-reply@example.com>\r\n")
+	assert.Contains(t, string(data), "To: user@example.com\r\n")
+	assert.Contains(t, string(data), "Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=\r\n")
+	assert.Contains(t, string(data), "\r\n\r\nHello there")
+}
+
+func TestMemoryMailer(t *testing.T) {
+	mailer := mail.NewMemoryMailer()
+	ctx := context.Background()
+
+	mailer.Send(ctx, mail.Message{To: "a@example.com", Subject: "first"})
+	mailer.Send(ctx, mail.Message{To: "b@example.com", Subject: "other"})
+	mailer.Send(ctx, mail.Message{To: "a@example.com", Subject: "second"})
+
+	assert.Len(t, mailer.Messages(), 3)
+
+	msg, ok := mailer.Last("a@example.com")
+	assert.True(t, ok)
+	assert.Equal(t, "second", msg.Subject)
+
+	_, ok = mailer.Last("nobody@example.com")
+	assert.False(t, ok)
+}
+
+func TestNew(t *testing.T) {
+	mailer, err := mail.New(config.Default().Mail)
+	assert.NoError(t, err)
+	assert.IsType(t, &mail.LogMailer{}, mailer)
+
+	_, err = mail.New(config.Mail{Driver: "smtp"})
+	assert.Error(t, err)
+
+	_, err = mail.New(config.Mail{Driver: "pigeon"})
+	assert.Error(t, err)
+}
+
+// blockingMailer delivers to the wrapped mailer once released
+type blockingMailer struct {
+	release chan struct{}
+	*mail.MemoryMailer
+}
+
+func (m blockingMailer) Send(ctx context.Context, msg mail.Message) error {
+	<-m.release
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.MemoryMailer.Send(ctx, msg)
+}
+
+func TestQueue(t *testing.T) {
+	delivered := blockingMailer{release: make(chan struct{}), MemoryMailer: mail.NewMemoryMailer()}
+	queue := mail.NewQueue(delivered, 2)
+
+	// Sending does not wait for the mail server
+	requestCtx, cancelRequest := context.WithCancel(context.Background())
+	assert.NoError(t, queue.Send(requestCtx, mail.Message{To: "a@example.com", Subject: "first"}))
+	assert.NoError(t, queue.Send(requestCtx, mail.Message{To: "b@example.com", Subject: "second"}))
+	assert.ErrorIs(t, queue.Send(requestCtx, mail.Message{To: "c@example.com"}), mail.ErrQueueFull)
+	// The request ending does not cancel its messages
+	cancelRequest()
+
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		queue.Run(ctx)
+		close(done)
+	}()
+
+	// Stopping delivers what is still queued
+	stop()
+	close(delivered.release)
+	<-done
+	assert.Len(t, delivered.Messages(), 2)
+	_, ok := delivered.Last("b@example.com")
+	assert.True(t, ok)
+}
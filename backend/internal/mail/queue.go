@@ -0,0 +1,79 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yourusername/backend/internal/logging"
+)
+
+// queueSendTimeout bounds how long one delivery may take
+const queueSendTimeout = 30 * time.Second
+
+// ErrQueueFull is returned by Queue.Send when the backlog is full
+var ErrQueueFull = errors.New("mail queue is full")
+
+// Queue delivers messages through another mailer in the background, so
+// requests do not wait on the mail server. Besides keeping responses fast,
+// this keeps their timing from revealing whether an email was sent at all.
+// Failed deliveries are logged with the request that queued them.
+type Queue struct {
+	mailer   Mailer
+	messages chan queuedMessage
+}
+
+type queuedMessage struct {
+	ctx context.Context
+	msg Message
+}
+
+// NewQueue returns a queue holding up to size undelivered messages. Nothing
+// is delivered until Run is called.
+func NewQueue(mailer Mailer, size int) *Queue {
+	return &Queue{
+		mailer:   mailer,
+		messages: make(chan queuedMessage, size),
+	}
+}
+
+// Send queues the message and returns at once. The message keeps the values
+// of ctx, such as its logger, but not its cancellation, since the request
+// usually ends before the message is delivered.
+func (q *Queue) Send(ctx context.Context, msg Message) error {
+	select {
+	case q.messages <- queuedMessage{ctx: context.WithoutCancel(ctx), msg: msg}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run delivers queued messages until ctx is done, then delivers what is
+// left in the queue before returning
+func (q *Queue) Run(ctx context.Context) {
+	for {
+		select {
+		case queued := <-q.messages:
+			q.deliver(queued)
+		case <-ctx.Done():
+			for {
+				select {
+				case queued := <-q.messages:
+					q.deliver(queued)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (q *Queue) deliver(queued queuedMessage) {
+	ctx, cancel := context.WithTimeout(queued.ctx, queueSendTimeout)
+	defer cancel()
+
+	if err := q.mailer.Send(ctx, queued.msg); err != nil {
+		logging.FromContext(ctx).Error("Could not send email", "subject", queued.msg.Subject, "error", err)
+	}
+}
@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/mail"
+	"net/smtp"
+)
+
+// SMTPMailer delivers messages through an SMTP relay. STARTTLS is used when
+// the server offers it; authentication is skipped without a username.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers the message
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if msg.From == "" {
+		msg.From = m.From
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	addr := net.JoinHostPort(m.Host, m.Port)
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, envelopeAddress(msg.From), []string{envelopeAddress(msg.To)}, format(msg))
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// envelopeAddress extracts the bare address from "Name <addr>"
+func envelopeAddress(address string) string {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return address
+	}
+	return parsed.Address
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// startKey stores the query start time on the statement
+const startKey = "metrics:start"
+
+// gormPlugin times every query GORM runs
+type gormPlugin struct {
+	m *Metrics
+}
+
+// GormPlugin returns a GORM plugin recording query durations by operation
+// and table. Register it with db.Use.
+func (m *Metrics) GormPlugin() gorm.Plugin {
+	return gormPlugin{m: m}
+}
+
+func (gormPlugin) Name() string {
+	return "metrics"
+}
+
+// Initialize wraps each of GORM's callback chains in a timer
+func (p gormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	chains := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("*").Register, callbacks.Create().After("*").Register},
+		{"query", callbacks.Query().Before("*").Register, callbacks.Query().After("*").Register},
+		{"update", callbacks.Update().Before("*").Register, callbacks.Update().After("*").Register},
+		{"delete", callbacks.Delete().Before("*").Register, callbacks.Delete().After("*").Register},
+		{"row", callbacks.Row().Before("*").Register, callbacks.Row().After("*").Register},
+		{"raw", callbacks.Raw().Before("*").Register, callbacks.Raw().After("*").Register},
+	}
+
+	for _, chain := range chains {
+		if err := chain.before("metrics:before_"+chain.operation, startTimer); err != nil {
+			return err
+		}
+		if err := chain.after("metrics:after_"+chain.operation, p.observe(chain.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startTimer(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func (p gormPlugin) observe(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startKey)
+		start, isTime := value.(time.Time)
+		if !ok || !isTime {
+			return
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		p.m.queryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,193 @@
+// Package metrics collects the Prometheus metrics served on /metrics: HTTP
+// requests by route, database query durations, logins, token validation
+// failures and a few business counters. A nil *Metrics records nothing, so
+// handlers and tests can run without it.
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "thoughts"
+
+// Login results
+const (
+	LoginSuccess = "success"
+	LoginFailure = "failure"
+)
+
+// Reasons an access token is rejected
+const (
+	JWTMissing = "missing"
+	JWTInvalid = "invalid"
+	JWTRevoked = "revoked"
+)
+
+// unmatchedRoute labels requests no route matched, so probing random paths
+// cannot grow the number of series. Every other label is a registered route.
+const unmatchedRoute = "unmatched"
+
+// Metrics holds the collectors and the registry they are exposed from
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	queryDuration   *prometheus.HistogramVec
+	thoughtsCreated prometheus.Counter
+	logins          *prometheus.CounterVec
+	jwtFailures     *prometheus.CounterVec
+}
+
+// New creates the metrics on a fresh registry, together with the standard
+// Go runtime and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Database query latency by operation and table.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation", "table"}),
+		thoughtsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "created_total",
+			Help:      "Thoughts created, including imported ones.",
+		}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Login attempts by result.",
+		}, []string{"result"}),
+		jwtFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "jwt_validation_failures_total",
+			Help:      "Requests rejected by the access token check, by reason.",
+		}, []string{"reason"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.queryDuration,
+		m.thoughtsCreated,
+		m.logins,
+		m.jwtFailures,
+	)
+	return m
+}
+
+// Registry returns the registry the metrics are registered with
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
+
+// Middleware counts and times every request. Requests are labeled with the
+// route pattern, such as /api/thoughts/:id, rather than the raw path.
+func (m *Metrics) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		self := c.Route()
+
+		// Handle errors here rather than on the way out, so the status is
+		// the one the error handler sends
+		if err := c.Next(); err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+		status := c.Response().StatusCode()
+
+		// Label with the last route that ran; if that is still this
+		// middleware no route matched
+		route := c.Route().Path
+		if c.Route() == self {
+			route = unmatchedRoute
+		}
+
+		// Fiber's strings point into buffers reused by later requests, so
+		// the label values are copied
+		labels := prometheus.Labels{
+			"method": utils.CopyString(c.Method()),
+			"route":  route,
+			"status": strconv.Itoa(status),
+		}
+		m.requests.With(labels).Inc()
+		m.requestDuration.With(labels).Observe(time.Since(start).Seconds())
+		return nil
+	}
+}
+
+// ActiveUsers exposes the number of logged-in users as a gauge, counted by
+// count on every scrape
+func (m *Metrics) ActiveUsers(count func(ctx context.Context) (int64, error)) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_users",
+		Help:      "Users holding an unexpired, unrevoked session.",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		n, err := count(ctx)
+		if err != nil {
+			slog.Error("Could not count active users", "error", err)
+			return math.NaN()
+		}
+		return float64(n)
+	}))
+}
+
+// ThoughtsCreated counts n new thoughts
+func (m *Metrics) ThoughtsCreated(n int) {
+	if m == nil {
+		return
+	}
+	m.thoughtsCreated.Add(float64(n))
+}
+
+// Login counts a login attempt with the given result
+func (m *Metrics) Login(result string) {
+	if m == nil {
+		return
+	}
+	m.logins.WithLabelValues(result).Inc()
+}
+
+// JWTFailure counts a request rejected by the access token check
+func (m *Metrics) JWTFailure(reason string) {
+	if m == nil {
+		return
+	}
+	m.jwtFailures.WithLabelValues(reason).Inc()
+}
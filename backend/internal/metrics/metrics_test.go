@@ -0,0 +1,107 @@
+package metrics_test
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/metrics"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+// scrape returns the /metrics output of the app
+func scrape(t *testing.T, app *fiber.App) string {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestMiddlewareLabelsRoutePattern(t *testing.T) {
+	m := metrics.New()
+	app := fiber.New()
+	app.Use(m.Middleware())
+	app.Get("/metrics", m.Handler())
+	app.Get("/thoughts/:id", func(c *fiber.Ctx) error {
+		return c.SendString(c.Params("id"))
+	})
+	app.Get("/boom", func(c *fiber.Ctx) error {
+		return fiber.ErrTeapot
+	})
+
+	for _, path := range []string{"/thoughts/1", "/thoughts/2", "/boom", "/no/such/page"} {
+		app.Test(httptest.NewRequest("GET", path, nil))
+	}
+
+	body := scrape(t, app)
+	assert.Contains(t, body, `thoughts_http_requests_total{method="GET",route="/thoughts/:id",status="200"} 2`)
+	assert.Contains(t, body, `thoughts_http_requests_total{method="GET",route="/boom",status="418"} 1`)
+	assert.Contains(t, body, `thoughts_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `thoughts_http_request_duration_seconds_count{method="GET",route="/thoughts/:id",status="200"} 2`)
+	assert.NotContains(t, body, "/thoughts/1")
+}
+
+func TestGormPlugin(t *testing.T) {
+	m := metrics.New()
+	db := testutils.SetupTestDB(t)
+	if err := db.Use(m.GormPlugin()); err != nil {
+		t.Fatal(err)
+	}
+
+	user := models.User{Email: "metrics@example.com", Password: "hash"}
+	db.Create(&user)
+	var found models.User
+	db.First(&found, user.ID)
+
+	families, err := m.Registry().Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]uint64{}
+	for _, family := range families {
+		if family.GetName() != "thoughts_db_query_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			var labels []string
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, label.GetValue())
+			}
+			counts[strings.Join(labels, " ")] = metric.GetHistogram().GetSampleCount()
+		}
+	}
+	assert.Equal(t, uint64(1), counts["create users"])
+	assert.Equal(t, uint64(1), counts["query users"])
+}
+
+func TestActiveUsers(t *testing.T) {
+	m := metrics.New()
+	m.ActiveUsers(func(ctx context.Context) (int64, error) {
+		return 3, nil
+	})
+
+	expected := `
+# HELP thoughts_active_users Users holding an unexpired, unrevoked session.
+# TYPE thoughts_active_users gauge
+thoughts_active_users 3
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected), "thoughts_active_users"))
+}
+
+func TestNilMetricsRecordNothing(t *testing.T) {
+	var m *metrics.Metrics
+	assert.NotPanics(t, func() {
+		m.Login(metrics.LoginSuccess)
+		m.JWTFailure(metrics.JWTInvalid)
+		m.ThoughtsCreated(1)
+	})
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// Identity links a user to their account at an OpenID Connect provider. The
+// provider's subject identifies the account; the email is only a record of
+// what the provider reported at the last login.
+type Identity struct {
+	ID          uint      `gorm:"primaryKey"`
+	UserID      uint      `gorm:"not null;index"`
+	Provider    string    `gorm:"size:64;not null;uniqueIndex:idx_identities_provider_subject"`
+	Subject     string    `gorm:"size:255;not null;uniqueIndex:idx_identities_provider_subject"`
+	Email       string    `gorm:"size:255"`
+	LastLoginAt time.Time `gorm:"not null"`
+	CreatedAt   time.Time
+}
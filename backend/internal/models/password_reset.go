@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordReset is a single-use token emailed to reset a forgotten password.
+// Only a hash of the token is stored.
+type PasswordReset struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// Session is one refresh token in a login session. Every refresh rotates the
+// token, adding a new row to the same family; reusing a rotated token revokes
+// the whole family.
+type Session struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	FamilyID  string    `gorm:"size:64;not null;index"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	RotatedAt *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// Tag is a per-user label attached to thoughts, either explicitly or through
+// #hashtags in the content
+type Tag struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"not null;uniqueIndex:idx_tags_user_name" json:"name"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_tags_user_name" json:"-"`
+	CreatedAt time.Time `json:"-"`
+}
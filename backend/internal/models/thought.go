@@ -7,7 +7,8 @@ import (
 type Thought struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	Content   string    `gorm:"not null" json:"content"`
-	UserID    uint      `gorm:"not null" json:"user_id"`
-	CreatedAt time.Time `json:"created_at"`
+	UserID    uint      `gorm:"not null;index:idx_thoughts_user_created,priority:1" json:"user_id"`
+	CreatedAt time.Time `gorm:"index:idx_thoughts_user_created,priority:2" json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	Tags      []Tag     `gorm:"many2many:thought_tags" json:"tags,omitempty"`
 }
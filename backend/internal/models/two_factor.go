@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// TwoFactor is a user's TOTP authenticator. It is pending from setup until
+// the user confirms it with a first code, and only then asked for at login.
+type TwoFactor struct {
+	UserID uint `gorm:"primaryKey;autoIncrement:false"`
+	// Secret is the base32 TOTP secret shared with the authenticator app
+	Secret      string `gorm:"size:64;not null"`
+	ConfirmedAt *time.Time
+	// LastStep is the time step of the last accepted code, so a code is
+	// never accepted twice
+	LastStep  int64 `gorm:"not null;default:0"`
+	CreatedAt time.Time
+}
+
+// Enabled reports whether the authenticator has been confirmed
+func (t *TwoFactor) Enabled() bool {
+	return t != nil && t.ConfirmedAt != nil
+}
+
+// RecoveryCode is a one-time code that logs in in place of a TOTP code,
+// for users who lost their authenticator. Only its hash is stored.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"size:64;not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -1,30 +1,75 @@
 package models
 
 import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// tracerName identifies the spans timing password hashing, which takes far
+// longer than anything else in a login or sign up request
+const tracerName = "github.com/yourusername/backend/internal/models"
+
+// ErrNoPassword is returned when checking the password of an account that
+// has none
+var ErrNoPassword = errors.New("account has no password")
+
 type User struct {
 	gorm.Model
-	Email               string    `gorm:"unique;not null" json:"email"`
-	Password            string    `gorm:"not null" json:"-"`
-	EmailVerified       bool      `gorm:"default:false" json:"email_verified"`
-	VerificationToken   string    `gorm:"size:255" json:"-"`
-	Thoughts           []Thought  `gorm:"foreignKey:UserID" json:"thoughts"`
+	Email              string     `gorm:"unique;not null" json:"email"`
+	Password           string     `gorm:"not null" json:"-"`
+	EmailVerified      bool       `gorm:"default:false" json:"email_verified"`
+	VerificationToken  string     `gorm:"size:255;index" json:"-"`
+	VerificationSentAt *time.Time `json:"-"`
+	// DeletionDueAt is set while the account is scheduled for deletion
+	DeletionDueAt *time.Time `gorm:"index" json:"-"`
+	Thoughts      []Thought  `gorm:"foreignKey:UserID" json:"thoughts"`
 }
 
-// BeforeCreate hashes the password before saving to database
+// BeforeCreate hashes the password before saving to database. Accounts
+// created through a login provider have no password and keep it empty,
+// which no password matches.
 func (u *User) BeforeCreate(tx *gorm.DB) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+	if !u.HasPassword() {
+		return nil
+	}
+	return u.SetPassword(tx.Statement.Context, u.Password)
+}
+
+// HasPassword reports whether the user can log in with a password
+func (u *User) HasPassword() bool {
+	return u.Password != ""
+}
+
+// SetPassword replaces the password with its bcrypt hash. BeforeCreate only
+// runs on insert, so password changes on existing users must go through here.
+func (u *User) SetPassword(ctx context.Context, password string) error {
+	_, span := otel.Tracer(tracerName).Start(ctx, "bcrypt.hash")
+	defer span.End()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
 	u.Password = string(hashedPassword)
 	return nil
 }
 
-// CheckPassword verifies the password
-func (u *User) CheckPassword(password string) error {
+// CheckPassword verifies the password. A mismatch is an expected outcome, so
+// it does not mark the span as failed.
+func (u *User) CheckPassword(ctx context.Context, password string) error {
+	if !u.HasPassword() {
+		return ErrNoPassword
+	}
+
+	_, span := otel.Tracer(tracerName).Start(ctx, "bcrypt.compare")
+	defer span.End()
+
 	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 }
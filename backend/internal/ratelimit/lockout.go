@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// FailureMemory is how long failed logins are remembered after the last
+// one. Lockouts grow with every failure within it.
+const FailureMemory = 24 * time.Hour
+
+// LockoutPolicy locks an account after Threshold failed logins. The first
+// lock lasts Duration and each further failure doubles it, up to
+// MaxDuration. Zero Threshold never locks.
+type LockoutPolicy struct {
+	Threshold   int
+	Duration    time.Duration
+	MaxDuration time.Duration
+}
+
+// Lockout tracks failed logins per account and locks out further attempts
+type Lockout struct {
+	store  Store
+	policy LockoutPolicy
+}
+
+// NewLockout returns a lockout keeping its failure counts in store
+func NewLockout(store Store, policy LockoutPolicy) *Lockout {
+	return &Lockout{store: store, policy: policy}
+}
+
+// Remaining returns how long the account at key stays locked, zero when it
+// is not. A nil lockout never locks.
+func (l *Lockout) Remaining(ctx context.Context, key string) (time.Duration, error) {
+	if l == nil || l.policy.Threshold <= 0 {
+		return 0, nil
+	}
+	now := time.Now()
+	failures, err := l.store.Failures(ctx, key, now)
+	if err != nil {
+		return 0, err
+	}
+	return l.remaining(failures, now), nil
+}
+
+// Fail counts a failed login and returns how long the account is now
+// locked for
+func (l *Lockout) Fail(ctx context.Context, key string) (time.Duration, error) {
+	if l == nil || l.policy.Threshold <= 0 {
+		return 0, nil
+	}
+	now := time.Now()
+	failures, err := l.store.AddFailure(ctx, key, now, FailureMemory)
+	if err != nil {
+		return 0, err
+	}
+	return l.remaining(failures, now), nil
+}
+
+// Reset forgets the failed logins of the account after a successful one
+func (l *Lockout) Reset(ctx context.Context, key string) error {
+	if l == nil || l.policy.Threshold <= 0 {
+		return nil
+	}
+	return l.store.ResetFailures(ctx, key)
+}
+
+// remaining is the part of the lock imposed by the last failure still to
+// run at now
+func (l *Lockout) remaining(failures Failures, now time.Time) time.Duration {
+	if failures.Count < l.policy.Threshold {
+		return 0
+	}
+
+	maxLock := max(l.policy.MaxDuration, l.policy.Duration)
+	lock := l.policy.Duration
+	for i := l.policy.Threshold; i < failures.Count && lock < maxLock; i++ {
+		lock *= 2
+	}
+	lock = min(lock, maxLock)
+
+	if left := failures.Last.Add(lock).Sub(now); left > 0 {
+		return left
+	}
+	return 0
+}
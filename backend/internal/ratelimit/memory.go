@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often Memory drops full buckets and forgotten
+// failures
+const sweepInterval = time.Minute
+
+var _ Store = (*Memory)(nil)
+
+// Memory is a Store in process memory
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]bucket
+	failures  map[string]failureRecord
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket will have refilled, after which it is no
+	// different from a missing one
+	full time.Time
+}
+
+type failureRecord struct {
+	Failures
+	expires time.Time
+}
+
+// NewMemory returns an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{
+		buckets:  make(map[string]bucket),
+		failures: make(map[string]failureRecord),
+	}
+}
+
+func (m *Memory) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	size := float64(limit.Requests)
+	perSecond := size / limit.Window.Seconds()
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = bucket{tokens: size, updated: now}
+	}
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = min(size, b.tokens+elapsed.Seconds()*perSecond)
+		b.updated = now
+	}
+
+	res := Result{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsToDuration((1 - b.tokens) / perSecond)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = secondsToDuration((size - b.tokens) / perSecond)
+
+	b.full = now.Add(res.Reset)
+	m.buckets[key] = b
+	return res, nil
+}
+
+func (m *Memory) AddFailure(ctx context.Context, key string, now time.Time, ttl time.Duration) (Failures, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	record := m.failures[key]
+	if !record.expires.After(now) {
+		record = failureRecord{}
+	}
+	record.Count++
+	record.Last = now
+	record.expires = now.Add(ttl)
+	m.failures[key] = record
+	return record.Failures, nil
+}
+
+func (m *Memory) Failures(ctx context.Context, key string, now time.Time) (Failures, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, ok := m.failures[key]
+	if !ok || !record.expires.After(now) {
+		return Failures{}, nil
+	}
+	return record.Failures, nil
+}
+
+func (m *Memory) ResetFailures(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.failures, key)
+	return nil
+}
+
+// sweep drops state that no longer matters, at most once per sweepInterval.
+// The caller holds m.mu.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, b := range m.buckets {
+		if !b.full.After(now) {
+			delete(m.buckets, key)
+		}
+	}
+	for key, record := range m.failures {
+		if !record.expires.After(now) {
+			delete(m.failures, key)
+		}
+	}
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
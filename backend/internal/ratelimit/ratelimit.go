@@ -0,0 +1,193 @@
+// Package ratelimit throttles requests with token buckets and locks accounts
+// after repeated failed logins. Buckets and failure counts live in a Store;
+// Memory keeps them in process, so every server instance enforces its own
+// limits until a shared store is plugged in.
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/logging"
+)
+
+// Response headers describing the most restrictive limit of a request, as
+// in the IETF RateLimit header fields draft
+const (
+	HeaderLimit     = "RateLimit-Limit"
+	HeaderRemaining = "RateLimit-Remaining"
+	HeaderReset     = "RateLimit-Reset"
+)
+
+// Limit allows Requests per Window. A full bucket holds Requests tokens and
+// refills steadily over Window, so bursts up to Requests are allowed. Zero
+// Requests disables the limit.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Enabled reports whether the limit applies
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Window > 0
+}
+
+// Result is the state of a bucket after taking a token from it
+type Result struct {
+	Allowed bool
+	// Limit is the bucket size
+	Limit int
+	// Remaining is the number of whole tokens left
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until a denied request would be allowed
+	RetryAfter time.Duration
+}
+
+// Failures counts the failed logins of an account
+type Failures struct {
+	Count int
+	// Last is the time of the latest failure
+	Last time.Time
+}
+
+// Store keeps buckets and failure counts. Implementations must be safe for
+// concurrent use, and Take and AddFailure must be atomic so that server
+// instances sharing a store share their limits.
+type Store interface {
+	// Take removes a token from the bucket at key, refilling it for the
+	// time passed since the last call
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+	// AddFailure counts a failure at now. Counts are forgotten ttl after the
+	// last failure.
+	AddFailure(ctx context.Context, key string, now time.Time, ttl time.Duration) (Failures, error)
+	// Failures returns the failures counted at key, if any
+	Failures(ctx context.Context, key string, now time.Time) (Failures, error)
+	// ResetFailures forgets the failures counted at key
+	ResetFailures(ctx context.Context, key string) error
+}
+
+// KeyFunc names the bucket a request is counted in. An empty key exempts
+// the request from the rule.
+type KeyFunc func(c *fiber.Ctx) string
+
+// Rule applies a limit to the requests sharing a key. Name keeps the buckets
+// of different rules apart.
+type Rule struct {
+	Name  string
+	Limit Limit
+	Key   KeyFunc
+}
+
+// Limiter enforces rules with the buckets in a store
+type Limiter struct {
+	store Store
+}
+
+// New returns a limiter keeping its buckets in store
+func New(store Store) *Limiter {
+	return &Limiter{store: store}
+}
+
+// Middleware rejects requests over any of the rules with 429 Too Many
+// Requests and a Retry-After header. Every counted request gets the
+// RateLimit-* headers of its most restrictive bucket. A nil limiter, and a
+// rule whose limit is disabled, lets everything through. Requests are let
+// through too when the store fails, so an outage of a shared store does not
+// take the API down with it.
+func (l *Limiter) Middleware(rules ...Rule) fiber.Handler {
+	var active []Rule
+	if l != nil {
+		for _, rule := range rules {
+			if rule.Limit.Enabled() {
+				active = append(active, rule)
+			}
+		}
+	}
+	if len(active) == 0 {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	return func(c *fiber.Ctx) error {
+		now := time.Now()
+		for _, rule := range active {
+			key := rule.Key(c)
+			if key == "" {
+				continue
+			}
+
+			res, err := l.store.Take(c.UserContext(), rule.Name+":"+key, rule.Limit, now)
+			if err != nil {
+				logging.FromContext(c.UserContext()).Error("Could not check rate limit", "rule", rule.Name, "error", err)
+				continue
+			}
+			setHeaders(c, res)
+			if !res.Allowed {
+				c.Set(fiber.HeaderRetryAfter, FormatSeconds(res.RetryAfter))
+				return apierror.New(fiber.StatusTooManyRequests, apierror.CodeRateLimited, "Too many requests, try again later")
+			}
+		}
+		return c.Next()
+	}
+}
+
+// setHeaders reports res unless an earlier rule left fewer requests
+func setHeaders(c *fiber.Ctx, res Result) {
+	if current := c.GetRespHeader(HeaderRemaining); current != "" {
+		if remaining, err := strconv.Atoi(current); err == nil && remaining <= res.Remaining {
+			return
+		}
+	}
+	c.Set(HeaderLimit, strconv.Itoa(res.Limit))
+	c.Set(HeaderRemaining, strconv.Itoa(res.Remaining))
+	c.Set(HeaderReset, FormatSeconds(res.Reset))
+}
+
+// FormatSeconds formats d as whole seconds for the Retry-After and
+// RateLimit-Reset headers, rounding up so clients that wait that long are
+// not rejected again
+func FormatSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
+
+// ByIP counts requests per client IP. Behind a reverse proxy the app must
+// trust the proxy and name its header, or every client shares the proxy's IP.
+func ByIP(c *fiber.Ctx) string {
+	return c.IP()
+}
+
+// ByUser counts requests per authenticated user. Requests that have not
+// been through auth.Protected are not counted.
+func ByUser(c *fiber.Ctx) string {
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return ""
+	}
+	return strconv.FormatUint(uint64(userID), 10)
+}
+
+// ByEmail counts requests per email address in the body, so guesses at one
+// account are limited however many IPs they come from. Requests without an
+// email are not counted; the handler rejects them.
+func ByEmail(c *fiber.Ctx) string {
+	var body struct {
+		Email string `json:"email" form:"email"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return ""
+	}
+	return NormalizeEmail(body.Email)
+}
+
+// NormalizeEmail returns the key of an email address, so that case and
+// surrounding spaces do not make a fresh bucket
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,188 @@
+package ratelimit_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/ratelimit"
+)
+
+func TestMemoryTake(t *testing.T) {
+	ctx := context.Background()
+	store := ratelimit.NewMemory()
+	limit := ratelimit.Limit{Requests: 3, Window: time.Minute}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// A full bucket allows a burst
+	for i := 2; i >= 0; i-- {
+		res, err := store.Take(ctx, "k", limit, start)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res, _ := store.Take(ctx, "k", limit, start)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 20*time.Second, res.RetryAfter, "one token refills every 20s")
+	assert.Equal(t, time.Minute, res.Reset)
+
+	// Other keys have their own bucket
+	res, _ = store.Take(ctx, "other", limit, start)
+	assert.True(t, res.Allowed)
+
+	res, _ = store.Take(ctx, "k", limit, start.Add(20*time.Second))
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	// Refills stop at the bucket size
+	res, _ = store.Take(ctx, "k", limit, start.Add(time.Hour))
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Remaining)
+}
+
+func TestLockoutGrowsWithFailures(t *testing.T) {
+	ctx := context.Background()
+	store := ratelimit.NewMemory()
+	lockout := ratelimit.NewLockout(store, ratelimit.LockoutPolicy{
+		Threshold:   2,
+		Duration:    time.Hour,
+		MaxDuration: 3 * time.Hour,
+	})
+
+	locked, err := lockout.Fail(ctx, "user@example.com")
+	assert.NoError(t, err)
+	assert.Zero(t, locked, "below the threshold")
+
+	locked, _ = lockout.Fail(ctx, "user@example.com")
+	assert.InDelta(t, time.Hour, locked, float64(time.Second))
+	remaining, _ := lockout.Remaining(ctx, "user@example.com")
+	assert.InDelta(t, time.Hour, remaining, float64(time.Second))
+
+	assert.NoError(t, lockout.Reset(ctx, "user@example.com"))
+	remaining, _ = lockout.Remaining(ctx, "user@example.com")
+	assert.Zero(t, remaining)
+
+	// Each failure past the threshold doubles the lock, counted from the
+	// last failure
+	past := time.Now().Add(-90 * time.Minute)
+	for i := 0; i < 3; i++ {
+		store.AddFailure(ctx, "doubled", past, ratelimit.FailureMemory)
+	}
+	remaining, _ = lockout.Remaining(ctx, "doubled")
+	assert.InDelta(t, 30*time.Minute, remaining, float64(time.Second))
+
+	for i := 0; i < 10; i++ {
+		store.AddFailure(ctx, "capped", past, ratelimit.FailureMemory)
+	}
+	remaining, _ = lockout.Remaining(ctx, "capped")
+	assert.InDelta(t, 90*time.Minute, remaining, float64(time.Second))
+}
+
+func TestNilLockoutNeverLocks(t *testing.T) {
+	var lockout *ratelimit.Lockout
+	locked, err := lockout.Fail(context.Background(), "user@example.com")
+	assert.NoError(t, err)
+	assert.Zero(t, locked)
+}
+
+func TestMiddleware(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: apierror.Handler})
+	limiter := ratelimit.New(ratelimit.NewMemory())
+	app.Post("/login",
+		limiter.Middleware(ratelimit.Rule{Name: "ip", Limit: ratelimit.Limit{Requests: 5, Window: time.Minute}, Key: ratelimit.ByIP}),
+		limiter.Middleware(ratelimit.Rule{Name: "email", Limit: ratelimit.Limit{Requests: 2, Window: time.Minute}, Key: ratelimit.ByEmail}),
+		func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusNoContent)
+		})
+
+	login := func(email string) *http.Response {
+		req := httptest.NewRequest("POST", "/login", strings.NewReader(`{"email":"`+email+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// The email bucket is the more restrictive one, so its headers win
+	resp := login("user@example.com")
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get(ratelimit.HeaderLimit))
+	assert.Equal(t, "1", resp.Header.Get(ratelimit.HeaderRemaining))
+	assert.Equal(t, "30", resp.Header.Get(ratelimit.HeaderReset))
+
+	// Case does not make a new bucket
+	assert.Equal(t, fiber.StatusNoContent, login(" USER@example.com").StatusCode)
+
+	resp = login("user@example.com")
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "30", resp.Header.Get(fiber.HeaderRetryAfter))
+	assert.Equal(t, "0", resp.Header.Get(ratelimit.HeaderRemaining))
+	assert.Equal(t, apierror.ContentType, resp.Header.Get(fiber.HeaderContentType))
+
+	// The IP bucket still has tokens for other accounts, then runs out
+	assert.Equal(t, fiber.StatusNoContent, login("other@example.com").StatusCode)
+	assert.Equal(t, fiber.StatusNoContent, login("third@example.com").StatusCode)
+	assert.Equal(t, fiber.StatusTooManyRequests, login("fourth@example.com").StatusCode)
+}
+
+func TestByIPBehindProxy(t *testing.T) {
+	// Test requests come from 0.0.0.0
+	tests := []struct {
+		name     string
+		proxies  []string
+		expected string
+	}{
+		{name: "trusted proxy", proxies: []string{"0.0.0.0/8"}, expected: "203.0.113.7"},
+		{name: "untrusted proxy", proxies: []string{"10.0.0.0/8"}, expected: "0.0.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{
+				EnableTrustedProxyCheck: true,
+				TrustedProxies:          tt.proxies,
+				ProxyHeader:             "X-Real-IP",
+				EnableIPValidation:      true,
+			})
+			app.Get("/", func(c *fiber.Ctx) error {
+				return c.SendString(ratelimit.ByIP(c))
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-Real-IP", "203.0.113.7")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tt.expected, string(body))
+		})
+	}
+}
+
+func TestNilLimiterAllowsEverything(t *testing.T) {
+	app := fiber.New()
+	var limiter *ratelimit.Limiter
+	app.Get("/", limiter.Middleware(ratelimit.Rule{Name: "ip", Limit: ratelimit.Limit{Requests: 1, Window: time.Hour}, Key: ratelimit.ByIP}),
+		func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusNoContent)
+		})
+
+	for i := 0; i < 3; i++ {
+		resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		if assert.NoError(t, err) {
+			assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+			assert.Empty(t, resp.Header.Get(ratelimit.HeaderLimit))
+		}
+	}
+}
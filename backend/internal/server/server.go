@@ -0,0 +1,146 @@
+// Package server runs the HTTP app together with its background workers and
+// shuts everything down in order when asked to stop.
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Server owns the lifecycle of a Fiber app. On shutdown it first reports
+// itself as not ready, then waits DrainDelay so load balancers stop routing
+// to it, lets in-flight requests finish within ShutdownTimeout, stops the
+// background workers and waits for them, and finally runs the close hooks.
+type Server struct {
+	App *fiber.App
+	// ShutdownTimeout bounds how long in-flight requests may take to finish
+	// before their connections are closed
+	ShutdownTimeout time.Duration
+	// DrainDelay is how long the server keeps serving while reported as not
+	// ready before it stops accepting connections
+	DrainDelay time.Duration
+	// Logger records the shutdown steps; nil uses slog.Default()
+	Logger *slog.Logger
+
+	draining atomic.Bool
+	workers  sync.WaitGroup
+	mu       sync.Mutex
+	cancel   []context.CancelFunc
+	closers  []func() error
+}
+
+// New returns a server for the app
+func New(app *fiber.App, shutdownTimeout, drainDelay time.Duration) *Server {
+	return &Server{
+		App:             app,
+		ShutdownTimeout: shutdownTimeout,
+		DrainDelay:      drainDelay,
+	}
+}
+
+// Ready reports whether the server accepts new traffic. It turns false as
+// soon as shutdown begins.
+func (s *Server) Ready() bool {
+	return !s.draining.Load()
+}
+
+// Go runs a background worker. Its context is cancelled once the HTTP
+// server has stopped; shutdown waits for the worker to return, so it should
+// finish the job at hand and exit.
+func (s *Server) Go(worker func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.cancel = append(s.cancel, cancel)
+	s.mu.Unlock()
+
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		worker(ctx)
+	}()
+}
+
+// OnClose registers a function run after the workers have stopped, such as
+// closing the database. Hooks run in reverse order of registration.
+func (s *Server) OnClose(fn func() error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closers = append(s.closers, fn)
+}
+
+// Run listens on addr and serves until ctx is done, then shuts down
+func (s *Server) Run(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Join(err, s.stop())
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve serves on ln until ctx is done or the listener fails, then shuts
+// down. The returned error joins everything that went wrong on the way.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	served := make(chan error, 1)
+	go func() {
+		served <- s.App.Listener(ln)
+	}()
+
+	select {
+	case err := <-served:
+		// The listener failed on its own; nothing is left to drain
+		s.draining.Store(true)
+		return errors.Join(err, s.stop())
+	case <-ctx.Done():
+	}
+
+	s.logger().Info("Shutting down", "drain_delay", s.DrainDelay, "shutdown_timeout", s.ShutdownTimeout)
+	s.draining.Store(true)
+	if s.DrainDelay > 0 {
+		time.Sleep(s.DrainDelay)
+	}
+
+	var errs []error
+	if err := s.App.ShutdownWithTimeout(s.ShutdownTimeout); err != nil {
+		errs = append(errs, err)
+	}
+	if err := <-served; err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, s.stop())
+	return errors.Join(errs...)
+}
+
+// stop cancels the workers, waits for them and runs the close hooks
+func (s *Server) stop() error {
+	s.mu.Lock()
+	cancel, closers := s.cancel, s.closers
+	s.cancel, s.closers = nil, nil
+	s.mu.Unlock()
+
+	for _, c := range cancel {
+		c()
+	}
+	s.workers.Wait()
+
+	var errs []error
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Server) logger() *slog.Logger {
+	if s.Logger == nil {
+		return slog.Default()
+	}
+	return s.Logger
+}
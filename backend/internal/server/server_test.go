@@ -0,0 +1,130 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/server"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	app := fiber.New(fiber.Config{DisableStartupMessage: true, IdleTimeout: time.Second})
+	srv := server.New(app, 5*time.Second, 0)
+
+	started := make(chan struct{})
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		return c.SendString("done")
+	})
+
+	// Record the order the worker and close hooks finish in
+	var mu sync.Mutex
+	var events []string
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+	srv.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		record("worker stopped")
+	})
+	srv.OnClose(func() error { record("database closed"); return nil })
+	srv.OnClose(func() error { record("cache closed"); return nil })
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+
+	assert.True(t, srv.Ready())
+
+	type result struct {
+		body string
+		err  error
+	}
+	response := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			response <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		response <- result{string(body), err}
+	}()
+
+	<-started
+	cancel()
+
+	// The in-flight request is drained rather than dropped
+	r := <-response
+	assert.NoError(t, r.err)
+	assert.Equal(t, "done", r.body)
+
+	assert.NoError(t, <-done)
+	assert.False(t, srv.Ready())
+	assert.Equal(t, []string{"worker stopped", "cache closed", "database closed"}, events)
+
+	// New connections are refused once the server has stopped
+	_, err = http.Get("http://" + ln.Addr().String() + "/slow")
+	assert.Error(t, err)
+}
+
+func TestReadyFlipsBeforeDraining(t *testing.T) {
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	srv := server.New(app, time.Second, 300*time.Millisecond)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+
+	cancel()
+	assert.Eventually(t, func() bool { return !srv.Ready() }, time.Second, 10*time.Millisecond)
+
+	// Requests are still served during the drain delay
+	resp, err := http.Get("http://" + ln.Addr().String() + "/")
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	}
+
+	assert.NoError(t, <-done)
+}
+
+func TestListenerFailureStopsWorkers(t *testing.T) {
+	srv := server.New(fiber.New(fiber.Config{DisableStartupMessage: true}), time.Second, 0)
+
+	stopped := make(chan struct{})
+	srv.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		close(stopped)
+	})
+
+	// The address is already taken
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	err = srv.Run(context.Background(), ln.Addr().String())
+	assert.Error(t, err)
+	<-stopped
+}
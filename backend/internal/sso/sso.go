@@ -0,0 +1,217 @@
+// Package sso logs users in through OpenID Connect providers, acting as a
+// relying party in the authorization code flow with PKCE. Providers are
+// discovered from their issuer URL on first use, so a provider that is down
+// at startup only breaks its own logins.
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// requestTimeout bounds each call to a provider
+const requestTimeout = 10 * time.Second
+
+// ErrNonceMismatch is returned when the ID token was not issued for the
+// login it is presented to, as when a token is replayed
+var ErrNonceMismatch = errors.New("ID token nonce does not match")
+
+// Config describes a provider registered for this application
+type Config struct {
+	// Name identifies the provider in URLs and linked identities
+	Name string
+	// DisplayName is shown on the login button
+	DisplayName string
+	// Issuer is the provider's issuer URL, where discovery starts
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// Scopes are requested besides openid; email is needed to provision
+	// accounts
+	Scopes []string
+}
+
+// Claims are what the provider asserts about the user who logged in
+type Claims struct {
+	// Subject identifies the user at the provider and never changes
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// Flow holds the secrets of one login: State ties the callback to the
+// browser that started it, Nonce ties the ID token to the login and
+// Verifier is the PKCE code verifier. They must be kept from the start of
+// the login until the callback, out of reach of other sites.
+type Flow struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// NewFlow returns fresh random secrets for a login
+func NewFlow() (Flow, error) {
+	state, err := randomString()
+	if err != nil {
+		return Flow{}, err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return Flow{}, err
+	}
+	return Flow{State: state, Nonce: nonce, Verifier: oauth2.GenerateVerifier()}, nil
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Provider is an OpenID Connect provider users can log in with
+type Provider struct {
+	Config
+	redirectURL string
+	client      *http.Client
+
+	mu         sync.Mutex
+	discovered *oidc.Provider
+}
+
+// New returns a provider that sends users back to redirectURL, which must
+// be registered with the provider
+func New(cfg Config, redirectURL string) *Provider {
+	return &Provider{
+		Config:      cfg,
+		redirectURL: redirectURL,
+		client:      &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// discover fetches the provider metadata, once it succeeds
+func (p *Provider) discover(ctx context.Context) (*oidc.Provider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovered != nil {
+		return p.discovered, nil
+	}
+
+	discovered, err := oidc.NewProvider(oidc.ClientContext(ctx, p.client), p.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discovering %s: %w", p.Name, err)
+	}
+	p.discovered = discovered
+	return discovered, nil
+}
+
+func (p *Provider) oauth2Config(discovered *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		Endpoint:     discovered.Endpoint(),
+		RedirectURL:  p.redirectURL,
+		Scopes:       append([]string{oidc.ScopeOpenID}, p.Scopes...),
+	}
+}
+
+// AuthCodeURL returns the provider URL the browser is sent to for login
+func (p *Provider) AuthCodeURL(ctx context.Context, flow Flow) (string, error) {
+	discovered, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.oauth2Config(discovered).AuthCodeURL(flow.State,
+		oidc.Nonce(flow.Nonce),
+		oauth2.S256ChallengeOption(flow.Verifier),
+	), nil
+}
+
+// Exchange redeems the authorization code from the callback and returns
+// the claims of the verified ID token. Providers that leave the email out
+// of the ID token are asked for it at their userinfo endpoint.
+func (p *Provider) Exchange(ctx context.Context, code string, flow Flow) (*Claims, error) {
+	discovered, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = oidc.ClientContext(ctx, p.client)
+	token, err := p.oauth2Config(discovered).Exchange(ctx, code, oauth2.VerifierOption(flow.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("exchanging code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("token response has no ID token")
+	}
+	idToken, err := discovered.Verifier(&oidc.Config{ClientID: p.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("verifying ID token: %w", err)
+	}
+	if idToken.Nonce != flow.Nonce {
+		return nil, ErrNonceMismatch
+	}
+	if idToken.Subject == "" {
+		return nil, errors.New("ID token has no subject")
+	}
+
+	var claims struct {
+		Email         string    `json:"email"`
+		EmailVerified claimBool `json:"email_verified"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("decoding ID token claims: %w", err)
+	}
+	result := &Claims{
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+	}
+
+	if result.Email == "" && discovered.UserInfoEndpoint() != "" {
+		info, err := discovered.UserInfo(ctx, oauth2.StaticTokenSource(token))
+		if err != nil {
+			return nil, fmt.Errorf("fetching user info: %w", err)
+		}
+		// The userinfo response is not signed; it only counts for the user
+		// the ID token is about
+		if info.Subject != result.Subject {
+			return nil, errors.New("user info is about another subject")
+		}
+		result.Email = info.Email
+		result.EmailVerified = info.EmailVerified
+	}
+	return result, nil
+}
+
+// claimBool decodes a boolean claim that some providers send as a string
+type claimBool bool
+
+func (b *claimBool) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case bool:
+		*b = claimBool(v)
+	case string:
+		*b = v == "true"
+	default:
+		*b = false
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package sso_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/sso"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+const redirectURL = "http://localhost:8080/api/auth/oidc/test/callback"
+
+func newProvider(mock *testutils.OIDCProvider) *sso.Provider {
+	return sso.New(sso.Config{
+		Name:         "test",
+		Issuer:       mock.Issuer(),
+		ClientID:     mock.ClientID,
+		ClientSecret: mock.ClientSecret,
+		Scopes:       []string{"email"},
+	}, redirectURL)
+}
+
+// authorize logs in at the provider and returns the code it redirected
+// back with
+func authorize(t *testing.T, provider *sso.Provider, flow sso.Flow) string {
+	t.Helper()
+
+	authURL, err := provider.AuthCodeURL(context.Background(), flow)
+	if err != nil {
+		t.Fatalf("Failed to build authorization URL: %v", err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("Failed to authorize: %v", err)
+	}
+	resp.Body.Close()
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("Unexpected authorization response %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	assert.Equal(t, flow.State, location.Query().Get("state"))
+	return location.Query().Get("code")
+}
+
+func TestExchange(t *testing.T) {
+	mock := testutils.NewOIDCProvider(t)
+	mock.SetUser(testutils.OIDCUser{Subject: "abc", Email: "user@example.com", EmailVerified: true})
+	provider := newProvider(mock)
+
+	flow, err := sso.NewFlow()
+	if !assert.NoError(t, err) {
+		return
+	}
+	claims, err := provider.Exchange(context.Background(), authorize(t, provider, flow), flow)
+	assert.NoError(t, err)
+	assert.Equal(t, &sso.Claims{Subject: "abc", Email: "user@example.com", EmailVerified: true}, claims)
+}
+
+func TestExchangeUserInfo(t *testing.T) {
+	mock := testutils.NewOIDCProvider(t)
+	mock.SetUser(testutils.OIDCUser{Subject: "abc", Email: "user@example.com"})
+	mock.EmailInUserInfo()
+	provider := newProvider(mock)
+
+	flow, _ := sso.NewFlow()
+	claims, err := provider.Exchange(context.Background(), authorize(t, provider, flow), flow)
+	assert.NoError(t, err)
+	assert.Equal(t, &sso.Claims{Subject: "abc", Email: "user@example.com"}, claims)
+}
+
+func TestExchangeRejects(t *testing.T) {
+	t.Run("wrong verifier", func(t *testing.T) {
+		provider := newProvider(testutils.NewOIDCProvider(t))
+		flow, _ := sso.NewFlow()
+		code := authorize(t, provider, flow)
+
+		other, _ := sso.NewFlow()
+		flow.Verifier = other.Verifier
+		_, err := provider.Exchange(context.Background(), code, flow)
+		assert.ErrorContains(t, err, "invalid_grant")
+	})
+
+	t.Run("code used twice", func(t *testing.T) {
+		provider := newProvider(testutils.NewOIDCProvider(t))
+		flow, _ := sso.NewFlow()
+		code := authorize(t, provider, flow)
+
+		_, err := provider.Exchange(context.Background(), code, flow)
+		assert.NoError(t, err)
+		_, err = provider.Exchange(context.Background(), code, flow)
+		assert.ErrorContains(t, err, "invalid_grant")
+	})
+
+	t.Run("nonce of another login", func(t *testing.T) {
+		mock := testutils.NewOIDCProvider(t)
+		mock.ReplaceNonce("replayed")
+		provider := newProvider(mock)
+		flow, _ := sso.NewFlow()
+
+		_, err := provider.Exchange(context.Background(), authorize(t, provider, flow), flow)
+		assert.ErrorIs(t, err, sso.ErrNonceMismatch)
+	})
+
+	t.Run("wrong client secret", func(t *testing.T) {
+		mock := testutils.NewOIDCProvider(t)
+		provider := newProvider(mock)
+		provider.ClientSecret = "wrong"
+		flow, _ := sso.NewFlow()
+
+		_, err := provider.Exchange(context.Background(), authorize(t, provider, flow), flow)
+		assert.ErrorContains(t, err, "invalid_client")
+	})
+}
+
+func TestProviderUnavailable(t *testing.T) {
+	mock := testutils.NewOIDCProvider(t)
+	provider := newProvider(mock)
+	mock.Server.Close()
+
+	flow, _ := sso.NewFlow()
+	_, err := provider.AuthCodeURL(context.Background(), flow)
+	assert.ErrorContains(t, err, "discovering test")
+}
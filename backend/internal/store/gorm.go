@@ -0,0 +1,698 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/yourusername/backend/internal/database"
+	"github.com/yourusername/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// exportBatchSize is how many thoughts EachThought loads at a time
+const exportBatchSize = 200
+
+// Gorm stores everything in the SQL database behind a GORM connection
+type Gorm struct {
+	db *gorm.DB
+}
+
+// NewGorm creates a store on top of a migrated database
+func NewGorm(db *gorm.DB) *Gorm {
+	return &Gorm{db: db}
+}
+
+// notFound maps GORM's missing-row error to ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// CreateUser inserts the user. The password is hashed by the model's
+// BeforeCreate hook.
+func (s *Gorm) CreateUser(ctx context.Context, user *models.User) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := emailAvailable(tx, user.Email); err != nil {
+			return err
+		}
+		return tx.Create(user).Error
+	})
+}
+
+// emailAvailable returns ErrEmailTaken if any account uses the email.
+// Unscoped so soft-deleted accounts, which still hold the unique email, count.
+func emailAvailable(tx *gorm.DB, email string) error {
+	var count int64
+	if err := tx.Unscoped().Model(&models.User{}).Where("email = ?", email).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrEmailTaken
+	}
+	return nil
+}
+
+func (s *Gorm) UserByID(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (s *Gorm) UserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (s *Gorm) UserByVerificationToken(ctx context.Context, tokenHash string) (*models.User, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).Where("verification_token = ?", tokenHash).First(&user).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (s *Gorm) SetVerificationToken(ctx context.Context, userID uint, tokenHash string, sentAt time.Time) error {
+	return s.updateUser(ctx, userID, map[string]interface{}{
+		"verification_token":   tokenHash,
+		"verification_sent_at": sentAt,
+	})
+}
+
+func (s *Gorm) MarkEmailVerified(ctx context.Context, userID uint) error {
+	return s.updateUser(ctx, userID, map[string]interface{}{
+		"email_verified":       true,
+		"verification_token":   "",
+		"verification_sent_at": nil,
+	})
+}
+
+func (s *Gorm) ChangeEmail(ctx context.Context, userID uint, email string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := emailAvailable(tx, email); err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"email":                email,
+			"email_verified":       false,
+			"verification_token":   "",
+			"verification_sent_at": nil,
+		}).Error
+	})
+}
+
+func (s *Gorm) ChangePassword(ctx context.Context, userID uint, passwordHash, keepSession string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("password", passwordHash).Error; err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, userID, keepSession)
+	})
+}
+
+func (s *Gorm) ScheduleDeletion(ctx context.Context, userID uint, dueAt time.Time) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("deletion_due_at", dueAt).Error; err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, userID, "")
+	})
+}
+
+func (s *Gorm) CancelDeletion(ctx context.Context, userID uint) error {
+	return s.updateUser(ctx, userID, map[string]interface{}{"deletion_due_at": nil})
+}
+
+func (s *Gorm) DueDeletions(ctx context.Context, now time.Time) ([]uint, error) {
+	var ids []uint
+	err := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("deletion_due_at IS NOT NULL AND deletion_due_at <= ?", now).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// PurgeUser hard-deletes a user and everything they own in one transaction.
+// Unscoped bypasses the gorm.Model soft delete, which would otherwise keep
+// the row and with it the unique email.
+func (s *Gorm) PurgeUser(ctx context.Context, userID uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		thoughtIDs := tx.Model(&models.Thought{}).Select("id").Where("user_id = ?", userID)
+		if err := tx.Exec("DELETE FROM thought_tags WHERE thought_id IN (?)", thoughtIDs).Error; err != nil {
+			return err
+		}
+
+		for _, model := range []interface{}{
+			&models.Thought{},
+			&models.Tag{},
+			&models.Session{},
+			&models.PasswordReset{},
+			&models.Identity{},
+			&models.TwoFactor{},
+			&models.RecoveryCode{},
+		} {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Unscoped().Delete(&models.User{}, userID).Error
+	})
+}
+
+func (s *Gorm) UserByIdentity(ctx context.Context, provider, subject, email string, now time.Time) (*models.User, error) {
+	var user models.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var identity models.Identity
+		if err := tx.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+			return notFound(err)
+		}
+		if err := tx.First(&user, identity.UserID).Error; err != nil {
+			return notFound(err)
+		}
+		return tx.Model(&identity).Updates(map[string]interface{}{
+			"email":         email,
+			"last_login_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *Gorm) LinkIdentity(ctx context.Context, identity *models.Identity) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createIdentity(tx, identity)
+	})
+}
+
+// CreateUserWithIdentity creates the user and the identity together, so a
+// failed link leaves no account behind
+func (s *Gorm) CreateUserWithIdentity(ctx context.Context, user *models.User, identity *models.Identity) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := emailAvailable(tx, user.Email); err != nil {
+			return err
+		}
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return createIdentity(tx, identity)
+	})
+}
+
+// createIdentity inserts the identity unless the provider's subject is
+// linked already. The unique index backs the check up against races.
+func createIdentity(tx *gorm.DB, identity *models.Identity) error {
+	var count int64
+	err := tx.Model(&models.Identity{}).
+		Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrIdentityTaken
+	}
+	return tx.Create(identity).Error
+}
+
+func (s *Gorm) TwoFactor(ctx context.Context, userID uint) (*models.TwoFactor, error) {
+	var twoFactor models.TwoFactor
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).First(&twoFactor).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &twoFactor, nil
+}
+
+func (s *Gorm) SetupTwoFactor(ctx context.Context, userID uint, secret string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing models.TwoFactor
+		err := tx.Where("user_id = ?", userID).First(&existing).Error
+		switch {
+		case err == nil && existing.Enabled():
+			return ErrTwoFactorEnabled
+		case err == nil:
+			if err := tx.Delete(&existing).Error; err != nil {
+				return err
+			}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		return tx.Create(&models.TwoFactor{UserID: userID, Secret: secret}).Error
+	})
+}
+
+func (s *Gorm) EnableTwoFactor(ctx context.Context, userID uint, step int64, codeHashes []string, now time.Time) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.TwoFactor{}).
+			Where("user_id = ? AND confirmed_at IS NULL", userID).
+			Updates(map[string]interface{}{"confirmed_at": now, "last_step": step})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]models.RecoveryCode, len(codeHashes))
+		for i, hash := range codeHashes {
+			codes[i] = models.RecoveryCode{UserID: userID, CodeHash: hash, CreatedAt: now}
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (s *Gorm) DisableTwoFactor(ctx context.Context, userID uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.TwoFactor{}).Error
+	})
+}
+
+// UseTOTPStep moves the last used step forward in a single update, so of
+// two concurrent logins with the same code only one succeeds
+func (s *Gorm) UseTOTPStep(ctx context.Context, userID uint, step int64) error {
+	result := s.db.WithContext(ctx).Model(&models.TwoFactor{}).
+		Where("user_id = ? AND confirmed_at IS NOT NULL AND last_step < ?", userID, step).
+		Update("last_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCodeUsed
+	}
+	return nil
+}
+
+func (s *Gorm) UseRecoveryCode(ctx context.Context, userID uint, codeHash string, now time.Time) error {
+	result := s.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func (s *Gorm) CountRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func (s *Gorm) updateUser(ctx context.Context, userID uint, fields map[string]interface{}) error {
+	return s.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Updates(fields).Error
+}
+
+func (s *Gorm) CreateSession(ctx context.Context, session *models.Session) error {
+	return s.db.WithContext(ctx).Create(session).Error
+}
+
+func (s *Gorm) RotateSession(ctx context.Context, tokenHash string, next *models.Session) error {
+	db := s.db.WithContext(ctx)
+	var reusedFamily string
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var session models.Session
+		if err := tx.Where("token_hash = ?", tokenHash).First(&session).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidToken
+			}
+			return err
+		}
+
+		if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+			return ErrInvalidToken
+		}
+
+		// The conditional update makes concurrent refreshes with the same
+		// token race safely: only one of them can claim it
+		result := tx.Model(&models.Session{}).
+			Where("id = ? AND rotated_at IS NULL", session.ID).
+			Update("rotated_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			reusedFamily = session.FamilyID
+			return ErrTokenReused
+		}
+
+		next.UserID = session.UserID
+		next.FamilyID = session.FamilyID
+		return tx.Create(next).Error
+	})
+
+	if reusedFamily != "" {
+		// Revoke outside the rolled back transaction so it sticks
+		if err := revokeSessionFamily(db, reusedFamily); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func (s *Gorm) SessionActive(ctx context.Context, userID uint, familyID string) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.Session{}).
+		Where("family_id = ? AND user_id = ? AND revoked_at IS NULL", familyID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (s *Gorm) SessionStartedAt(ctx context.Context, userID uint, familyID string) (time.Time, error) {
+	var first models.Session
+	err := s.db.WithContext(ctx).
+		Where("family_id = ? AND user_id = ?", familyID, userID).
+		Order("id").
+		First(&first).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, ErrNotFound
+	}
+	return first.CreatedAt, err
+}
+
+func (s *Gorm) RevokeSessionFamily(ctx context.Context, familyID string) error {
+	return revokeSessionFamily(s.db.WithContext(ctx), familyID)
+}
+
+func (s *Gorm) CountActiveUsers(ctx context.Context, now time.Time) (int64, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.Session{}).
+		Where("revoked_at IS NULL AND rotated_at IS NULL AND expires_at > ?", now).
+		Distinct("user_id").
+		Count(&count).Error
+	return count, err
+}
+
+// revokeSessionFamily revokes every refresh token in a session family
+func revokeSessionFamily(db *gorm.DB, familyID string) error {
+	return db.Model(&models.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// revokeUserSessions revokes every session of a user except keepSession
+func revokeUserSessions(db *gorm.DB, userID uint, keepSession string) error {
+	query := db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+	if keepSession != "" {
+		query = query.Where("family_id <> ?", keepSession)
+	}
+	return query.Update("revoked_at", time.Now()).Error
+}
+
+func (s *Gorm) CreatePasswordReset(ctx context.Context, reset *models.PasswordReset) error {
+	return s.db.WithContext(ctx).Create(reset).Error
+}
+
+func (s *Gorm) CountPasswordResets(ctx context.Context, userID uint, since time.Time) (int64, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.PasswordReset{}).
+		Where("user_id = ? AND created_at > ?", userID, since).
+		Count(&count).Error
+	return count, err
+}
+
+func (s *Gorm) ResetPassword(ctx context.Context, tokenHash, passwordHash string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var reset models.PasswordReset
+		if err := tx.Where("token_hash = ?", tokenHash).First(&reset).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidToken
+			}
+			return err
+		}
+		if time.Now().After(reset.ExpiresAt) {
+			return ErrInvalidToken
+		}
+
+		// Claim the token; a concurrent reset with the same token loses
+		now := time.Now()
+		result := tx.Model(&models.PasswordReset{}).
+			Where("id = ? AND used_at IS NULL", reset.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidToken
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", reset.UserID).Update("password", passwordHash).Error; err != nil {
+			return err
+		}
+
+		// Any other outstanding reset links die with this one
+		if err := tx.Model(&models.PasswordReset{}).
+			Where("user_id = ? AND used_at IS NULL", reset.UserID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		return revokeUserSessions(tx, reset.UserID, "")
+	})
+}
+
+func (s *Gorm) CreateThought(ctx context.Context, thought *models.Thought) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createThought(tx, thought)
+	})
+}
+
+func (s *Gorm) ImportThoughts(ctx context.Context, thoughts []*models.Thought) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, thought := range thoughts {
+			updatedAt := thought.UpdatedAt
+			if err := createThought(tx, thought); err != nil {
+				return err
+			}
+			// Replacing the tags touches updated_at, so put the original back
+			if err := tx.Model(thought).UpdateColumn("updated_at", updatedAt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// createThought inserts the thought and links its tags
+func createThought(tx *gorm.DB, thought *models.Thought) error {
+	tags := thought.Tags
+	if err := tx.Omit("Tags").Create(thought).Error; err != nil {
+		return err
+	}
+	return setThoughtTags(tx, thought, tags)
+}
+
+func (s *Gorm) Thought(ctx context.Context, userID, id uint) (*models.Thought, error) {
+	var thought models.Thought
+	err := s.db.WithContext(ctx).Preload("Tags").Where("id = ? AND user_id = ?", id, userID).First(&thought).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &thought, nil
+}
+
+func (s *Gorm) UpdateThought(ctx context.Context, thought *models.Thought) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tags := thought.Tags
+		if err := tx.Omit("Tags").Save(thought).Error; err != nil {
+			return err
+		}
+		return setThoughtTags(tx, thought, tags)
+	})
+}
+
+func (s *Gorm) DeleteThought(ctx context.Context, thought *models.Thought) error {
+	// Selecting Tags removes the thought_tags join rows along with the thought
+	return s.db.WithContext(ctx).Select("Tags").Delete(thought).Error
+}
+
+// setThoughtTags replaces the thought's tags with the named ones, creating
+// missing tags for the owner as needed
+func setThoughtTags(tx *gorm.DB, thought *models.Thought, named []models.Tag) error {
+	tags := make([]models.Tag, 0, len(named))
+	for _, t := range named {
+		tag := models.Tag{UserID: thought.UserID, Name: t.Name}
+		if err := tx.Where(&tag).FirstOrCreate(&tag).Error; err != nil {
+			return err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := tx.Model(thought).Association("Tags").Replace(tags); err != nil {
+		return err
+	}
+	thought.Tags = tags
+	return nil
+}
+
+func (s *Gorm) ListThoughts(ctx context.Context, userID uint, opts ListOptions) ([]models.Thought, error) {
+	query := s.db.WithContext(ctx).Preload("Tags").Where("thoughts.user_id = ?", userID)
+	if opts.Tag != "" {
+		query = query.Where(
+			"thoughts.id IN (SELECT thought_tags.thought_id FROM thought_tags JOIN tags ON tags.id = thought_tags.tag_id WHERE tags.user_id = ? AND tags.name = ?)",
+			userID, opts.Tag,
+		)
+	}
+	if opts.After != nil {
+		query = query.Where(
+			"thoughts.created_at < ? OR (thoughts.created_at = ? AND thoughts.id < ?)",
+			opts.After.CreatedAt, opts.After.CreatedAt, opts.After.ID,
+		)
+	}
+
+	var thoughts []models.Thought
+	err := query.Order("thoughts.created_at DESC, thoughts.id DESC").Limit(opts.Limit).Find(&thoughts).Error
+	return thoughts, err
+}
+
+func (s *Gorm) EachThought(ctx context.Context, userID uint, fn func(models.Thought) error) error {
+	var batch []models.Thought
+	return s.db.WithContext(ctx).Preload("Tags").Where("user_id = ?", userID).
+		FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, thought := range batch {
+				if err := fn(thought); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// SearchThoughts ranks matches with bm25 when the FTS5 index is available
+// and falls back to unranked LIKE matching otherwise
+func (s *Gorm) SearchThoughts(ctx context.Context, userID uint, terms []SearchTerm, limit int) ([]SearchHit, error) {
+	db := s.db.WithContext(ctx)
+	if database.FullTextSearchEnabled(db) {
+		return searchFTS(db, userID, terms, limit)
+	}
+	return searchLike(db, userID, terms, limit)
+}
+
+// searchRow is the row shape returned by the FTS query
+type searchRow struct {
+	models.Thought
+	Snippet string
+	Rank    float64
+}
+
+// searchFTS ranks matches with bm25 using the FTS5 index
+func searchFTS(db *gorm.DB, userID uint, terms []SearchTerm, limit int) ([]SearchHit, error) {
+	var rows []searchRow
+	err := db.Raw(`
+		SELECT thoughts.*,
+			snippet(thoughts_fts, 0, ?, ?, '…', 16) AS snippet,
+			bm25(thoughts_fts) AS rank
+		FROM thoughts_fts
+		JOIN thoughts ON thoughts.id = thoughts_fts.rowid
+		WHERE thoughts_fts MATCH ? AND thoughts.user_id = ?
+		ORDER BY rank, thoughts.id DESC
+		LIMIT ?`,
+		HighlightStart, HighlightEnd, buildMatchExpression(terms), userID, limit,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]SearchHit, 0, len(rows))
+	ids := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		hits = append(hits, SearchHit{
+			Thought: row.Thought,
+			Snippet: row.Snippet,
+			Rank:    row.Rank,
+		})
+		ids = append(ids, row.ID)
+	}
+	if len(ids) == 0 {
+		return hits, nil
+	}
+
+	// Raw queries cannot preload, so fetch the tags separately
+	var tagged []models.Thought
+	if err := db.Preload("Tags").Select("id").Find(&tagged, ids).Error; err != nil {
+		return nil, err
+	}
+	tags := make(map[uint][]models.Tag, len(tagged))
+	for _, thought := range tagged {
+		tags[thought.ID] = thought.Tags
+	}
+	for i := range hits {
+		hits[i].Thought.Tags = tags[hits[i].Thought.ID]
+	}
+
+	return hits, nil
+}
+
+// searchLike is used when FTS5 is unavailable. Every term must appear in the
+// content; results are unranked and ordered newest first.
+func searchLike(db *gorm.DB, userID uint, terms []SearchTerm, limit int) ([]SearchHit, error) {
+	query := db.Preload("Tags").Where("user_id = ?", userID)
+	for _, term := range terms {
+		query = query.Where("LOWER(content) LIKE ? ESCAPE '!'", "%"+escapeLike(strings.ToLower(term.Text))+"%")
+	}
+
+	var thoughts []models.Thought
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&thoughts).Error; err != nil {
+		return nil, err
+	}
+
+	hits := make([]SearchHit, 0, len(thoughts))
+	for _, thought := range thoughts {
+		hits = append(hits, SearchHit{Thought: thought, Snippet: thought.Content})
+	}
+
+	return hits, nil
+}
+
+// buildMatchExpression renders terms as an FTS5 MATCH expression. Every term
+// is quoted so FTS5 operators typed by users are treated as plain text.
+func buildMatchExpression(terms []SearchTerm) string {
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		part := `"` + strings.ReplaceAll(term.Text, `"`, `""`) + `"`
+		if term.Prefix {
+			part += "*"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+// escapeLike escapes LIKE wildcards in user input. The escape character is
+// ! rather than a backslash, which MySQL treats specially in string literals.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`).Replace(s)
+}
+
+func (s *Gorm) TagCounts(ctx context.Context, userID uint) ([]TagCount, error) {
+	tags := []TagCount{}
+	err := s.db.WithContext(ctx).Table("tags").
+		Select("tags.name AS name, COUNT(thought_tags.thought_id) AS count").
+		Joins("JOIN thought_tags ON thought_tags.tag_id = tags.id").
+		Where("tags.user_id = ?", userID).
+		Group("tags.id, tags.name").
+		Order("count DESC, tags.name ASC").
+		Scan(&tags).Error
+	return tags, err
+}
@@ -0,0 +1,742 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/backend/internal/models"
+)
+
+// Memory keeps everything in process. It behaves like Gorm without full-text
+// ranking and is meant for tests.
+type Memory struct {
+	mu            sync.Mutex
+	nextID        uint
+	users         map[uint]*models.User
+	sessions      []*models.Session
+	resets        []*models.PasswordReset
+	identities    []*models.Identity
+	twoFactors    map[uint]*models.TwoFactor
+	recoveryCodes []*models.RecoveryCode
+	thoughts      map[uint]*models.Thought
+	tags          []*models.Tag
+}
+
+// NewMemory creates an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{
+		users:      make(map[uint]*models.User),
+		twoFactors: make(map[uint]*models.TwoFactor),
+		thoughts:   make(map[uint]*models.Thought),
+	}
+}
+
+// id hands out the next ID. IDs are shared by every record type, which
+// keeps them unique per type as well.
+func (s *Memory) id() uint {
+	s.nextID++
+	return s.nextID
+}
+
+// CreateUser inserts the user, hashing the password like the model's
+// BeforeCreate hook does for Gorm
+func (s *Memory) CreateUser(ctx context.Context, user *models.User) error {
+	if err := hashNewPassword(ctx, user); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.userByEmail(user.Email) != nil {
+		return ErrEmailTaken
+	}
+
+	now := time.Now()
+	user.ID = s.id()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	stored := *user
+	s.users[user.ID] = &stored
+	return nil
+}
+
+func (s *Memory) UserByID(ctx context.Context, id uint) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (s *Memory) UserByEmail(ctx context.Context, email string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user := s.userByEmail(email)
+	if user == nil {
+		return nil, ErrNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (s *Memory) userByEmail(email string) *models.User {
+	for _, user := range s.users {
+		if user.Email == email {
+			return user
+		}
+	}
+	return nil
+}
+
+func (s *Memory) UserByVerificationToken(ctx context.Context, tokenHash string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range s.users {
+		if tokenHash != "" && user.VerificationToken == tokenHash {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *Memory) SetVerificationToken(ctx context.Context, userID uint, tokenHash string, sentAt time.Time) error {
+	return s.updateUser(userID, func(user *models.User) {
+		user.VerificationToken = tokenHash
+		user.VerificationSentAt = &sentAt
+	})
+}
+
+func (s *Memory) MarkEmailVerified(ctx context.Context, userID uint) error {
+	return s.updateUser(userID, func(user *models.User) {
+		user.EmailVerified = true
+		user.VerificationToken = ""
+		user.VerificationSentAt = nil
+	})
+}
+
+func (s *Memory) ChangeEmail(ctx context.Context, userID uint, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.userByEmail(email) != nil {
+		return ErrEmailTaken
+	}
+	return s.updateUserLocked(userID, func(user *models.User) {
+		user.Email = email
+		user.EmailVerified = false
+		user.VerificationToken = ""
+		user.VerificationSentAt = nil
+	})
+}
+
+func (s *Memory) ChangePassword(ctx context.Context, userID uint, passwordHash, keepSession string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.updateUserLocked(userID, func(user *models.User) {
+		user.Password = passwordHash
+	})
+	if err != nil {
+		return err
+	}
+	s.revokeUserSessions(userID, keepSession)
+	return nil
+}
+
+func (s *Memory) ScheduleDeletion(ctx context.Context, userID uint, dueAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.updateUserLocked(userID, func(user *models.User) {
+		user.DeletionDueAt = &dueAt
+	})
+	if err != nil {
+		return err
+	}
+	s.revokeUserSessions(userID, "")
+	return nil
+}
+
+func (s *Memory) CancelDeletion(ctx context.Context, userID uint) error {
+	return s.updateUser(userID, func(user *models.User) {
+		user.DeletionDueAt = nil
+	})
+}
+
+func (s *Memory) DueDeletions(ctx context.Context, now time.Time) ([]uint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []uint
+	for _, user := range s.users {
+		if user.DeletionDueAt != nil && !user.DeletionDueAt.After(now) {
+			ids = append(ids, user.ID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+func (s *Memory) PurgeUser(ctx context.Context, userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, thought := range s.thoughts {
+		if thought.UserID == userID {
+			delete(s.thoughts, id)
+		}
+	}
+	s.tags = filter(s.tags, func(tag *models.Tag) bool { return tag.UserID != userID })
+	s.sessions = filter(s.sessions, func(session *models.Session) bool { return session.UserID != userID })
+	s.resets = filter(s.resets, func(reset *models.PasswordReset) bool { return reset.UserID != userID })
+	s.identities = filter(s.identities, func(identity *models.Identity) bool { return identity.UserID != userID })
+	s.recoveryCodes = filter(s.recoveryCodes, func(code *models.RecoveryCode) bool { return code.UserID != userID })
+	delete(s.twoFactors, userID)
+	delete(s.users, userID)
+	return nil
+}
+
+func (s *Memory) UserByIdentity(ctx context.Context, provider, subject, email string, now time.Time) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, identity := range s.identities {
+		if identity.Provider != provider || identity.Subject != subject {
+			continue
+		}
+		user, ok := s.users[identity.UserID]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		identity.Email = email
+		identity.LastLoginAt = now
+		copied := *user
+		return &copied, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *Memory) LinkIdentity(ctx context.Context, identity *models.Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.linkIdentity(identity)
+}
+
+func (s *Memory) CreateUserWithIdentity(ctx context.Context, user *models.User, identity *models.Identity) error {
+	if err := hashNewPassword(ctx, user); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.userByEmail(user.Email) != nil {
+		return ErrEmailTaken
+	}
+	for _, linked := range s.identities {
+		if linked.Provider == identity.Provider && linked.Subject == identity.Subject {
+			return ErrIdentityTaken
+		}
+	}
+
+	now := time.Now()
+	user.ID = s.id()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	stored := *user
+	s.users[user.ID] = &stored
+	identity.UserID = user.ID
+	return s.linkIdentity(identity)
+}
+
+func (s *Memory) linkIdentity(identity *models.Identity) error {
+	for _, linked := range s.identities {
+		if linked.Provider == identity.Provider && linked.Subject == identity.Subject {
+			return ErrIdentityTaken
+		}
+	}
+	identity.ID = s.id()
+	identity.CreatedAt = time.Now()
+	stored := *identity
+	s.identities = append(s.identities, &stored)
+	return nil
+}
+
+func (s *Memory) TwoFactor(ctx context.Context, userID uint) (*models.TwoFactor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	twoFactor, ok := s.twoFactors[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *twoFactor
+	return &copied, nil
+}
+
+func (s *Memory) SetupTwoFactor(ctx context.Context, userID uint, secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.twoFactors[userID].Enabled() {
+		return ErrTwoFactorEnabled
+	}
+	s.twoFactors[userID] = &models.TwoFactor{UserID: userID, Secret: secret, CreatedAt: time.Now()}
+	return nil
+}
+
+func (s *Memory) EnableTwoFactor(ctx context.Context, userID uint, step int64, codeHashes []string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	twoFactor, ok := s.twoFactors[userID]
+	if !ok || twoFactor.Enabled() {
+		return ErrNotFound
+	}
+	twoFactor.ConfirmedAt = &now
+	twoFactor.LastStep = step
+
+	s.recoveryCodes = filter(s.recoveryCodes, func(code *models.RecoveryCode) bool { return code.UserID != userID })
+	for _, hash := range codeHashes {
+		s.recoveryCodes = append(s.recoveryCodes, &models.RecoveryCode{ID: s.id(), UserID: userID, CodeHash: hash, CreatedAt: now})
+	}
+	return nil
+}
+
+func (s *Memory) DisableTwoFactor(ctx context.Context, userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recoveryCodes = filter(s.recoveryCodes, func(code *models.RecoveryCode) bool { return code.UserID != userID })
+	delete(s.twoFactors, userID)
+	return nil
+}
+
+func (s *Memory) UseTOTPStep(ctx context.Context, userID uint, step int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	twoFactor, ok := s.twoFactors[userID]
+	if !ok || !twoFactor.Enabled() || twoFactor.LastStep >= step {
+		return ErrCodeUsed
+	}
+	twoFactor.LastStep = step
+	return nil
+}
+
+func (s *Memory) UseRecoveryCode(ctx context.Context, userID uint, codeHash string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, code := range s.recoveryCodes {
+		if code.UserID == userID && code.CodeHash == codeHash && code.UsedAt == nil {
+			code.UsedAt = &now
+			return nil
+		}
+	}
+	return ErrInvalidToken
+}
+
+func (s *Memory) CountRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var count int64
+	for _, code := range s.recoveryCodes {
+		if code.UserID == userID && code.UsedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// hashNewPassword hashes a new user's password like the model's BeforeCreate
+// hook does for Gorm, leaving accounts without a password unusable for
+// password logins
+func hashNewPassword(ctx context.Context, user *models.User) error {
+	if !user.HasPassword() {
+		return nil
+	}
+	return user.SetPassword(ctx, user.Password)
+}
+
+// filter keeps the items for which keep returns true
+func filter[T any](items []T, keep func(T) bool) []T {
+	kept := items[:0]
+	for _, item := range items {
+		if keep(item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+func (s *Memory) updateUser(userID uint, update func(*models.User)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.updateUserLocked(userID, update)
+}
+
+func (s *Memory) updateUserLocked(userID uint, update func(*models.User)) error {
+	user, ok := s.users[userID]
+	if !ok {
+		return nil
+	}
+	update(user)
+	user.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *Memory) CreateSession(ctx context.Context, session *models.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.createSession(session)
+	return nil
+}
+
+func (s *Memory) createSession(session *models.Session) {
+	session.ID = s.id()
+	session.CreatedAt = time.Now()
+	stored := *session
+	s.sessions = append(s.sessions, &stored)
+}
+
+func (s *Memory) RotateSession(ctx context.Context, tokenHash string, next *models.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var session *models.Session
+	for _, candidate := range s.sessions {
+		if candidate.TokenHash == tokenHash {
+			session = candidate
+			break
+		}
+	}
+	if session == nil || session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return ErrInvalidToken
+	}
+	if session.RotatedAt != nil {
+		s.revokeSessionFamily(session.FamilyID)
+		return ErrTokenReused
+	}
+
+	now := time.Now()
+	session.RotatedAt = &now
+	next.UserID = session.UserID
+	next.FamilyID = session.FamilyID
+	s.createSession(next)
+	return nil
+}
+
+func (s *Memory) SessionActive(ctx context.Context, userID uint, familyID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, session := range s.sessions {
+		if session.FamilyID == familyID && session.UserID == userID && session.RevokedAt == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Memory) SessionStartedAt(ctx context.Context, userID uint, familyID string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, session := range s.sessions {
+		if session.FamilyID == familyID && session.UserID == userID {
+			return session.CreatedAt, nil
+		}
+	}
+	return time.Time{}, ErrNotFound
+}
+
+func (s *Memory) RevokeSessionFamily(ctx context.Context, familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revokeSessionFamily(familyID)
+	return nil
+}
+
+func (s *Memory) CountActiveUsers(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := map[uint]bool{}
+	for _, session := range s.sessions {
+		if session.RevokedAt == nil && session.RotatedAt == nil && session.ExpiresAt.After(now) {
+			users[session.UserID] = true
+		}
+	}
+	return int64(len(users)), nil
+}
+
+func (s *Memory) revokeSessionFamily(familyID string) {
+	now := time.Now()
+	for _, session := range s.sessions {
+		if session.FamilyID == familyID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+		}
+	}
+}
+
+func (s *Memory) revokeUserSessions(userID uint, keepSession string) {
+	now := time.Now()
+	for _, session := range s.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && (keepSession == "" || session.FamilyID != keepSession) {
+			session.RevokedAt = &now
+		}
+	}
+}
+
+func (s *Memory) CreatePasswordReset(ctx context.Context, reset *models.PasswordReset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reset.ID = s.id()
+	reset.CreatedAt = time.Now()
+	stored := *reset
+	s.resets = append(s.resets, &stored)
+	return nil
+}
+
+func (s *Memory) CountPasswordResets(ctx context.Context, userID uint, since time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var count int64
+	for _, reset := range s.resets {
+		if reset.UserID == userID && reset.CreatedAt.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *Memory) ResetPassword(ctx context.Context, tokenHash, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var reset *models.PasswordReset
+	for _, candidate := range s.resets {
+		if candidate.TokenHash == tokenHash {
+			reset = candidate
+			break
+		}
+	}
+	if reset == nil || reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return ErrInvalidToken
+	}
+
+	err := s.updateUserLocked(reset.UserID, func(user *models.User) {
+		user.Password = passwordHash
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, other := range s.resets {
+		if other.UserID == reset.UserID && other.UsedAt == nil {
+			other.UsedAt = &now
+		}
+	}
+	s.revokeUserSessions(reset.UserID, "")
+	return nil
+}
+
+func (s *Memory) CreateThought(ctx context.Context, thought *models.Thought) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.createThought(thought)
+	return nil
+}
+
+func (s *Memory) ImportThoughts(ctx context.Context, thoughts []*models.Thought) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, thought := range thoughts {
+		s.createThought(thought)
+	}
+	return nil
+}
+
+func (s *Memory) createThought(thought *models.Thought) {
+	now := time.Now()
+	thought.ID = s.id()
+	if thought.CreatedAt.IsZero() {
+		thought.CreatedAt = now
+	}
+	if thought.UpdatedAt.IsZero() {
+		thought.UpdatedAt = now
+	}
+	thought.Tags = s.resolveTags(thought.UserID, thought.Tags)
+	s.thoughts[thought.ID] = copyThought(thought)
+}
+
+// resolveTags looks up the owner's tags by name, creating missing ones
+func (s *Memory) resolveTags(userID uint, named []models.Tag) []models.Tag {
+	tags := make([]models.Tag, 0, len(named))
+	for _, t := range named {
+		var tag *models.Tag
+		for _, existing := range s.tags {
+			if existing.UserID == userID && existing.Name == t.Name {
+				tag = existing
+				break
+			}
+		}
+		if tag == nil {
+			tag = &models.Tag{ID: s.id(), UserID: userID, Name: t.Name, CreatedAt: time.Now()}
+			s.tags = append(s.tags, tag)
+		}
+		tags = append(tags, *tag)
+	}
+	return tags
+}
+
+// copyThought copies the thought so callers cannot change stored tags
+func copyThought(thought *models.Thought) *models.Thought {
+	copied := *thought
+	copied.Tags = append([]models.Tag(nil), thought.Tags...)
+	return &copied
+}
+
+func (s *Memory) Thought(ctx context.Context, userID, id uint) (*models.Thought, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thought, ok := s.thoughts[id]
+	if !ok || thought.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return copyThought(thought), nil
+}
+
+func (s *Memory) UpdateThought(ctx context.Context, thought *models.Thought) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.thoughts[thought.ID]; !ok {
+		return ErrNotFound
+	}
+	thought.UpdatedAt = time.Now()
+	thought.Tags = s.resolveTags(thought.UserID, thought.Tags)
+	s.thoughts[thought.ID] = copyThought(thought)
+	return nil
+}
+
+func (s *Memory) DeleteThought(ctx context.Context, thought *models.Thought) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.thoughts, thought.ID)
+	return nil
+}
+
+func (s *Memory) ListThoughts(ctx context.Context, userID uint, opts ListOptions) ([]models.Thought, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	thoughts := []models.Thought{}
+	for _, thought := range s.newestFirst(userID) {
+		if opts.Tag != "" && !hasTag(thought, opts.Tag) {
+			continue
+		}
+		if after := opts.After; after != nil && !(thought.CreatedAt.Before(after.CreatedAt) ||
+			thought.CreatedAt.Equal(after.CreatedAt) && thought.ID < after.ID) {
+			continue
+		}
+		thoughts = append(thoughts, *copyThought(thought))
+		if opts.Limit > 0 && len(thoughts) == opts.Limit {
+			break
+		}
+	}
+	return thoughts, nil
+}
+
+// newestFirst returns the user's thoughts in (created_at, id) descending order
+func (s *Memory) newestFirst(userID uint) []*models.Thought {
+	var thoughts []*models.Thought
+	for _, thought := range s.thoughts {
+		if thought.UserID == userID {
+			thoughts = append(thoughts, thought)
+		}
+	}
+	sort.Slice(thoughts, func(i, j int) bool {
+		if !thoughts[i].CreatedAt.Equal(thoughts[j].CreatedAt) {
+			return thoughts[i].CreatedAt.After(thoughts[j].CreatedAt)
+		}
+		return thoughts[i].ID > thoughts[j].ID
+	})
+	return thoughts
+}
+
+func hasTag(thought *models.Thought, name string) bool {
+	for _, tag := range thought.Tags {
+		if tag.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// EachThought visits the thoughts in ID order, like the batched Gorm query
+func (s *Memory) EachThought(ctx context.Context, userID uint, fn func(models.Thought) error) error {
+	s.mu.Lock()
+	var thoughts []models.Thought
+	for _, thought := range s.thoughts {
+		if thought.UserID == userID {
+			thoughts = append(thoughts, *copyThought(thought))
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(thoughts, func(i, j int) bool { return thoughts[i].ID < thoughts[j].ID })
+	for _, thought := range thoughts {
+		if err := fn(thought); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SearchThoughts matches terms case-insensitively anywhere in the content.
+// Hits are unranked and ordered newest first.
+func (s *Memory) SearchThoughts(ctx context.Context, userID uint, terms []SearchTerm, limit int) ([]SearchHit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hits := []SearchHit{}
+	for _, thought := range s.newestFirst(userID) {
+		content := strings.ToLower(thought.Content)
+		matched := true
+		for _, term := range terms {
+			if !strings.Contains(content, strings.ToLower(term.Text)) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		hits = append(hits, SearchHit{Thought: *copyThought(thought), Snippet: thought.Content})
+		if len(hits) == limit {
+			break
+		}
+	}
+	return hits, nil
+}
+
+func (s *Memory) TagCounts(ctx context.Context, userID uint) ([]TagCount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int64)
+	for _, thought := range s.thoughts {
+		if thought.UserID != userID {
+			continue
+		}
+		for _, tag := range thought.Tags {
+			counts[tag.Name]++
+		}
+	}
+
+	tags := make([]TagCount, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, TagCount{Name: name, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
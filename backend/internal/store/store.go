@@ -0,0 +1,196 @@
+// Package store persists users, sessions and thoughts. Handlers depend on
+// the UserStore and ThoughtStore interfaces; Gorm implements them on top of
+// the SQL database and Memory keeps everything in process for tests.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yourusername/backend/internal/models"
+)
+
+var (
+	// ErrNotFound is returned when the requested record does not exist
+	ErrNotFound = errors.New("record not found")
+	// ErrEmailTaken is returned when another account already uses the email
+	ErrEmailTaken = errors.New("email already in use")
+	// ErrInvalidToken is returned for unknown, expired, used or revoked
+	// refresh and password reset tokens
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrTokenReused is returned when an already rotated refresh token is
+	// presented again. Its session family has been revoked by then.
+	ErrTokenReused = errors.New("refresh token reused")
+	// ErrIdentityTaken is returned when the external identity is already
+	// linked to an account
+	ErrIdentityTaken = errors.New("identity already linked")
+	// ErrCodeUsed is returned for a TOTP code of a time step that was
+	// already used to log in, or of an earlier one
+	ErrCodeUsed = errors.New("code already used")
+	// ErrTwoFactorEnabled is returned when setting up an authenticator for
+	// a user who has confirmed one already
+	ErrTwoFactorEnabled = errors.New("two-factor authentication already enabled")
+)
+
+// Both stores implement both interfaces
+var (
+	_ UserStore    = (*Gorm)(nil)
+	_ ThoughtStore = (*Gorm)(nil)
+	_ UserStore    = (*Memory)(nil)
+	_ ThoughtStore = (*Memory)(nil)
+)
+
+// UserStore persists accounts together with their login sessions and
+// password reset tokens. Tokens are only ever stored and looked up by hash.
+type UserStore interface {
+	// CreateUser inserts the user, hashing its plain-text Password
+	CreateUser(ctx context.Context, user *models.User) error
+	UserByID(ctx context.Context, id uint) (*models.User, error)
+	UserByEmail(ctx context.Context, email string) (*models.User, error)
+	UserByVerificationToken(ctx context.Context, tokenHash string) (*models.User, error)
+
+	// SetVerificationToken replaces the user's pending verification token
+	SetVerificationToken(ctx context.Context, userID uint, tokenHash string, sentAt time.Time) error
+	// MarkEmailVerified verifies the email and clears the verification token
+	MarkEmailVerified(ctx context.Context, userID uint) error
+	// ChangeEmail moves the account to an unverified new email, dropping
+	// any verification token sent to the old one
+	ChangeEmail(ctx context.Context, userID uint, email string) error
+	// ChangePassword stores a new password hash and revokes every session
+	// of the user except keepSession; an empty keepSession revokes them all
+	ChangePassword(ctx context.Context, userID uint, passwordHash, keepSession string) error
+
+	// ScheduleDeletion marks the account for deletion at dueAt and revokes
+	// its sessions
+	ScheduleDeletion(ctx context.Context, userID uint, dueAt time.Time) error
+	// CancelDeletion restores an account scheduled for deletion
+	CancelDeletion(ctx context.Context, userID uint) error
+	// DueDeletions lists the accounts scheduled for deletion at or before now
+	DueDeletions(ctx context.Context, now time.Time) ([]uint, error)
+	// PurgeUser permanently removes the user and everything they own
+	PurgeUser(ctx context.Context, userID uint) error
+
+	CreateSession(ctx context.Context, session *models.Session) error
+	// RotateSession claims the refresh token with tokenHash and stores next
+	// as its successor in the same family, filling in next's UserID and
+	// FamilyID. Claiming an already rotated token revokes the family and
+	// returns ErrTokenReused.
+	RotateSession(ctx context.Context, tokenHash string, next *models.Session) error
+	// SessionActive reports whether the session family still has an
+	// unrevoked refresh token
+	SessionActive(ctx context.Context, userID uint, familyID string) (bool, error)
+	// SessionStartedAt returns when the login that started the session
+	// family happened; refreshes keep it
+	SessionStartedAt(ctx context.Context, userID uint, familyID string) (time.Time, error)
+	RevokeSessionFamily(ctx context.Context, familyID string) error
+	// CountActiveUsers counts the users holding an unexpired, unrevoked
+	// refresh token at now, i.e. those still logged in somewhere
+	CountActiveUsers(ctx context.Context, now time.Time) (int64, error)
+
+	CreatePasswordReset(ctx context.Context, reset *models.PasswordReset) error
+	// CountPasswordResets counts the reset tokens issued to the user since
+	CountPasswordResets(ctx context.Context, userID uint, since time.Time) (int64, error)
+	// ResetPassword claims the reset token, stores the new password hash,
+	// expires the user's other reset tokens and revokes all their sessions
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string) error
+
+	// UserByIdentity returns the user linked to the provider's subject and
+	// records the login, updating the identity's email
+	UserByIdentity(ctx context.Context, provider, subject, email string, now time.Time) (*models.User, error)
+	// LinkIdentity links the identity to its UserID, returning
+	// ErrIdentityTaken if it is linked already
+	LinkIdentity(ctx context.Context, identity *models.Identity) error
+	// CreateUserWithIdentity creates the user like CreateUser and links the
+	// identity to it in the same transaction
+	CreateUserWithIdentity(ctx context.Context, user *models.User, identity *models.Identity) error
+
+	// TwoFactor returns the user's authenticator, pending or confirmed
+	TwoFactor(ctx context.Context, userID uint) (*models.TwoFactor, error)
+	// SetupTwoFactor stores a pending authenticator with the secret,
+	// replacing any other pending one, or returns ErrTwoFactorEnabled
+	SetupTwoFactor(ctx context.Context, userID uint, secret string) error
+	// EnableTwoFactor confirms the pending authenticator, recording step as
+	// used, and replaces the recovery codes with codeHashes. It returns
+	// ErrNotFound when no authenticator is pending.
+	EnableTwoFactor(ctx context.Context, userID uint, step int64, codeHashes []string, now time.Time) error
+	// DisableTwoFactor removes the authenticator and the recovery codes
+	DisableTwoFactor(ctx context.Context, userID uint) error
+	// UseTOTPStep records a code of the time step as used, returning
+	// ErrCodeUsed unless the step is later than the last one used
+	UseTOTPStep(ctx context.Context, userID uint, step int64) error
+	// UseRecoveryCode claims the unused recovery code with codeHash,
+	// returning ErrInvalidToken when there is none
+	UseRecoveryCode(ctx context.Context, userID uint, codeHash string, now time.Time) error
+	// CountRecoveryCodes counts the user's unused recovery codes
+	CountRecoveryCodes(ctx context.Context, userID uint) (int64, error)
+}
+
+// ThoughtStore persists thoughts and their tags. Tags are given by name on
+// the thought's Tags field; missing tags are created for the owner.
+type ThoughtStore interface {
+	CreateThought(ctx context.Context, thought *models.Thought) error
+	// ImportThoughts creates the thoughts in one transaction, keeping the
+	// CreatedAt and UpdatedAt already set on them
+	ImportThoughts(ctx context.Context, thoughts []*models.Thought) error
+	// Thought returns the user's thought with its tags; thoughts of other
+	// users are reported as ErrNotFound
+	Thought(ctx context.Context, userID, id uint) (*models.Thought, error)
+	// UpdateThought saves the content and replaces the tags
+	UpdateThought(ctx context.Context, thought *models.Thought) error
+	DeleteThought(ctx context.Context, thought *models.Thought) error
+
+	// ListThoughts returns the user's thoughts with tags, newest first
+	ListThoughts(ctx context.Context, userID uint, opts ListOptions) ([]models.Thought, error)
+	// EachThought calls fn for every thought of the user, oldest first,
+	// without loading them all at once
+	EachThought(ctx context.Context, userID uint, fn func(models.Thought) error) error
+	// SearchThoughts returns the user's thoughts containing every term
+	SearchThoughts(ctx context.Context, userID uint, terms []SearchTerm, limit int) ([]SearchHit, error)
+	// TagCounts lists the user's tags in use, most used first
+	TagCounts(ctx context.Context, userID uint) ([]TagCount, error)
+}
+
+// Cursor is a position in the (created_at, id) descending thought order
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// ListOptions filter and page a thought listing
+type ListOptions struct {
+	// Tag restricts the listing to thoughts carrying the tag
+	Tag string
+	// After starts the listing after the thought at the cursor
+	After *Cursor
+	Limit int
+}
+
+// SearchTerm is a single word or quoted phrase from a search query
+type SearchTerm struct {
+	Text   string
+	Prefix bool
+}
+
+// Markers placed around matches in SearchHit snippets. They are control
+// characters so they survive HTML escaping and can be swapped for tags after.
+const (
+	HighlightStart = "\x01"
+	HighlightEnd   = "\x02"
+)
+
+// SearchHit is a thought matching a search
+type SearchHit struct {
+	Thought models.Thought
+	// Snippet is a plain-text excerpt with matches between HighlightStart
+	// and HighlightEnd
+	Snippet string
+	// Rank orders hits by relevance, lower is better; zero when unranked
+	Rank float64
+}
+
+// TagCount is a tag with the number of thoughts carrying it
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
@@ -0,0 +1,450 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/store"
+	"github.com/yourusername/backend/internal/testutils"
+)
+
+// bothStores is implemented by Gorm and Memory
+type bothStores interface {
+	store.UserStore
+	store.ThoughtStore
+}
+
+// forEachStore runs the test against the database store and the memory
+// store, which must behave the same
+func forEachStore(t *testing.T, test func(t *testing.T, s bothStores)) {
+	t.Run("gorm", func(t *testing.T) {
+		test(t, store.NewGorm(testutils.SetupTestDB(t)))
+	})
+	t.Run("memory", func(t *testing.T) {
+		test(t, store.NewMemory())
+	})
+}
+
+func createUser(t *testing.T, s bothStores, email string) *models.User {
+	t.Helper()
+	user := &models.User{Email: email, Password: "password123"}
+	if err := s.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	return user
+}
+
+func tagNames(thought *models.Thought) []string {
+	names := []string{}
+	for _, tag := range thought.Tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func TestUsers(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s bothStores) {
+		ctx := context.Background()
+		user := createUser(t, s, "user@example.com")
+		assert.NotZero(t, user.ID)
+		assert.NoError(t, user.CheckPassword(context.Background(), "password123"), "password is hashed on create")
+
+		err := s.CreateUser(ctx, &models.User{Email: "user@example.com", Password: "password123"})
+		assert.ErrorIs(t, err, store.ErrEmailTaken)
+
+		found, err := s.UserByEmail(ctx, "user@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, user.ID, found.ID)
+
+		_, err = s.UserByID(ctx, user.ID+1000)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		assert.NoError(t, s.SetVerificationToken(ctx, user.ID, "hash", time.Now()))
+		found, err = s.UserByVerificationToken(ctx, "hash")
+		assert.NoError(t, err)
+		assert.Equal(t, user.ID, found.ID)
+
+		assert.NoError(t, s.MarkEmailVerified(ctx, user.ID))
+		found, _ = s.UserByID(ctx, user.ID)
+		assert.True(t, found.EmailVerified)
+		_, err = s.UserByVerificationToken(ctx, "hash")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		createUser(t, s, "taken@example.com")
+		assert.ErrorIs(t, s.ChangeEmail(ctx, user.ID, "taken@example.com"), store.ErrEmailTaken)
+		assert.NoError(t, s.SetVerificationToken(ctx, user.ID, "old", time.Now()))
+		assert.NoError(t, s.ChangeEmail(ctx, user.ID, "new@example.com"))
+		found, _ = s.UserByID(ctx, user.ID)
+		assert.Equal(t, "new@example.com", found.Email)
+		assert.False(t, found.EmailVerified)
+		assert.Nil(t, found.VerificationSentAt)
+		// A link sent to the old address cannot verify the new one
+		_, err = s.UserByVerificationToken(ctx, "old")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}
+
+func TestSessions(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s bothStores) {
+		ctx := context.Background()
+		user := createUser(t, s, "user@example.com")
+
+		session := &models.Session{UserID: user.ID, FamilyID: "family", TokenHash: "first", ExpiresAt: time.Now().Add(time.Hour)}
+		assert.NoError(t, s.CreateSession(ctx, session))
+
+		next := &models.Session{TokenHash: "second", ExpiresAt: time.Now().Add(time.Hour)}
+		assert.NoError(t, s.RotateSession(ctx, "first", next))
+		assert.Equal(t, user.ID, next.UserID)
+		assert.Equal(t, "family", next.FamilyID)
+
+		// Refreshes keep the time the login started the family
+		startedAt, err := s.SessionStartedAt(ctx, user.ID, "family")
+		assert.NoError(t, err)
+		assert.WithinDuration(t, session.CreatedAt, startedAt, time.Millisecond)
+		_, err = s.SessionStartedAt(ctx, user.ID+1, "family")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		err = s.RotateSession(ctx, "unknown", &models.Session{TokenHash: "x", ExpiresAt: time.Now().Add(time.Hour)})
+		assert.ErrorIs(t, err, store.ErrInvalidToken)
+
+		active, err := s.SessionActive(ctx, user.ID, "family")
+		assert.NoError(t, err)
+		assert.True(t, active)
+
+		// Replaying the rotated token revokes the family
+		err = s.RotateSession(ctx, "first", &models.Session{TokenHash: "third", ExpiresAt: time.Now().Add(time.Hour)})
+		assert.ErrorIs(t, err, store.ErrTokenReused)
+		active, _ = s.SessionActive(ctx, user.ID, "family")
+		assert.False(t, active)
+	})
+}
+
+func TestCountActiveUsers(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s bothStores) {
+		ctx := context.Background()
+		now := time.Now()
+		alice := createUser(t, s, "alice@example.com")
+		bob := createUser(t, s, "bob@example.com")
+		carol := createUser(t, s, "carol@example.com")
+
+		// Two devices count once; rotated and expired tokens do not count
+		s.CreateSession(ctx, &models.Session{UserID: alice.ID, FamilyID: "phone", TokenHash: "a1", ExpiresAt: now.Add(time.Hour)})
+		s.CreateSession(ctx, &models.Session{UserID: alice.ID, FamilyID: "laptop", TokenHash: "a2", ExpiresAt: now.Add(time.Hour)})
+		s.CreateSession(ctx, &models.Session{UserID: bob.ID, FamilyID: "bob", TokenHash: "b1", ExpiresAt: now.Add(time.Hour)})
+		s.RotateSession(ctx, "b1", &models.Session{TokenHash: "b2", ExpiresAt: now.Add(time.Hour)})
+		s.CreateSession(ctx, &models.Session{UserID: carol.ID, FamilyID: "carol", TokenHash: "c1", ExpiresAt: now.Add(-time.Minute)})
+
+		count, err := s.CountActiveUsers(ctx, now)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+
+		assert.NoError(t, s.RevokeSessionFamily(ctx, "bob"))
+		count, _ = s.CountActiveUsers(ctx, now)
+		assert.Equal(t, int64(1), count)
+	})
+}
+
+func TestChangePasswordKeepsSession(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s bothStores) {
+		ctx := context.Background()
+		user := createUser(t, s, "user@example.com")
+		for _, family := range []string{"current", "other"} {
+			s.CreateSession(ctx, &models.Session{UserID: user.ID, FamilyID: family, TokenHash: family, ExpiresAt: time.Now().Add(time.Hour)})
+		}
+
+		assert.NoError(t, s.ChangePassword(ctx, user.ID, "new-hash", "current"))
+
+		current, _ := s.SessionActive(ctx, user.ID, "current")
+		other, _ := s.SessionActive(ctx, user.ID, "other")
+		assert.True(t, current)
+		assert.False(t, other)
+		found, _ := s.UserByID(ctx, user.ID)
+		assert.Equal(t, "new-hash", found.Password)
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s bothStores) {
+		ctx := context.Background()
+		user := createUser(t, s, "user@example.com")
+		s.CreateSession(ctx, &models.Session{UserID: user.ID, FamilyID: "family", TokenHash: "refresh", ExpiresAt: time.Now().Add(time.Hour)})
+		for _, hash := range []string{"reset-1", "reset-2"} {
+			s.CreatePasswordReset(ctx, &models.PasswordReset{UserID: user.ID, TokenHash: hash, ExpiresAt: time.Now().Add(time.Hour)})
+		}
+		s.CreatePasswordReset(ctx, &models.PasswordReset{UserID: user.ID, TokenHash: "expired", ExpiresAt: time.Now().Add(-time.Minute)})
+
+		count, err := s.CountPasswordResets(ctx, user.ID, time.Now().Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+
+		assert.ErrorIs(t, s.ResetPassword(ctx, "expired", "new-hash"), store.ErrInvalidToken)
+		assert.NoError(t, s.ResetPassword(ctx, "reset-1", "new-hash"))
+
+		found, _ := s.UserByID(ctx, user.ID)
+		assert.Equal(t, "new-hash", found.Password)
+		active, _ := s.SessionActive(ctx, user.ID, "family")
+		assert.False(t, active)
+
+		// The token is used up, and so is every other outstanding one
+		assert.ErrorIs(t, s.ResetPassword(ctx, "reset-1", "again"), store.ErrInvalidToken)
+		assert.ErrorIs(t, s.ResetPassword(ctx, "reset-2", "again"), store.ErrInvalidToken)
+	})
+}
+
+func TestIdentities(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s bothStores) {
+		ctx := context.Background()
+		now := time.Now()
+
+		_, err := s.UserByIdentity(ctx, "google", "123", "new@example.com", now)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		user := &models.User{Email: "new@example.com", Password: "random-password", EmailVerified: true}
+		identity := &models.Identity{Provider: "google", Subject: "123", Email: "new@example.com", LastLoginAt: now}
+		assert.NoError(t, s.CreateUserWithIdentity(ctx, user, identity))
+		assert.NotZero(t, user.ID)
+		assert.Equal(t, user.ID, identity.UserID)
+
+		found, err := s.UserByIdentity(ctx, "google", "123", "changed@example.com", now)
+		if assert.NoError(t, err) {
+			assert.Equal(t, user.ID, found.ID)
+			assert.True(t, found.EmailVerified)
+		}
+
+		// Subjects are unique per provider only
+		_, err = s.UserByIdentity(ctx, "gitlab", "123", "new@example.com", now)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		assert.NoError(t, s.LinkIdentity(ctx, &models.Identity{UserID: user.ID, Provider: "gitlab", Subject: "123", LastLoginAt: now}))
+		err = s.LinkIdentity(ctx, &models.Identity{UserID: user.ID, Provider: "gitlab", Subject: "123", LastLoginAt: now})
+		assert.ErrorIs(t, err, store.ErrIdentityTaken)
+
+		// A failed link creates no account
+		err = s.CreateUserWithIdentity(ctx, &models.User{Email: "other@example.com", Password: "random-password"},
+			&models.Identity{Provider: "google", Subject: "123", LastLoginAt: now})
+		assert.ErrorIs(t, err, store.ErrIdentityTaken)
+		_, err = s.UserByEmail(ctx, "other@example.com")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		err = s.CreateUserWithIdentity(ctx, &models.User{Email: "new@example.com", Password: "random-password"},
+			&models.Identity{Provider: "google", Subject: "456", LastLoginAt: now})
+		assert.ErrorIs(t, err, store.ErrEmailTaken)
+
+		// Purged accounts take their identities with them
+		assert.NoError(t, s.PurgeUser(ctx, user.ID))
+		_, err = s.UserByIdentity(ctx, "google", "123", "new@example.com", now)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}
+
+func TestTwoFactor(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s bothStores) {
+		ctx := context.Background()
+		now := time.Now()
+		user := createUser(t, s, "user@example.com")
+
+		_, err := s.TwoFactor(ctx, user.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		assert.ErrorIs(t, s.EnableTwoFactor(ctx, user.ID, 1, nil, now), store.ErrNotFound)
+
+		// A new setup replaces a pending one
+		assert.NoError(t, s.SetupTwoFactor(ctx, user.ID, "FIRST"))
+		assert.NoError(t, s.SetupTwoFactor(ctx, user.ID, "SECOND"))
+		pending, err := s.TwoFactor(ctx, user.ID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "SECOND", pending.Secret)
+			assert.False(t, pending.Enabled())
+		}
+		assert.ErrorIs(t, s.UseTOTPStep(ctx, user.ID, 200), store.ErrCodeUsed)
+
+		assert.NoError(t, s.EnableTwoFactor(ctx, user.ID, 100, []string{"hash-1", "hash-2"}, now))
+		enabled, err := s.TwoFactor(ctx, user.ID)
+		if assert.NoError(t, err) {
+			assert.True(t, enabled.Enabled())
+			assert.Equal(t, int64(100), enabled.LastStep)
+		}
+		assert.ErrorIs(t, s.SetupTwoFactor(ctx, user.ID, "THIRD"), store.ErrTwoFactorEnabled)
+
+		// Steps only move forward
+		assert.ErrorIs(t, s.UseTOTPStep(ctx, user.ID, 100), store.ErrCodeUsed)
+		assert.ErrorIs(t, s.UseTOTPStep(ctx, user.ID, 99), store.ErrCodeUsed)
+		assert.NoError(t, s.UseTOTPStep(ctx, user.ID, 101))
+
+		// Recovery codes work once
+		count, err := s.CountRecoveryCodes(ctx, user.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+		assert.NoError(t, s.UseRecoveryCode(ctx, user.ID, "hash-1", now))
+		assert.ErrorIs(t, s.UseRecoveryCode(ctx, user.ID, "hash-1", now), store.ErrInvalidToken)
+		assert.ErrorIs(t, s.UseRecoveryCode(ctx, user.ID, "unknown", now), store.ErrInvalidToken)
+		count, _ = s.CountRecoveryCodes(ctx, user.ID)
+		assert.Equal(t, int64(1), count)
+
+		// Codes belong to their user
+		other := createUser(t, s, "other@example.com")
+		assert.ErrorIs(t, s.UseRecoveryCode(ctx, other.ID, "hash-2", now), store.ErrInvalidToken)
+
+		assert.NoError(t, s.DisableTwoFactor(ctx, user.ID))
+		_, err = s.TwoFactor(ctx, user.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		count, _ = s.CountRecoveryCodes(ctx, user.ID)
+		assert.Zero(t, count)
+
+		// Purged accounts take their authenticator with them
+		assert.NoError(t, s.SetupTwoFactor(ctx, user.ID, "SECRET"))
+		assert.NoError(t, s.EnableTwoFactor(ctx, user.ID, 1, []string{"hash-3"}, now))
+		assert.NoError(t, s.PurgeUser(ctx, user.ID))
+		_, err = s.TwoFactor(ctx, user.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		count, _ = s.CountRecoveryCodes(ctx, user.ID)
+		assert.Zero(t, count)
+	})
+}
+
+func TestThoughts(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s bothStores) {
+		ctx := context.Background()
+		user := createUser(t, s, "user@example.com")
+		other := createUser(t, s, "other@example.com")
+
+		thought := &models.Thought{UserID: user.ID, Content: "first", Tags: []models.Tag{{Name: "a"}, {Name: "b"}}}
+		assert.NoError(t, s.CreateThought(ctx, thought))
+		assert.NotZero(t, thought.ID)
+		assert.ElementsMatch(t, []string{"a", "b"}, tagNames(thought))
+
+		found, err := s.Thought(ctx, user.ID, thought.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "first", found.Content)
+		assert.ElementsMatch(t, []string{"a", "b"}, tagNames(found))
+
+		_, err = s.Thought(ctx, other.ID, thought.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		found.Content = "edited"
+		found.Tags = []models.Tag{{Name: "b"}, {Name: "c"}}
+		assert.NoError(t, s.UpdateThought(ctx, found))
+		found, _ = s.Thought(ctx, user.ID, thought.ID)
+		assert.Equal(t, "edited", found.Content)
+		assert.ElementsMatch(t, []string{"b", "c"}, tagNames(found))
+
+		second := &models.Thought{UserID: user.ID, Content: "second", Tags: []models.Tag{{Name: "c"}}}
+		assert.NoError(t, s.CreateThought(ctx, second))
+
+		tags, err := s.TagCounts(ctx, user.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, []store.TagCount{{Name: "c", Count: 2}, {Name: "b", Count: 1}}, tags)
+
+		assert.NoError(t, s.DeleteThought(ctx, found))
+		_, err = s.Thought(ctx, user.ID, thought.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}
+
+func TestListThoughts(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s bothStores) {
+		ctx := context.Background()
+		user := createUser(t, s, "user@example.com")
+
+		base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		var created []*models.Thought
+		for i := 0; i < 4; i++ {
+			at := base.Add(time.Duration(i) * time.Hour)
+			thought := &models.Thought{UserID: user.ID, Content: "thought", CreatedAt: at, UpdatedAt: at}
+			if i%2 == 0 {
+				thought.Tags = []models.Tag{{Name: "even"}}
+			}
+			created = append(created, thought)
+		}
+		assert.NoError(t, s.ImportThoughts(ctx, created))
+
+		page, err := s.ListThoughts(ctx, user.ID, store.ListOptions{Limit: 2})
+		assert.NoError(t, err)
+		if assert.Len(t, page, 2) {
+			assert.Equal(t, created[3].ID, page[0].ID)
+			assert.Equal(t, created[2].ID, page[1].ID)
+		}
+
+		page, err = s.ListThoughts(ctx, user.ID, store.ListOptions{
+			Limit: 10,
+			After: &store.Cursor{CreatedAt: page[1].CreatedAt, ID: page[1].ID},
+		})
+		assert.NoError(t, err)
+		if assert.Len(t, page, 2) {
+			assert.Equal(t, created[1].ID, page[0].ID)
+			assert.Equal(t, created[0].ID, page[1].ID)
+		}
+
+		page, err = s.ListThoughts(ctx, user.ID, store.ListOptions{Limit: 10, Tag: "even"})
+		assert.NoError(t, err)
+		assert.Len(t, page, 2)
+
+		var visited []uint
+		err = s.EachThought(ctx, user.ID, func(thought models.Thought) error {
+			visited = append(visited, thought.ID)
+			assert.True(t, thought.UpdatedAt.Equal(thought.CreatedAt), "imported timestamps are kept")
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{created[0].ID, created[1].ID, created[2].ID, created[3].ID}, visited)
+	})
+}
+
+func TestSearchThoughts(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s bothStores) {
+		ctx := context.Background()
+		user := createUser(t, s, "user@example.com")
+		for _, content := range []string{"Coffee with Sam", "Tea with Alex", "coffee beans"} {
+			s.CreateThought(ctx, &models.Thought{UserID: user.ID, Content: content})
+		}
+
+		hits, err := s.SearchThoughts(ctx, user.ID, []store.SearchTerm{{Text: "coffee"}}, 10)
+		assert.NoError(t, err)
+		assert.Len(t, hits, 2)
+
+		hits, err = s.SearchThoughts(ctx, user.ID, []store.SearchTerm{{Text: "coffee"}, {Text: "sam"}}, 10)
+		assert.NoError(t, err)
+		if assert.Len(t, hits, 1) {
+			assert.Equal(t, "Coffee with Sam", hits[0].Thought.Content)
+		}
+	})
+}
+
+func TestAccountDeletion(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s bothStores) {
+		ctx := context.Background()
+		user := createUser(t, s, "user@example.com")
+		keep := createUser(t, s, "keep@example.com")
+		s.CreateSession(ctx, &models.Session{UserID: user.ID, FamilyID: "family", TokenHash: "refresh", ExpiresAt: time.Now().Add(time.Hour)})
+		s.CreateThought(ctx, &models.Thought{UserID: user.ID, Content: "mine", Tags: []models.Tag{{Name: "a"}}})
+		s.CreateThought(ctx, &models.Thought{UserID: keep.ID, Content: "theirs", Tags: []models.Tag{{Name: "a"}}})
+
+		dueAt := time.Now().Add(time.Hour)
+		assert.NoError(t, s.ScheduleDeletion(ctx, user.ID, dueAt))
+		active, _ := s.SessionActive(ctx, user.ID, "family")
+		assert.False(t, active)
+
+		due, err := s.DueDeletions(ctx, time.Now())
+		assert.NoError(t, err)
+		assert.Empty(t, due)
+		due, _ = s.DueDeletions(ctx, dueAt.Add(time.Minute))
+		assert.Equal(t, []uint{user.ID}, due)
+
+		assert.NoError(t, s.CancelDeletion(ctx, user.ID))
+		due, _ = s.DueDeletions(ctx, dueAt.Add(time.Minute))
+		assert.Empty(t, due)
+
+		assert.NoError(t, s.PurgeUser(ctx, user.ID))
+		_, err = s.UserByID(ctx, user.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		page, _ := s.ListThoughts(ctx, user.ID, store.ListOptions{Limit: 10})
+		assert.Empty(t, page)
+
+		// The email can be registered again
+		createUser(t, s, "user@example.com")
+
+		tags, _ := s.TagCounts(ctx, keep.ID)
+		assert.Equal(t, []store.TagCount{{Name: "a", Count: 1}}, tags)
+	})
+}
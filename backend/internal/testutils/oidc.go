@@ -0,0 +1,262 @@
+package testutils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDCUser is the user logging in at an OIDCProvider
+type OIDCUser struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// OIDCProvider is a local OpenID Connect provider for login tests. Every
+// authorization request logs in the current user at once and redirects with a
+// code, which the token endpoint redeems once for an ID token, checking the
+// client, the redirect URL and the PKCE verifier like a real provider.
+type OIDCProvider struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	mu   sync.Mutex
+	user OIDCUser
+	// emailInUserInfo leaves the email out of ID tokens
+	emailInUserInfo bool
+	// nonce, when set, replaces the nonce of the login in ID tokens
+	nonce  string
+	key    *rsa.PrivateKey
+	codes  map[string]oidcGrant
+	tokens map[string]OIDCUser
+}
+
+type oidcGrant struct {
+	user          OIDCUser
+	nonce         string
+	redirectURI   string
+	codeChallenge string
+}
+
+// NewOIDCProvider starts a provider logging in a verified user, stopped when
+// the test ends
+func NewOIDCProvider(t *testing.T) *OIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate provider key: %v", err)
+	}
+	p := &OIDCProvider{
+		ClientID:     "thoughts-test",
+		ClientSecret: "client-secret",
+		user:         OIDCUser{Subject: "provider-user-1", Email: "sso@example.com", EmailVerified: true},
+		key:          key,
+		codes:        make(map[string]oidcGrant),
+		tokens:       make(map[string]OIDCUser),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/userinfo", p.userInfo)
+	mux.HandleFunc("/jwks", p.jwks)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Server.Close)
+	return p
+}
+
+// Issuer is the provider's issuer URL
+func (p *OIDCProvider) Issuer() string {
+	return p.Server.URL
+}
+
+// SetUser changes the user logging in
+func (p *OIDCProvider) SetUser(user OIDCUser) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.user = user
+}
+
+// EmailInUserInfo makes the provider share the email only at its userinfo
+// endpoint, as some providers do
+func (p *OIDCProvider) EmailInUserInfo() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.emailInUserInfo = true
+}
+
+// ReplaceNonce makes the provider issue ID tokens with the given nonce
+// instead of the one of the login, as a replayed token would have
+func (p *OIDCProvider) ReplaceNonce(nonce string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nonce = nonce
+}
+
+func (p *OIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.Issuer(),
+		"authorization_endpoint":                p.Issuer() + "/authorize",
+		"token_endpoint":                        p.Issuer() + "/token",
+		"userinfo_endpoint":                     p.Issuer() + "/userinfo",
+		"jwks_uri":                              p.Issuer() + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *OIDCProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("client_id") != p.ClientID || query.Get("response_type") != "code" ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code := randomToken()
+	p.mu.Lock()
+	p.codes[code] = oidcGrant{
+		user:          p.user,
+		nonce:         query.Get("nonce"),
+		redirectURI:   redirectURI.String(),
+		codeChallenge: query.Get("code_challenge"),
+	}
+	p.mu.Unlock()
+
+	values := redirectURI.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirectURI.RawQuery = values.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (p *OIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != p.ClientID || clientSecret != p.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mu.Lock()
+	code := r.PostForm.Get("code")
+	grant, ok := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("redirect_uri") != grant.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(challenge[:]) != grant.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken, err := p.idToken(grant)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	accessToken := randomToken()
+	p.mu.Lock()
+	p.tokens[accessToken] = grant.user
+	p.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func (p *OIDCProvider) idToken(grant oidcGrant) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   p.Issuer(),
+		"aud":   p.ClientID,
+		"sub":   grant.user.Subject,
+		"nonce": grant.nonce,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+	}
+	if p.nonce != "" {
+		claims["nonce"] = p.nonce
+	}
+	if !p.emailInUserInfo {
+		claims["email"] = grant.user.Email
+		claims["email_verified"] = grant.user.EmailVerified
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+	return token.SignedString(p.key)
+}
+
+func (p *OIDCProvider) userInfo(w http.ResponseWriter, r *http.Request) {
+	accessToken, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	p.mu.Lock()
+	user, ok := p.tokens[accessToken]
+	p.mu.Unlock()
+	if !found || !ok {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sub":            user.Subject,
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
+	})
+}
+
+func (p *OIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	public := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": "test",
+			"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}},
+	})
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -1,24 +1,71 @@
 package testutils
 
 import (
+	"io"
+	"log/slog"
+	"os"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/yourusername/backend/internal/api"
+	"github.com/yourusername/backend/internal/apierror"
+	"github.com/yourusername/backend/internal/auth"
 	"github.com/yourusername/backend/internal/database"
+	"github.com/yourusername/backend/internal/health"
+	"github.com/yourusername/backend/internal/mail"
+	"github.com/yourusername/backend/internal/store"
 	"gorm.io/gorm"
 )
 
-// SetupTestDB initializes a test database with migrations
+// Keys signs the access tokens of test apps. Tests forging their own tokens
+// sign them with it.
+var Keys = mustKeyRing()
+
+func mustKeyRing() *auth.KeyRing {
+	key, err := auth.GenerateKey()
+	if err != nil {
+		panic(err)
+	}
+	ring, err := auth.NewKeyRing("http://localhost:8080", "thoughts", key)
+	if err != nil {
+		panic(err)
+	}
+	return ring
+}
+
+// SetupTestDB initializes a test database with migrations. Tests run
+// against the SQLite file thoughts.db unless TEST_DATABASE_URL names another
+// database, e.g. postgres://localhost/thoughts_test. Every table is dropped,
+// so never point it at a database holding real data, and run packages one
+// at a time (go test -p 1) when they share a server.
 func SetupTestDB(t *testing.T) *gorm.DB {
-	db, err := database.Connect()
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		databaseURL = "sqlite://thoughts.db"
+	}
+
+	db, err := database.Open(databaseURL)
 	if err != nil {
 		t.Fatalf("Failed to connect to test database: %v", err)
 	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
 
 	// Clear all tables
+	db.Exec("DROP TABLE IF EXISTS recovery_codes")
+	db.Exec("DROP TABLE IF EXISTS two_factors")
+	db.Exec("DROP TABLE IF EXISTS identities")
+	db.Exec("DROP TABLE IF EXISTS password_resets")
+	db.Exec("DROP TABLE IF EXISTS sessions")
+	db.Exec("DROP TABLE IF EXISTS thoughts_fts")
+	db.Exec("DROP TABLE IF EXISTS thought_tags")
+	db.Exec("DROP TABLE IF EXISTS tags")
 	db.Exec("DROP TABLE IF EXISTS thoughts")
 	db.Exec("DROP TABLE IF EXISTS users")
+	db.Exec("DROP TABLE IF EXISTS schema_migrations")
 
 	// Re-run migrations
 	if err := database.Migrate(db); err != nil {
@@ -30,7 +77,56 @@ func SetupTestDB(t *testing.T) *gorm.DB {
 
 // SetupTestApp initializes a test Fiber app with routes
 func SetupTestApp(t *testing.T, db *gorm.DB) *fiber.App {
-	app := fiber.New()
-	api.SetupRoutes(app, db)
+	return SetupTestAppWithServices(t, db, api.Services{})
+}
+
+// SetupTestAppWithServices initializes a test Fiber app with the given
+// services. Missing stores and health checks are backed by db and a missing
+// mailer is replaced by an in-memory one.
+func SetupTestAppWithServices(t *testing.T, db *gorm.DB, svc api.Services) *fiber.App {
+	if svc.Health == nil && db != nil {
+		svc.Health = health.NewChecker(health.Database(db), health.Migrations(db))
+	}
+	if svc.Users == nil || svc.Thoughts == nil {
+		gormStore := store.NewGorm(db)
+		if svc.Users == nil {
+			svc.Users = gormStore
+		}
+		if svc.Thoughts == nil {
+			svc.Thoughts = gormStore
+		}
+	}
+	return setupApp(svc)
+}
+
+// SetupMemoryApp initializes a test Fiber app backed by an in-memory store,
+// for handler tests that need no database. The store is returned so tests
+// can seed and inspect it.
+func SetupMemoryApp(t *testing.T) (*fiber.App, *store.Memory) {
+	memory := store.NewMemory()
+	return setupApp(api.Services{Users: memory, Thoughts: memory}), memory
+}
+
+// setupApp fills in test defaults for the services and registers the routes
+func setupApp(svc api.Services) *fiber.App {
+	if svc.Mailer == nil {
+		svc.Mailer = mail.NewMemoryMailer()
+	}
+	if svc.Keys == nil {
+		svc.Keys = Keys
+	}
+	if svc.BaseURL == "" {
+		svc.BaseURL = "http://localhost:8080"
+	}
+	if svc.AppURL == "" {
+		svc.AppURL = "http://localhost:3000"
+	}
+	if svc.Logger == nil {
+		// Keep request logs out of the test output
+		svc.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: apierror.Handler})
+	api.SetupRoutes(app, svc)
 	return app
 }
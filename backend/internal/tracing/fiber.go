@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// headerCarrier reads propagation headers from a Fiber request
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+func (h headerCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+// Set is unused, spans are only extracted from requests
+func (h headerCarrier) Set(key, value string) {}
+
+func (h headerCarrier) Keys() []string {
+	var keys []string
+	h.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
+
+var _ propagation.TextMapCarrier = headerCarrier{}
+
+// Middleware starts a server span for every request, continuing the trace
+// of an incoming traceparent header. Handlers reach the span through
+// c.UserContext(), so spans they start become its children.
+func Middleware() fiber.Handler {
+	tracer := otel.Tracer(instrumentationName)
+
+	return func(c *fiber.Ctx) error {
+		self := c.Route()
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headerCarrier{c})
+
+		// Fiber's strings point into buffers reused by later requests, so
+		// anything kept on the span is copied
+		method := utils.CopyString(c.Method())
+		ctx, span := tracer.Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(method),
+				semconv.URLPath(utils.CopyString(c.Path())),
+				semconv.UserAgentOriginal(utils.CopyString(c.Get(fiber.HeaderUserAgent))),
+			),
+		)
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		// Let the app's error handler write the response now, so the span
+		// records the status the client gets
+		err := c.Next()
+		if err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+		status := c.Response().StatusCode()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+
+		// Name the span after the route pattern rather than the raw path,
+		// which would make every thought ID its own operation
+		if route := c.Route(); route != self {
+			span.SetName(method + " " + route.Path)
+			span.SetAttributes(semconv.HTTPRoute(route.Path))
+		}
+
+		// Client errors are the caller's problem, not a failed operation
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, "")
+			if err != nil {
+				span.RecordError(err)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey stores the query span on the statement
+const spanKey = "tracing:span"
+
+// gormPlugin starts a span for every query GORM runs
+type gormPlugin struct {
+	tracer trace.Tracer
+}
+
+// GormPlugin returns a GORM plugin tracing every query as a child of the
+// span in the statement context. Register it with db.Use; queries only join
+// the request trace when run with db.WithContext.
+func GormPlugin() gorm.Plugin {
+	return gormPlugin{tracer: otel.Tracer(instrumentationName)}
+}
+
+func (gormPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize wraps each of GORM's callback chains in a span
+func (p gormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	chains := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("*").Register, callbacks.Create().After("*").Register},
+		{"query", callbacks.Query().Before("*").Register, callbacks.Query().After("*").Register},
+		{"update", callbacks.Update().Before("*").Register, callbacks.Update().After("*").Register},
+		{"delete", callbacks.Delete().Before("*").Register, callbacks.Delete().After("*").Register},
+		{"row", callbacks.Row().Before("*").Register, callbacks.Row().After("*").Register},
+		{"raw", callbacks.Raw().Before("*").Register, callbacks.Raw().After("*").Register},
+	}
+
+	for _, chain := range chains {
+		if err := chain.before("tracing:before_"+chain.operation, p.startSpan(chain.operation)); err != nil {
+			return err
+		}
+		if err := chain.after("tracing:after_"+chain.operation, endSpan(chain.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p gormPlugin) startSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		// Queries outside a traced operation, such as migrations and the
+		// purge job, would each start a trace of their own
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			return
+		}
+
+		_, span := p.tracer.Start(ctx, "db."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBOperationName(operation),
+				semconv.DBSystemKey.String(db.Dialector.Name()),
+			),
+		)
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func endSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(spanKey)
+		span, isSpan := value.(trace.Span)
+		if !ok || !isSpan {
+			return
+		}
+		defer span.End()
+
+		if table := db.Statement.Table; table != "" {
+			span.SetName("db." + operation + " " + table)
+			span.SetAttributes(semconv.DBCollectionName(table))
+		}
+		// The statement has placeholders, never the bound values
+		span.SetAttributes(
+			semconv.DBQueryText(db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+		)
+
+		if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/backend/internal/config"
+	"github.com/yourusername/backend/internal/models"
+	"github.com/yourusername/backend/internal/testutils"
+	"github.com/yourusername/backend/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider that keeps finished spans in memory
+// for the duration of the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+// spanNamed returns the finished span with the given name
+func spanNamed(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	var names []string
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+		names = append(names, span.Name())
+	}
+	t.Fatalf("no span named %q, got %v", name, names)
+	return nil
+}
+
+func TestMiddlewareContinuesTrace(t *testing.T) {
+	recorder := recordSpans(t)
+
+	app := fiber.New()
+	app.Use(tracing.Middleware())
+	app.Get("/thoughts/:id", func(c *fiber.Ctx) error {
+		_, span := otel.Tracer("test").Start(c.UserContext(), "handler")
+		span.End()
+		return c.SendString(c.Params("id"))
+	})
+	app.Get("/boom", func(c *fiber.Ctx) error {
+		return fiber.ErrInternalServerError
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest("GET", "/thoughts/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	app.Test(req)
+	app.Test(httptest.NewRequest("GET", "/boom", nil))
+	app.Test(httptest.NewRequest("GET", "/no/such/page", nil))
+
+	server := spanNamed(t, recorder, "GET /thoughts/:id")
+	assert.Equal(t, traceID, server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.True(t, server.Parent().IsRemote())
+	assert.Contains(t, server.Attributes(), attribute.Int("http.response.status_code", 200))
+
+	handler := spanNamed(t, recorder, "handler")
+	assert.Equal(t, server.SpanContext().SpanID(), handler.Parent().SpanID())
+
+	assert.Equal(t, codes.Error, spanNamed(t, recorder, "GET /boom").Status().Code)
+	// Unmatched paths keep the bare method so they cannot add span names
+	unmatched := spanNamed(t, recorder, "GET")
+	assert.Contains(t, unmatched.Attributes(), attribute.Int("http.response.status_code", 404))
+	assert.Equal(t, codes.Unset, unmatched.Status().Code)
+}
+
+func TestGormPlugin(t *testing.T) {
+	recorder := recordSpans(t)
+	db := testutils.SetupTestDB(t)
+	if err := db.Use(tracing.GormPlugin()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Queries without a trace to join are not traced
+	db.Where("email = ?", "nobody@example.com").First(&models.User{})
+	assert.Empty(t, recorder.Ended())
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	user := models.User{Email: "tracing@example.com", Password: "password123"}
+	assert.NoError(t, db.WithContext(ctx).Create(&user).Error)
+	parent.End()
+
+	create := spanNamed(t, recorder, "db.create users")
+	assert.Equal(t, parent.SpanContext().SpanID(), create.Parent().SpanID())
+	assert.Contains(t, create.Attributes(), attribute.String("db.collection.name", "users"))
+	for _, kv := range create.Attributes() {
+		if kv.Key == "db.query.text" {
+			assert.Contains(t, kv.Value.AsString(), "INSERT INTO")
+			assert.NotContains(t, kv.Value.AsString(), "tracing@example.com", "values are not recorded")
+		}
+	}
+
+	hash := spanNamed(t, recorder, "bcrypt.hash")
+	assert.Equal(t, parent.SpanContext().TraceID(), hash.SpanContext().TraceID())
+}
+
+func TestSetupFileExporter(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := tracing.Setup(context.Background(), config.Tracing{
+		Exporter:    "file",
+		File:        path,
+		ServiceName: "thoughts-test",
+		SampleRatio: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "exported")
+	span.End()
+	assert.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"exported"`)
+	assert.Contains(t, string(data), "thoughts-test")
+}
+
+func TestSetupNone(t *testing.T) {
+	shutdown, err := tracing.Setup(context.Background(), config.Tracing{Exporter: "none"})
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = tracing.Setup(context.Background(), config.Tracing{Exporter: "jaeger"})
+	assert.Error(t, err)
+}
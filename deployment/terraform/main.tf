@@ -33,7 +33,7 @@ module "frontend" {
 module "backend" {
   source = "./modules/backend"
 
-  environment  = var.environment
-  github_token = var.github_token
-  jwt_secret   = var.jwt_secret
+  environment     = var.environment
+  github_token    = var.github_token
+  jwt_signing_key = var.jwt_signing_key
 }
@@ -35,12 +35,18 @@ resource "aws_instance" "backend" {
               # Login to GitHub Container Registry
               echo "${var.github_token}" | docker login ghcr.io -u mesbahtanvir --password-stdin
               
+              # Write the token signing key, readable by root only
+              mkdir -p /etc/thoughts
+              echo "${base64encode(var.jwt_signing_key)}" | base64 -d > /etc/thoughts/jwt.pem
+              chmod 600 /etc/thoughts/jwt.pem
+              
               # Run the container
               docker run -d \
                 --name thoughts-backend \
                 -p 80:8000 \
                 -e PORT=8000 \
-                -e JWT_SECRET=${var.jwt_secret} \
+                -e JWT_SIGNING_KEY_FILE=/app/keys/jwt.pem \
+                -v /etc/thoughts/jwt.pem:/app/keys/jwt.pem:ro \
                 --restart always \
                 ghcr.io/mesbahtanvir/thoughts-backend:latest
               EOF
@@ -33,6 +33,13 @@ usermod -aG docker ubuntu
 mkdir -p /home/ubuntu/app
 chown -R ubuntu:ubuntu /home/ubuntu/app
 
+# Write the key that signs access tokens, readable by root only
+mkdir -p /home/ubuntu/app/keys
+cat > /home/ubuntu/app/keys/jwt.pem << 'KEY_EOF'
+$${jwt_signing_key}
+KEY_EOF
+chmod 600 /home/ubuntu/app/keys/jwt.pem
+
 # Create Docker Compose file
 cat > /home/ubuntu/app/docker-compose.yml << EOL
 version: '3.8'
@@ -45,11 +52,15 @@ services:
     ports:
       - "8000:8080"
     environment:
-      - JWT_SECRET=$${jwt_secret}
+      - JWT_SIGNING_KEY_FILE=/app/keys/jwt.pem
       - ENVIRONMENT=$${environment}
       - PORT=8080
+      # nginx on the host reaches the container through the Docker bridge
+      - TRUSTED_PROXIES=172.16.0.0/12
+      - PROXY_HEADER=X-Real-IP
     volumes:
       - app_data:/app/data
+      - /home/ubuntu/app/keys:/app/keys:ro
     logging:
       driver: "json-file"
       options:
@@ -62,7 +73,6 @@ EOL
 
 # Create environment file
 cat > /home/ubuntu/app/.env << EOL
-JWT_SECRET=your_jwt_secret_here
 ENVIRONMENT=production
 EOL
 
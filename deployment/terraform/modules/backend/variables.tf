@@ -4,11 +4,15 @@ variable "github_token" {
   sensitive   = true
 }
 
-variable "jwt_secret" {
+variable "jwt_signing_key" {
   type        = string
-  description = "JWT secret for the application"
+  description = "PEM private key (Ed25519 or RSA) that signs access tokens, e.g. from `openssl genpkey -algorithm ed25519`"
   sensitive   = true
-  default     = "your_secure_jwt_secret_here"
+
+  validation {
+    condition     = can(regex("-----BEGIN (RSA )?PRIVATE KEY-----", var.jwt_signing_key))
+    error_message = "The JWT signing key must be a PEM private key."
+  }
 }
 
 variable "environment" {
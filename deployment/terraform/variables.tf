@@ -34,11 +34,10 @@ variable "github_username" {
   default     = "mesbahtanvir"
 }
 
-variable "jwt_secret" {
+variable "jwt_signing_key" {
   type        = string
-  description = "JWT secret key for authentication"
+  description = "PEM private key (Ed25519 or RSA) that signs access tokens; set TF_VAR_jwt_signing_key=\"$(cat jwt.pem)\""
   sensitive   = true
-  default     = "default-insecure-secret-change-me" # In production, always set this explicitly
 }
 
 variable "allowed_ips" {
@@ -4,6 +4,8 @@ import { Box, CircularProgress } from '@mui/material';
 import { authAPI } from './services/api';
 import Login from './components/auth/Login';
 import Register from './components/auth/Register';
+import ResetPassword from './components/auth/ResetPassword';
+import AuthCallback from './components/auth/AuthCallback';
 import Home from './components/Home';
 import Profile from './components/Profile';
 
@@ -93,6 +95,12 @@ function App() {
             <Register />
           </PublicRoute>
         } />
+        <Route path="/reset-password" element={
+          <PublicRoute restricted={true}>
+            <ResetPassword />
+          </PublicRoute>
+        } />
+        <Route path="/auth/callback" element={<AuthCallback />} />
         <Route path="/home" element={
           <ProtectedRoute>
             <Home />
@@ -25,6 +25,9 @@ const Home = () => {
   const navigate = useNavigate();
   const [thought, setThought] = useState('');
   const [thoughts, setThoughts] = useState([]);
+  // Cursor of the next page of older thoughts, null when all are loaded
+  const [nextCursor, setNextCursor] = useState(null);
+  const [isLoadingMore, setIsLoadingMore] = useState(false);
   const [isLoading, setIsLoading] = useState(false);
   // Using eslint-disable on next line as error state is set but not directly used in UI
   // eslint-disable-next-line no-unused-vars
@@ -40,9 +43,10 @@ const Home = () => {
         const user = await authAPI.getCurrentUser();
         setCurrentUser(user);
         
-        // Fetch thoughts
-        const data = await thoughtsAPI.getThoughts();
-        setThoughts(data);
+        // Fetch the first page of thoughts
+        const page = await thoughtsAPI.getThoughts();
+        setThoughts(page.thoughts);
+        setNextCursor(page.nextCursor);
       } catch (err) {
         console.error('Failed to fetch data:', err);
         setError('Failed to load data');
@@ -54,8 +58,26 @@ const Home = () => {
     fetchData();
   }, []);
 
-  const handleLogout = () => {
-    authAPI.logout();
+  const handleLoadMore = async () => {
+    try {
+      setIsLoadingMore(true);
+      const page = await thoughtsAPI.getThoughts(nextCursor);
+      // Thoughts shared since the first page can reappear; keep one copy
+      setThoughts((loaded) => {
+        const seen = new Set(loaded.map((t) => t.id));
+        return [...loaded, ...page.thoughts.filter((t) => !seen.has(t.id))];
+      });
+      setNextCursor(page.nextCursor);
+    } catch (err) {
+      console.error('Failed to load more thoughts:', err);
+      setError('Failed to load more thoughts');
+    } finally {
+      setIsLoadingMore(false);
+    }
+  };
+
+  const handleLogout = async () => {
+    await authAPI.logout();
     navigate('/login');
   };
 
@@ -183,6 +205,19 @@ const Home = () => {
                 </Box>
               </Paper>
             ))}
+
+            {nextCursor && (
+              <Box sx={{ textAlign: 'center' }}>
+                <Button
+                  variant="text"
+                  onClick={handleLoadMore}
+                  disabled={isLoadingMore}
+                  sx={{ textTransform: 'none' }}
+                >
+                  {isLoadingMore ? 'Loading...' : 'Load more'}
+                </Button>
+              </Box>
+            )}
           </Box>
         </Container>
 
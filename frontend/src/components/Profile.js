@@ -42,14 +42,18 @@ const Profile = () => {
     fetchUserProfile();
   }, []);
 
-  const handleLogout = () => {
-    authAPI.logout();
+  const handleLogout = async () => {
+    await authAPI.logout();
     navigate('/login');
   };
 
   const handleResendVerification = async () => {
-    // TODO: Implement resend verification email
-    alert('Verification email resent! Please check your inbox.');
+    try {
+      await authAPI.resendVerification();
+      alert('Verification email resent! Please check your inbox.');
+    } catch (err) {
+      alert(err.message || 'Failed to resend verification email');
+    }
   };
 
   if (isLoading) {
@@ -74,7 +74,7 @@ describe('Home Component Integration Tests', () => {
   it('should display user thoughts when authenticated', async () => {
     // Setup test to simulate authenticated state
     authAPI.getCurrentUser.mockResolvedValue(mockUser);
-    thoughtsAPI.getThoughts.mockResolvedValue(mockThoughts);
+    thoughtsAPI.getThoughts.mockResolvedValue({ thoughts: mockThoughts, nextCursor: null });
     
     renderHome();
     
@@ -93,7 +93,7 @@ describe('Home Component Integration Tests', () => {
   it('should display thoughts content when loaded', async () => {
     // Mock being logged in with thoughts data
     authAPI.getCurrentUser.mockResolvedValue(mockUser);
-    thoughtsAPI.getThoughts.mockResolvedValue(mockThoughts);
+    thoughtsAPI.getThoughts.mockResolvedValue({ thoughts: mockThoughts, nextCursor: null });
     
     renderHome();
     
@@ -114,7 +114,7 @@ describe('Home Component Integration Tests', () => {
     
     // Mock being logged in
     authAPI.getCurrentUser.mockResolvedValue(mockUser);
-    thoughtsAPI.getThoughts.mockResolvedValue(mockThoughts);
+    thoughtsAPI.getThoughts.mockResolvedValue({ thoughts: mockThoughts, nextCursor: null });
     thoughtsAPI.createThought.mockResolvedValue(newThought);
     
     renderHome();
@@ -137,10 +137,28 @@ describe('Home Component Integration Tests', () => {
     expect(submitButton).toBeInTheDocument();
   });
 
+  it('should load older thoughts page by page', async () => {
+    authAPI.getCurrentUser.mockResolvedValue(mockUser);
+    thoughtsAPI.getThoughts
+      .mockResolvedValueOnce({ thoughts: mockThoughts, nextCursor: 'page-2' })
+      .mockResolvedValueOnce({ thoughts: [{ id: 3, content: 'Older thought', user_id: 1 }], nextCursor: null });
+
+    renderHome();
+
+    const loadMore = await screen.findByText('Load more');
+    fireEvent.click(loadMore);
+
+    expect(await screen.findByText('Older thought')).toBeInTheDocument();
+    expect(thoughtsAPI.getThoughts).toHaveBeenLastCalledWith('page-2');
+    expect(screen.getByText('First thought')).toBeInTheDocument();
+    // The last page has no cursor, so there is nothing more to load
+    expect(screen.queryByText('Load more')).not.toBeInTheDocument();
+  });
+
   it('should handle logout correctly', async () => {
     // Mock being logged in
     authAPI.getCurrentUser.mockResolvedValue(mockUser);
-    thoughtsAPI.getThoughts.mockResolvedValue([]);
+    thoughtsAPI.getThoughts.mockResolvedValue({ thoughts: [], nextCursor: null });
     
     renderHome();
     
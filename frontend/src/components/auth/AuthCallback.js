@@ -0,0 +1,51 @@
+import React, { useEffect, useState } from 'react';
+import { authAPI } from '../../services/api';
+import { Box, CircularProgress, Container, Link, Typography } from '@mui/material';
+import { useNavigate } from 'react-router-dom';
+
+// Where external logins end. The backend puts the tokens, a two-factor
+// challenge, or an error code and detail in the URL fragment, which is
+// cleared before anything else.
+const AuthCallback = () => {
+  const [error, setError] = useState(null);
+  const navigate = useNavigate();
+
+  useEffect(() => {
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    window.history.replaceState(null, '', window.location.pathname);
+
+    const token = params.get('token');
+    const refreshToken = params.get('refresh_token');
+    const mfaToken = params.get('mfa_token');
+    if (token && refreshToken) {
+      authAPI.completeExternalLogin(token, refreshToken);
+      navigate('/home', { replace: true });
+    } else if (mfaToken) {
+      // The login page asks for the code
+      navigate('/login', { replace: true, state: { mfaToken } });
+    } else {
+      setError(params.get('detail') || 'Login failed. Please try again.');
+    }
+  }, [navigate]);
+
+  return (
+    <Box sx={{ minHeight: '100vh', display: 'flex', alignItems: 'center', bgcolor: '#f5f5f5' }}>
+      <Container maxWidth="xs" sx={{ textAlign: 'center' }}>
+        {error ? (
+          <>
+            <Typography color="error" sx={{ mb: 2 }}>
+              {error}
+            </Typography>
+            <Link href="/login" color="primary" underline="hover" variant="body2">
+              Back to sign in
+            </Link>
+          </>
+        ) : (
+          <CircularProgress />
+        )}
+      </Container>
+    </Box>
+  );
+};
+
+export default AuthCallback;
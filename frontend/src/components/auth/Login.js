@@ -1,4 +1,4 @@
-import React, { useState } from 'react';
+import React, { useEffect, useState } from 'react';
 import { authAPI } from '../../services/api';
 import {
   Box,
@@ -11,8 +11,8 @@ import {
   InputAdornment,
   IconButton
 } from '@mui/material';
-import { useNavigate } from 'react-router-dom';
-import { Email as EmailIcon, Lock as LockIcon, Visibility, VisibilityOff } from '@mui/icons-material';
+import { useLocation, useNavigate } from 'react-router-dom';
+import { Email as EmailIcon, Key as KeyIcon, Lock as LockIcon, Visibility, VisibilityOff } from '@mui/icons-material';
 
 const Login = () => {
   const [formData, setFormData] = useState({
@@ -21,8 +21,28 @@ const Login = () => {
   });
   const [showPassword, setShowPassword] = useState(false);
   const [error, setError] = useState(null);
+  const [providers, setProviders] = useState([]);
+  // Set once the password was right for an account with two-factor
+  // authentication; external logins arrive here with it in the state
+  const location = useLocation();
+  const [mfaToken, setMfaToken] = useState(location.state?.mfaToken || null);
+  const [code, setCode] = useState('');
   const navigate = useNavigate();
 
+  useEffect(() => {
+    authAPI.getLoginProviders()
+      .then((list) => setProviders(list || []))
+      .catch((err) => console.error('Could not load login providers:', err));
+  }, []);
+
+  const finishLogin = () => {
+    // Get the redirect path from the URL or default to '/home'
+    const from = new URLSearchParams(window.location.search).get('from') || '/home';
+
+    // Use replace: true to prevent going back to the login page with the back button
+    navigate(from, { replace: true });
+  };
+
   const handleClickShowPassword = () => setShowPassword((show) => !show);
 
   const handleChange = (e) => {
@@ -41,17 +61,18 @@ const Login = () => {
       setError({ message: 'Logging in...', type: 'info' });
       
       // Call the login API
-      const { token, user } = await authAPI.login(formData.email, formData.password);
-      
+      const { token, user, mfaRequired, mfaToken: challenge } = await authAPI.login(formData.email, formData.password);
+
+      if (mfaRequired) {
+        setMfaToken(challenge);
+        setError(null);
+        return;
+      }
       if (!token || !user) {
         throw new Error('Login successful but no user data received');
       }
       
-      // Get the redirect path from the URL or default to '/home'
-      const from = new URLSearchParams(window.location.search).get('from') || '/home';
-      
-      // Use replace: true to prevent going back to the login page with the back button
-      navigate(from, { replace: true });
+      finishLogin();
     } catch (err) {
       console.error('Login error:', err);
       setError({ 
@@ -61,6 +82,102 @@ const Login = () => {
     }
   };
 
+  const handleCodeSubmit = async (e) => {
+    e.preventDefault();
+
+    try {
+      setError({ message: 'Checking code...', type: 'info' });
+      await authAPI.loginTwoFactor(mfaToken, code);
+      finishLogin();
+    } catch (err) {
+      console.error('Two-factor login error:', err);
+      if (err.code === 'invalid_token') {
+        // The challenge expired; start over with the password
+        setMfaToken(null);
+      }
+      setCode('');
+      setError({ message: err.message || 'Login failed. Please try again.', type: 'error' });
+    }
+  };
+
+  if (mfaToken) {
+    return (
+      <Box sx={{ minHeight: '100vh', display: 'flex', alignItems: 'center', bgcolor: '#f5f5f5' }}>
+        <Container maxWidth="xs">
+          <Box sx={{ textAlign: 'center', mb: 4 }}>
+            <Typography variant="h5" component="h1" sx={{ fontWeight: 500, mb: 1 }}>
+              Two-factor authentication
+            </Typography>
+            <Typography variant="body1" color="text.secondary">
+              Enter the code from your authenticator app, or one of your recovery codes
+            </Typography>
+          </Box>
+
+          <Box
+            component="form"
+            onSubmit={handleCodeSubmit}
+            sx={{
+              bgcolor: 'background.paper',
+              p: 3,
+              borderRadius: 2,
+              boxShadow: '0 1px 3px rgba(0,0,0,0.05)'
+            }}
+          >
+            <TextField
+              fullWidth
+              autoFocus
+              margin="normal"
+              placeholder="Code"
+              name="code"
+              autoComplete="one-time-code"
+              value={code}
+              onChange={(e) => setCode(e.target.value)}
+              InputProps={{
+                startAdornment: (
+                  <InputAdornment position="start">
+                    <KeyIcon color="action" />
+                  </InputAdornment>
+                ),
+              }}
+            />
+
+            <Button
+              type="submit"
+              fullWidth
+              variant="contained"
+              size="large"
+              disabled={!code.trim()}
+              sx={{
+                mt: 3,
+                py: 1.5,
+                textTransform: 'none',
+                fontWeight: 500,
+                borderRadius: 2
+              }}
+            >
+              Verify
+            </Button>
+
+            {error && (
+              <Typography
+                color={error.type === 'error' ? 'error' : 'primary'}
+                sx={{ mt: 2, textAlign: 'center' }}
+              >
+                {error.message}
+              </Typography>
+            )}
+
+            <Box sx={{ textAlign: 'center', mt: 2 }}>
+              <Link component="button" type="button" onClick={() => { setMfaToken(null); setError(null); }} color="primary" underline="hover" variant="body2">
+                Back to sign in
+              </Link>
+            </Box>
+          </Box>
+        </Container>
+      </Box>
+    );
+  }
+
   return (
     <Box sx={{ minHeight: '100vh', display: 'flex', alignItems: 'center', bgcolor: '#f5f5f5' }}>
       <Container maxWidth="xs">
@@ -157,6 +274,25 @@ const Login = () => {
             </Typography>
           )}
 
+          {providers.map((provider) => (
+            <Button
+              key={provider.name}
+              href={provider.login_url}
+              fullWidth
+              variant="outlined"
+              size="large"
+              sx={{ mt: 2, py: 1.5, textTransform: 'none', fontWeight: 500, borderRadius: 2 }}
+            >
+              Sign in with {provider.display_name}
+            </Button>
+          ))}
+
+          <Box sx={{ textAlign: 'center', mt: 2 }}>
+            <Link href="/reset-password" color="primary" underline="hover" variant="body2">
+              Forgot your password?
+            </Link>
+          </Box>
+
           <Divider sx={{ my: 3 }} />
           
           <Box sx={{ textAlign: 'center' }}>
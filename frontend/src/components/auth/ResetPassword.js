@@ -0,0 +1,119 @@
+import React, { useState } from 'react';
+import { authAPI } from '../../services/api';
+import {
+  Box,
+  Button,
+  Container,
+  TextField,
+  Typography,
+  Link,
+  Divider,
+} from '@mui/material';
+import { useNavigate, useSearchParams } from 'react-router-dom';
+
+// Handles both steps of a password reset: asking for the email link, and
+// choosing a new password once the user follows it (?token=...)
+const ResetPassword = () => {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get('token');
+  const [email, setEmail] = useState('');
+  const [password, setPassword] = useState('');
+  const [status, setStatus] = useState(null);
+  const navigate = useNavigate();
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    setStatus(null);
+
+    try {
+      if (token) {
+        await authAPI.resetPassword(token, password);
+        navigate('/login', { replace: true });
+      } else {
+        const response = await authAPI.forgotPassword(email);
+        setStatus({ message: response.message, type: 'info' });
+      }
+    } catch (err) {
+      setStatus({ message: err.message || 'Something went wrong', type: 'error' });
+    }
+  };
+
+  return (
+    <Box sx={{ minHeight: '100vh', display: 'flex', alignItems: 'center', bgcolor: '#f5f5f5' }}>
+      <Container maxWidth="xs">
+        <Box sx={{ textAlign: 'center', mb: 4 }}>
+          <Typography variant="h5" component="h1" sx={{ fontWeight: 500, mb: 1 }}>
+            {token ? 'Choose a new password' : 'Reset your password'}
+          </Typography>
+          <Typography variant="body1" color="text.secondary">
+            {token ? 'You will be signed out everywhere' : "We'll email you a reset link"}
+          </Typography>
+        </Box>
+
+        <Box
+          component="form"
+          onSubmit={handleSubmit}
+          sx={{
+            bgcolor: 'background.paper',
+            p: 3,
+            borderRadius: 2,
+            boxShadow: '0 1px 3px rgba(0,0,0,0.05)'
+          }}
+        >
+          {token ? (
+            <TextField
+              fullWidth
+              margin="normal"
+              placeholder="New password"
+              name="password"
+              type="password"
+              autoComplete="new-password"
+              value={password}
+              onChange={(e) => setPassword(e.target.value)}
+            />
+          ) : (
+            <TextField
+              fullWidth
+              margin="normal"
+              placeholder="Email address"
+              name="email"
+              type="email"
+              autoComplete="email"
+              value={email}
+              onChange={(e) => setEmail(e.target.value)}
+            />
+          )}
+
+          <Button
+            type="submit"
+            fullWidth
+            variant="contained"
+            size="large"
+            sx={{ mt: 3, py: 1.5, textTransform: 'none', fontWeight: 500, borderRadius: 2 }}
+          >
+            {token ? 'Reset password' : 'Send reset link'}
+          </Button>
+
+          {status && (
+            <Typography
+              color={status.type === 'error' ? 'error' : 'primary'}
+              sx={{ mt: 2, textAlign: 'center' }}
+            >
+              {status.message}
+            </Typography>
+          )}
+
+          <Divider sx={{ my: 3 }} />
+
+          <Box sx={{ textAlign: 'center' }}>
+            <Link href="/login" color="primary" underline="hover" sx={{ fontWeight: 500 }}>
+              Back to sign in
+            </Link>
+          </Box>
+        </Box>
+      </Container>
+    </Box>
+  );
+};
+
+export default ResetPassword;
@@ -6,8 +6,51 @@ console.log('Environment variables:', process.env);
 console.log('Using API URL:', API_BASE_URL);
 console.log('REACT_APP_API_URL:', process.env.REACT_APP_API_URL);
 
+// The refresh under way, if any. Refresh tokens work once and the server
+// revokes the whole session when one is presented twice, so requests that
+// fail together wait on a single refresh instead of starting their own.
+let refreshInFlight = null;
+
+// Exchange the stored refresh token for a new token pair.
+// Returns true when new tokens were stored.
+const refreshTokens = () => {
+  if (!refreshInFlight) {
+    refreshInFlight = doRefreshTokens().finally(() => {
+      refreshInFlight = null;
+    });
+  }
+  return refreshInFlight;
+};
+
+const doRefreshTokens = async () => {
+  const refreshToken = localStorage.getItem('refreshToken');
+  if (!refreshToken) {
+    return false;
+  }
+
+  try {
+    const response = await fetch(`${API_BASE_URL}/auth/refresh`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ refresh_token: refreshToken }),
+    });
+    if (!response.ok) {
+      localStorage.removeItem('refreshToken');
+      return false;
+    }
+
+    const { token, refresh_token } = await response.json();
+    localStorage.setItem('token', token);
+    localStorage.setItem('refreshToken', refresh_token);
+    return true;
+  } catch (error) {
+    console.error('Token refresh failed:', error);
+    return false;
+  }
+};
+
 // Helper function to handle API requests
-const apiRequest = async (endpoint, options = {}) => {
+const apiRequest = async (endpoint, options = {}, retried = false) => {
   const token = localStorage.getItem('token');
   
   const headers = {
@@ -35,6 +78,11 @@ const apiRequest = async (endpoint, options = {}) => {
       body: options.body ? JSON.stringify(options.body) : undefined,
     });
 
+    // No Content responses (e.g. DELETE) have no body to parse
+    if (response.status === 204) {
+      return null;
+    }
+
     let data;
     try {
       data = await response.json();
@@ -45,9 +93,19 @@ const apiRequest = async (endpoint, options = {}) => {
     }
 
     if (!response.ok) {
+      // Access tokens are short-lived; try a refresh once before giving up,
+      // unless another request already refreshed them meanwhile
+      if (response.status === 401 && !retried) {
+        const refreshed = localStorage.getItem('token') !== token || await refreshTokens();
+        if (refreshed) {
+          return apiRequest(endpoint, options, true);
+        }
+      }
+
       // If token is invalid or expired, clear it
       if (response.status === 401) {
         localStorage.removeItem('token');
+        localStorage.removeItem('refreshToken');
         localStorage.removeItem('user');
         
         // Only redirect if we're not already on the login page
@@ -57,7 +115,7 @@ const apiRequest = async (endpoint, options = {}) => {
         }
       }
       
-      const errorMessage = data?.message || data?.error || `Request failed with status ${response.status}`;
+      const errorMessage = data?.detail || data?.message || `Request failed with status ${response.status}`;
       throw new Error(errorMessage);
     }
 
@@ -69,58 +127,83 @@ const apiRequest = async (endpoint, options = {}) => {
   }
 };
 
+// finishLogin stores the tokens of a login response and fetches the user's
+// profile, or passes on the challenge of an account with two-factor
+// authentication
+const finishLogin = async (loginResponse) => {
+  try {
+    if (!loginResponse.ok) {
+      const errorData = await loginResponse.json().catch(() => ({}));
+      const error = new Error(errorData.detail || errorData.message || 'Login failed');
+      error.code = errorData.code;
+      throw error;
+    }
+
+    const { token, refresh_token, mfa_required, mfa_token } = await loginResponse.json();
+
+    if (mfa_required) {
+      return { mfaRequired: true, mfaToken: mfa_token };
+    }
+    if (!token) {
+      throw new Error('No token received from server');
+    }
+
+    // Store the tokens
+    localStorage.setItem('token', token);
+    localStorage.setItem('refreshToken', refresh_token);
+
+    // Now fetch the user's profile
+    const userResponse = await fetch(`${API_BASE_URL}/me`, {
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!userResponse.ok) {
+      throw new Error('Failed to fetch user profile');
+    }
+
+    const user = await userResponse.json();
+
+    // Store user data
+    localStorage.setItem('user', JSON.stringify(user));
+
+    return { token, user };
+  } catch (error) {
+    console.error('Login failed:', error);
+    // Clean up on error
+    localStorage.removeItem('token');
+    localStorage.removeItem('refreshToken');
+    localStorage.removeItem('user');
+    throw error;
+  }
+};
+
 // Auth API
 export const authAPI = {
+  // Resolves to { token, user }, or to { mfaRequired, mfaToken } for accounts
+  // with two-factor authentication; finish those with loginTwoFactor
   login: async (email, password) => {
-    try {
-      // First, make the login request to get the token
-      const loginResponse = await fetch(`${API_BASE_URL}/auth/login`, {
-        method: 'POST',
-        headers: {
-          'Content-Type': 'application/json',
-        },
-        body: JSON.stringify({ email, password }),
-      });
-
-      if (!loginResponse.ok) {
-        const errorData = await loginResponse.json().catch(() => ({}));
-        throw new Error(errorData.error || errorData.message || 'Login failed');
-      }
+    const loginResponse = await fetch(`${API_BASE_URL}/auth/login`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({ email, password }),
+    });
+    return finishLogin(loginResponse);
+  },
 
-      const { token } = await loginResponse.json();
-      
-      if (!token) {
-        throw new Error('No token received from server');
-      }
-      
-      // Store the token
-      localStorage.setItem('token', token);
-      
-      // Now fetch the user's profile
-      const userResponse = await fetch(`${API_BASE_URL}/me`, {
-        headers: {
-          'Authorization': `Bearer ${token}`,
-          'Content-Type': 'application/json',
-        },
-      });
-      
-      if (!userResponse.ok) {
-        throw new Error('Failed to fetch user profile');
-      }
-      
-      const user = await userResponse.json();
-      
-      // Store user data
-      localStorage.setItem('user', JSON.stringify(user));
-      
-      return { token, user };
-    } catch (error) {
-      console.error('Login failed:', error);
-      // Clean up on error
-      localStorage.removeItem('token');
-      localStorage.removeItem('user');
-      throw error;
-    }
+  loginTwoFactor: async (mfaToken, code) => {
+    const loginResponse = await fetch(`${API_BASE_URL}/auth/login/2fa`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({ mfa_token: mfaToken, code }),
+    });
+    return finishLogin(loginResponse);
   },
 
   register: async (email, password) => {
@@ -130,6 +213,9 @@ export const authAPI = {
     });
     // The backend returns the token directly as a string
     const token = typeof response === 'string' ? response : response.token;
+    if (response?.refresh_token) {
+      localStorage.setItem('refreshToken', response.refresh_token);
+    }
     return {
       token,
       user: { email }
@@ -165,18 +251,113 @@ export const authAPI = {
     }
   },
 
-  logout: () => {
+  forgotPassword: async (email) => {
+    return apiRequest('/auth/forgot-password', {
+      method: 'POST',
+      body: { email },
+    });
+  },
+
+  resetPassword: async (token, password) => {
+    return apiRequest('/auth/reset-password', {
+      method: 'POST',
+      body: { token, password },
+    });
+  },
+
+  changePassword: async (currentPassword, newPassword) => {
+    return apiRequest('/me/password', {
+      method: 'PUT',
+      body: { current_password: currentPassword, new_password: newPassword },
+    });
+  },
+
+  changeEmail: async (email, password) => {
+    const user = await apiRequest('/me/email', {
+      method: 'PUT',
+      body: { email, password },
+    });
+    localStorage.setItem('user', JSON.stringify(user));
+    return user;
+  },
+
+  deleteAccount: async (password) => {
+    await apiRequest('/me', {
+      method: 'DELETE',
+      body: { password },
+    });
+    localStorage.removeItem('token');
+    localStorage.removeItem('refreshToken');
+    localStorage.removeItem('user');
+  },
+
+  // External login providers; logging in with one is a navigation to its
+  // login_url that ends on /auth/callback
+  getLoginProviders: async () => {
+    return apiRequest('/auth/oidc/providers');
+  },
+
+  // Store the tokens an external login handed back in the URL fragment
+  completeExternalLogin: (token, refreshToken) => {
+    localStorage.setItem('token', token);
+    localStorage.setItem('refreshToken', refreshToken);
+  },
+
+  // Two-factor authentication: setup returns the secret and otpauth_uri,
+  // confirming it with a first code returns the recovery codes
+  getTwoFactor: async () => {
+    return apiRequest('/me/2fa');
+  },
+
+  setupTwoFactor: async (password) => {
+    return apiRequest('/me/2fa/setup', {
+      method: 'POST',
+      body: { password },
+    });
+  },
+
+  confirmTwoFactor: async (code) => {
+    return apiRequest('/me/2fa/confirm', {
+      method: 'POST',
+      body: { code },
+    });
+  },
+
+  disableTwoFactor: async (password, code) => {
+    return apiRequest('/me/2fa', {
+      method: 'DELETE',
+      body: { password, code },
+    });
+  },
+
+  resendVerification: async () => {
+    return apiRequest('/auth/resend-verification', { method: 'POST' });
+  },
+
+  logout: async () => {
+    // Revoke the session server-side; clear local state even if that fails
+    try {
+      await apiRequest('/auth/logout', { method: 'POST' }, true);
+    } catch (error) {
+      console.error('Logout request failed:', error);
+    }
     localStorage.removeItem('token');
+    localStorage.removeItem('refreshToken');
     localStorage.removeItem('user');
   },
 };
 
 // Thoughts API
 export const thoughtsAPI = {
-  getThoughts: async () => {
-    const response = await apiRequest('/thoughts');
-    // The backend returns thoughts in the data field
-    return Array.isArray(response) ? response : [];
+  // Resolves to a page of thoughts, newest first. nextCursor fetches the
+  // following page and is null on the last one.
+  getThoughts: async (cursor) => {
+    const query = cursor ? `?cursor=${encodeURIComponent(cursor)}` : '';
+    const response = await apiRequest(`/thoughts${query}`);
+    return {
+      thoughts: Array.isArray(response?.thoughts) ? response.thoughts : [],
+      nextCursor: response?.next_cursor || null,
+    };
   },
 
   createThought: async (content) => {